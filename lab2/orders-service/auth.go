@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// minAuthSecret - минимальная длина AUTH_JWT_SECRET в байтах: для HS256
+// ключ должен быть не короче хеша (RFC 7518, 3.2).
+const minAuthSecret = 32
+
+// loadAuthSecret читает AUTH_JWT_SECRET; пустое значение выключает
+// проверку токенов.
+func loadAuthSecret(getenv func(string) string) (string, error) {
+	secret := getenv("AUTH_JWT_SECRET")
+	if secret != "" && len(secret) < minAuthSecret {
+		return "", fmt.Errorf("AUTH_JWT_SECRET must be at least %d bytes", minAuthSecret)
+	}
+	return secret, nil
+}
+
+// jwtClaims - поля токена, которые читает сервис.
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt *int64 `json:"exp"`
+}
+
+// parseJWT проверяет подпись HS256 и срок действия токена и возвращает
+// его claims.
+func parseJWT(token string, secret []byte, at time.Time) (jwtClaims, error) {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return claims, err
+	}
+	// Алгоритм из заголовка не выбирает проверку: принимаем только HS256
+	if header.Alg != "HS256" {
+		return claims, fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errors.New("malformed signature")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return claims, errors.New("invalid signature")
+	}
+
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return claims, err
+	}
+	if claims.Subject == "" {
+		return claims, errors.New("missing sub claim")
+	}
+	if claims.ExpiresAt != nil && !at.Before(time.Unix(*claims.ExpiresAt, 0)) {
+		return claims, errors.New("token expired")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// publicPaths - пробы оркестратора. Они отвечают и без токена при
+// включенном require_auth (авторизоваться пробы не умеют), и до конца
+// старта (startupGate).
+var publicPaths = map[string]bool{
+	"/health":  true,
+	"/live":    true,
+	"/startup": true,
+	"/ready":   true,
+	"/healthz": true,
+}
+
+// authenticate кладет в контекст запроса вызывающего пользователя из
+// Authorization: Bearer <JWT> (claim sub). Дальше он попадает в журнал
+// изменений заказов и в X-On-Behalf-Of запросов к user-service.
+//
+// Запрос без Authorization проходит анонимно, если не включен флаг
+// require_auth. Неверный или истекший токен получает 401: молча принять
+// его как анонимный значило бы потерять вызывающего. Без AUTH_JWT_SECRET
+// заголовок не читается.
+func (s *server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" && s.flags.Get().RequireAuth && !publicPaths[r.URL.Path] {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+			return
+		}
+		if len(s.authSecret) == 0 || header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid_token", "Authorization must be a Bearer token")
+			return
+		}
+		claims, err := parseJWT(strings.TrimSpace(token), s.authSecret, now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), claims.Subject)))
+	})
+}
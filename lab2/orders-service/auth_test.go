@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testAuthSecret = "0123456789abcdef0123456789abcdef"
+
+// signJWT собирает токен с заголовком header и claims, подписанный HS256.
+func signJWT(header, claims, secret string) string {
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func authRequest(s *server, authorization string) (*httptest.ResponseRecorder, string) {
+	var caller string
+	h := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = callerFromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec, caller
+}
+
+func TestAuthenticate_SetsCaller(t *testing.T) {
+	setClock(t, time.Unix(1700000000, 0))
+	s := newTestServer(t, nil)
+	s.authSecret = []byte(testAuthSecret)
+
+	token := signJWT(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"42","exp":1700000060}`, testAuthSecret)
+	rec, caller := authRequest(s, "Bearer "+token)
+	if rec.Code != http.StatusOK || caller != "42" {
+		t.Errorf("Expected caller 42, got: %q (status %d)", caller, rec.Code)
+	}
+
+	if rec, caller := authRequest(s, ""); rec.Code != http.StatusOK || caller != "" {
+		t.Errorf("Expected anonymous request to pass without caller, got: %q (status %d)", caller, rec.Code)
+	}
+}
+
+func TestAuthenticate_RejectsInvalidTokens(t *testing.T) {
+	setClock(t, time.Unix(1700000000, 0))
+	s := newTestServer(t, nil)
+	s.authSecret = []byte(testAuthSecret)
+
+	for name, authorization := range map[string]string{
+		"basic":         "Basic b3JkZXJzOnNlY3JldA==",
+		"malformed":     "Bearer not-a-token",
+		"wrong secret":  "Bearer " + signJWT(`{"alg":"HS256"}`, `{"sub":"42"}`, "another-secret-another-secret-xx"),
+		"alg none":      "Bearer " + signJWT(`{"alg":"none"}`, `{"sub":"42"}`, testAuthSecret),
+		"expired":       "Bearer " + signJWT(`{"alg":"HS256"}`, `{"sub":"42","exp":1700000000}`, testAuthSecret),
+		"missing sub":   "Bearer " + signJWT(`{"alg":"HS256"}`, `{"exp":1700000060}`, testAuthSecret),
+		"invalid claim": "Bearer " + signJWT(`{"alg":"HS256"}`, `{"sub":42}`, testAuthSecret),
+	} {
+		rec, caller := authRequest(s, authorization)
+		if rec.Code != http.StatusUnauthorized || caller != "" {
+			t.Errorf("%s: expected status 401, got: %d (caller %q)", name, rec.Code, caller)
+		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected WWW-Authenticate header", name)
+		}
+	}
+}
+
+func TestAuthenticate_DisabledWithoutSecret(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec, caller := authRequest(s, "Bearer "+signJWT(`{"alg":"HS256"}`, `{"sub":"42"}`, testAuthSecret))
+	if rec.Code != http.StatusOK || caller != "" {
+		t.Errorf("Expected token to be ignored without AUTH_JWT_SECRET, got: %q (status %d)", caller, rec.Code)
+	}
+}
+
+func TestAuthenticate_ForwardsCallerToUserService(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.authSecret = []byte(testAuthSecret)
+	callers := make(chan string, 4)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		callers <- r.Header.Get(onBehalfOfHeader)
+		userServiceStub(w, r)
+	})
+
+	r := jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`)
+	r.Header.Set("Authorization", "Bearer "+signJWT(`{"alg":"HS256"}`, `{"sub":"42"}`, testAuthSecret))
+	rec := httptest.NewRecorder()
+	s.authenticate(s.routes()).ServeHTTP(rec, r)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if got := <-callers; got != "42" {
+		t.Errorf("Expected %s: 42 from the token, got: %q", onBehalfOfHeader, got)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBatchOrders ограничивает размер одного POST /orders/batch.
+const maxBatchOrders = 100
+
+// batchItemResult - исход создания одного заказа из пакета. Index -
+// позиция в запросе, Status - код, которым ответил бы POST /orders.
+type batchItemResult struct {
+	Index  int        `json:"index"`
+	Status int        `json:"status"`
+	ID     int        `json:"id,omitempty"`
+	Error  *ErrorBody `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []batchItemResult `json:"results"`
+}
+
+// createOrdersBatch обрабатывает POST /orders/batch. Каждый заказ проходит
+// тот же путь, что и POST /orders, и создается независимо от остальных.
+// Общий статус: 201 - созданы все, 207 - часть, 400 - ни одного.
+func (s *server) createOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var orders []createOrderRequest
+	if err := decodeBody(r, &orders); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(orders) == 0 {
+		writeError(w, http.StatusBadRequest, "validation_failed", "batch must contain at least one order")
+		return
+	}
+	if len(orders) > maxBatchOrders {
+		writeError(w, http.StatusBadRequest, "validation_failed", fmt.Sprintf("at most %d orders per batch", maxBatchOrders))
+		return
+	}
+
+	resp := batchResponse{Results: make([]batchItemResult, 0, len(orders))}
+	for i, order := range orders {
+		result := s.createBatchItem(r, order)
+		result.Index = i
+		if result.Error == nil {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	status := http.StatusMultiStatus
+	switch {
+	case resp.Failed == 0:
+		status = http.StatusCreated
+	case resp.Succeeded == 0:
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// createBatchItem создает один заказ пакета, переиспользуя обработчики
+// POST /orders: их ответ перехватывается и переводится в batchItemResult.
+func (s *server) createBatchItem(r *http.Request, req createOrderRequest) batchItemResult {
+	rec := newItemRecorder()
+	s.createValidOrder(rec, r, req)
+
+	result := batchItemResult{Status: rec.status}
+	if rec.status == http.StatusCreated {
+		// Тело ответа может быть в MessagePack (по Accept), а ID есть в Location
+		result.ID, _ = strconv.Atoi(strings.TrimPrefix(rec.header.Get("Location"), "/orders/"))
+		return result
+	}
+
+	var envelope ErrorResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &envelope); err != nil || envelope.Error.Code == "" {
+		envelope.Error = ErrorBody{Code: "internal", Message: http.StatusText(rec.status)}
+	}
+	result.Error = &envelope.Error
+	return result
+}
+
+// itemRecorder - http.ResponseWriter, который только запоминает ответ.
+type itemRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newItemRecorder() *itemRecorder {
+	return &itemRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (rec *itemRecorder) Header() http.Header         { return rec.header }
+func (rec *itemRecorder) WriteHeader(code int)        { rec.status = code }
+func (rec *itemRecorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func decodeBatch(t *testing.T, rec *httptest.ResponseRecorder) batchResponse {
+	t.Helper()
+	var resp batchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	return resp
+}
+
+func TestCreateOrdersBatch_AllSucceeded(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/batch", `[
+		{"user_id":1,"product":"Pen","quantity":1},
+		{"user_id":2,"product":"Ink","quantity":2}
+	]`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	resp := decodeBatch(t, rec)
+	if resp.Succeeded != 2 || resp.Failed != 0 || len(resp.Results) != 2 {
+		t.Fatalf("Expected 2 created orders, got: %+v", resp)
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != http.StatusCreated || result.ID == 0 || result.Error != nil {
+			t.Errorf("Unexpected result %d: %+v", i, result)
+		}
+		if _, ok := s.orders[result.ID]; !ok {
+			t.Errorf("Expected order %d to be stored", result.ID)
+		}
+	}
+}
+
+func TestCreateOrdersBatch_Partial(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/batch", `[
+		{"user_id":1,"product":"Pen","quantity":0},
+		{"user_id":1,"product":"Pen","quantity":1}
+	]`))
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	resp := decodeBatch(t, rec)
+	if resp.Succeeded != 1 || resp.Failed != 1 {
+		t.Fatalf("Expected 1 created and 1 failed, got: %+v", resp)
+	}
+
+	failed := resp.Results[0]
+	if failed.Index != 0 || failed.Status != http.StatusBadRequest || failed.ID != 0 ||
+		failed.Error == nil || failed.Error.Code != "validation_failed" {
+		t.Errorf("Expected first item to fail validation, got: %+v", failed)
+	}
+
+	created := resp.Results[1]
+	if created.Index != 1 || created.Status != http.StatusCreated || created.ID != 1 || created.Error != nil {
+		t.Errorf("Expected second item created as order 1, got: %+v", created)
+	}
+}
+
+func TestCreateOrdersBatch_AllFailed(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/batch", `[
+		{"user_id":1,"product":"Pen","quantity":1},
+		{"user_id":2,"product":"Pen","quantity":-1}
+	]`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	resp := decodeBatch(t, rec)
+	if resp.Succeeded != 0 || resp.Failed != 2 {
+		t.Fatalf("Expected 2 failed items, got: %+v", resp)
+	}
+	if e := resp.Results[0].Error; e == nil || e.Code != "invalid_user" {
+		t.Errorf("Expected invalid_user for unknown user, got: %+v", resp.Results[0])
+	}
+	if e := resp.Results[1].Error; e == nil || e.Code != "validation_failed" {
+		t.Errorf("Expected validation_failed for negative quantity, got: %+v", resp.Results[1])
+	}
+	if len(s.orders) != 0 {
+		t.Errorf("Expected no orders to be created, got: %v", s.orders)
+	}
+}
+
+func TestCreateOrdersBatch_InvalidBatch(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+
+	for _, body := range []string{`[]`, `{"user_id":1}`} {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders/batch", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userCache - кэш пользователей по ID с общим TTL.
+type userCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[int]cachedUser
+}
+
+type cachedUser struct {
+	user    User
+	expires time.Time
+}
+
+func newUserCache(ttl time.Duration) *userCache {
+	return &userCache{ttl: ttl, entries: map[int]cachedUser{}}
+}
+
+func (c *userCache) Get(id int) (*User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !now().Before(entry.expires) {
+		delete(c.entries, id)
+		return nil, false
+	}
+	user := entry.user
+	return &user, true
+}
+
+func (c *userCache) Put(user User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[user.ID] = cachedUser{user: user, expires: now().Add(c.ttl)}
+}
+
+func (c *userCache) Delete(id int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[id]
+	delete(c.entries, id)
+	return ok
+}
+
+func (c *userCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[int]cachedUser{}
+}
+
+// cacheEntryInfo - запись кэша в ответе /admin/cache/users.
+type cacheEntryInfo struct {
+	UserID         int   `json:"user_id"`
+	TTLRemainingMS int64 `json:"ttl_remaining_ms"`
+}
+
+// Entries возвращает живые записи по возрастанию ID.
+func (c *userCache) Entries() []cacheEntryInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := now()
+	list := make([]cacheEntryInfo, 0, len(c.entries))
+	for id, entry := range c.entries {
+		if remaining := entry.expires.Sub(current); remaining > 0 {
+			list = append(list, cacheEntryInfo{UserID: id, TTLRemainingMS: remaining.Milliseconds()})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list
+}
+
+type cacheListResponse struct {
+	Enabled bool             `json:"enabled"`
+	Entries []cacheEntryInfo `json:"entries"`
+}
+
+// handleUserCache обслуживает /admin/cache/users[/{id}]: GET показывает
+// записи с остатком TTL, DELETE выбрасывает одну запись или весь кэш.
+// Нужен при инцидентах, когда в кэше застряли устаревшие данные.
+func (s *server) handleUserCache(w http.ResponseWriter, r *http.Request) {
+	cache := s.userClient.Cache
+	idStr := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/cache/users"), "/")
+
+	if idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "Invalid user ID")
+			return
+		}
+		if r.Method != http.MethodDelete {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+			return
+		}
+		if cache != nil {
+			cache.Delete(id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		resp := cacheListResponse{Enabled: cache != nil, Entries: []cacheEntryInfo{}}
+		if cache != nil {
+			resp.Entries = cache.Entries()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	case http.MethodDelete:
+		if cache != nil {
+			cache.Clear()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCachingServer - сервер с кэшем пользователей и счетчиком запросов к user-service.
+func newCachingServer(t *testing.T) (*server, *atomic.Int32) {
+	t.Helper()
+	s := newTestServer(t, quantityDataset())
+	s.adminEnabled = true
+
+	var calls atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		userServiceStub(w, r)
+	})
+	s.userClient.Cache = newUserCache(time.Minute)
+	s.setFlags(func(f *Flags) { f.EmbedUser = true })
+	return s, &calls
+}
+
+func listCache(t *testing.T, s *server) []cacheEntryInfo {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/cache/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	var resp cacheListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode cache listing: %v", err)
+	}
+	return resp.Entries
+}
+
+func TestUserCache_EvictOneRefetches(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s, calls := newCachingServer(t)
+
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/2", nil))
+	if calls.Load() != 1 {
+		t.Fatalf("Expected second lookup of user 1 to hit the cache, got %d upstream calls", calls.Load())
+	}
+
+	clock.Set(start.Add(20 * time.Second))
+	entries := listCache(t, s)
+	if len(entries) != 1 || entries[0].UserID != 1 || entries[0].TTLRemainingMS != 40000 {
+		t.Fatalf("Expected user 1 with 40s left, got: %+v", entries)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/admin/cache/users/1", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if entries := listCache(t, s); len(entries) != 0 {
+		t.Errorf("Expected cache to be empty after eviction, got: %+v", entries)
+	}
+
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	if calls.Load() != 2 {
+		t.Errorf("Expected lookup after eviction to refetch, got %d upstream calls", calls.Load())
+	}
+}
+
+func TestUserCache_EvictAll(t *testing.T) {
+	s, calls := newCachingServer(t)
+
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/3", nil))
+	if entries := listCache(t, s); len(entries) != 2 {
+		t.Fatalf("Expected two cached users, got: %+v", entries)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/admin/cache/users", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if entries := listCache(t, s); len(entries) != 0 {
+		t.Errorf("Expected empty cache, got: %+v", entries)
+	}
+
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/3", nil))
+	if calls.Load() != 3 {
+		t.Errorf("Expected refetch after clearing the cache, got %d upstream calls", calls.Load())
+	}
+}
+
+func TestUserCache_AdminDisabled(t *testing.T) {
+	s, _ := newCachingServer(t)
+	s.adminEnabled = false
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/cache/users", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/admin/cache/users/1", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+}
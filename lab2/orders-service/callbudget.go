@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrUpstreamCallLimit возвращается UserServiceClient без обращения к
+// сети, когда обработка одного входящего запроса уже сделала разрешенное
+// число запросов к user-service.
+var ErrUpstreamCallLimit = errors.New("upstream call limit exceeded for this request")
+
+// upstreamCalls - счетчик исходящих запросов одного входящего запроса.
+// Общий для всех горутин обработчика, поэтому атомарный.
+type upstreamCalls struct {
+	max  int64
+	used atomic.Int64
+}
+
+type upstreamCallsKey struct{}
+
+// withUpstreamCallLimit ограничивает число исходящих запросов, сделанных
+// с контекстом ctx и производными от него.
+func withUpstreamCallLimit(ctx context.Context, max int) context.Context {
+	return context.WithValue(ctx, upstreamCallsKey{}, &upstreamCalls{max: int64(max)})
+}
+
+// spendUpstreamCall учитывает один исходящий запрос. Без лимита в
+// контексте (фоновые задачи) запросы не ограничены.
+func spendUpstreamCall(ctx context.Context) error {
+	calls, ok := ctx.Value(upstreamCallsKey{}).(*upstreamCalls)
+	if !ok {
+		return nil
+	}
+	if used := calls.used.Add(1); used > calls.max {
+		upstreamCallsLimited.Add(1)
+		return fmt.Errorf("%w (max %d)", ErrUpstreamCallLimit, calls.max)
+	}
+	return nil
+}
+
+// upstreamCallLimit кладет в контекст каждого запроса лимит исходящих
+// запросов s.maxUpstreamCalls. Ограничивает ущерб от обработчика,
+// который из-за ошибки или конфигурации начинает ходить в user-service
+// без меры, например при рекурсивном встраивании. Попадания в кэш
+// пользователей не считаются, повторы считаются.
+func (s *server) upstreamCallLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maxUpstreamCalls <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withUpstreamCallLimit(r.Context(), s.maxUpstreamCalls)))
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpstreamCallLimit_StopsRunawayHandler(t *testing.T) {
+	s := newTestServer(t, nil)
+	var upstream atomic.Int64
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		upstream.Add(1)
+		userServiceStub(w, r)
+	})
+	s.maxUpstreamCalls = 3
+	before := upstreamCallsLimited.Value()
+
+	// Обработчик, который ходит в user-service, пока ему не откажут
+	var calls int
+	var stopErr error
+	handler := s.upstreamCallLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for calls = 0; calls < 100; calls++ {
+			if _, stopErr = s.userClient.FetchUserByID(r.Context(), calls+1); stopErr != nil {
+				return
+			}
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !errors.Is(stopErr, ErrUpstreamCallLimit) {
+		t.Fatalf("Expected ErrUpstreamCallLimit, got: %v", stopErr)
+	}
+	if calls != 3 || upstream.Load() != 3 {
+		t.Errorf("Expected handler stopped after 3 upstream calls, got %d calls and %d requests", calls, upstream.Load())
+	}
+	if got := upstreamCallsLimited.Value() - before; got != 1 {
+		t.Errorf("Expected 1 limited call in the metric, got: %d", got)
+	}
+
+	// Лимит на запрос, а не на сервер: следующий запрос начинает с нуля
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if calls != 3 {
+		t.Errorf("Expected a fresh budget for the next request, got %d calls", calls)
+	}
+}
+
+func TestUpstreamCallLimit_ExpandUser(t *testing.T) {
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending"},
+		2: {ID: 2, UserID: 2, Product: "Pen", Quantity: 1, Status: "pending"},
+		3: {ID: 3, UserID: 3, Product: "Pen", Quantity: 1, Status: "pending"},
+	})
+	var upstream atomic.Int64
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		upstream.Add(1)
+		userServiceStub(w, r)
+	})
+	s.maxUpstreamCalls = 2
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?expand=user", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if got := upstream.Load(); got != 2 {
+		t.Errorf("Expected 2 user-service requests, got: %d", got)
+	}
+
+	// Пользователь сверх лимита не встраивается, но список все равно отдается
+	var list []Order
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode orders: %v", err)
+	}
+	missing := 0
+	for _, order := range list {
+		if order.User == nil {
+			missing++
+		}
+	}
+	if len(list) != 3 || missing != 1 {
+		t.Errorf("Expected 3 orders with 1 user not embedded, got %d orders, %d without user", len(list), missing)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Варианты ?case=: snake_case - канонический вид из JSON-тегов,
+// camelCase - для клиентов, которым так привычнее.
+const (
+	caseSnake = "snake"
+	caseCamel = "camel"
+)
+
+// camelCaseKey превращает user_id в userId.
+func camelCaseKey(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+	parts := strings.Split(key, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+// camelFields - JSON-имена полей структур, которые отдают обработчики.
+// Переименовываются только они: ключи карт с данными (статусы в
+// by_status, имена проверок в /healthz, метрики /debug/vars) - это
+// значения, а не поля, и остаются как есть. Новый тип ответа нужно
+// добавить сюда, иначе его поля останутся в snake_case.
+var camelFields = structFieldNames(
+	expandedOrder{}, ErrorResponse{}, Problem{}, batchResponse{},
+	lookupResponse{}, reassignResponse{}, orderStats{}, productCount{},
+	OrderChange{}, reservation{}, healthReport{}, orphanReport{},
+	Flags{}, circuitSnapshot{}, inflightResponse{}, cacheListResponse{},
+	warmupReport{}, ordersSnapshot{}, Config{},
+)
+
+// structFieldNames собирает JSON-имена полей типов roots и всех
+// вложенных в них структур, в том числе в срезах и значениях карт.
+func structFieldNames(roots ...interface{}) map[string]bool {
+	names := map[string]bool{}
+	seen := map[reflect.Type]bool{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			// Встроенная структура без тега раскрывается в родителя
+			if name == "" && !field.Anonymous {
+				name = field.Name
+			}
+			if name != "" {
+				names[name] = true
+			}
+			walk(field.Type)
+		}
+	}
+	for _, root := range roots {
+		walk(reflect.TypeOf(root))
+	}
+	return names
+}
+
+// camelCaseKeys рекурсивно переименовывает ключи объектов, совпадающие с
+// именами полей из camelFields. Значения (например, статусы) не меняются.
+func camelCaseKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if camelFields[key] {
+				key = camelCaseKey(key)
+			}
+			out[key] = camelCaseKeys(value)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = camelCaseKeys(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// caseRecorder буферизует ответ, чтобы переписать его ключи целиком.
+type caseRecorder struct {
+	h    http.Header
+	code int
+	buf  bytes.Buffer
+}
+
+func (c *caseRecorder) Header() http.Header { return c.h }
+
+func (c *caseRecorder) WriteHeader(code int) {
+	if c.code == 0 {
+		c.code = code
+	}
+}
+
+func (c *caseRecorder) Write(p []byte) (int, error) {
+	if c.code == 0 {
+		c.code = http.StatusOK
+	}
+	return c.buf.Write(p)
+}
+
+// jsonCaseMiddleware по ?case=camel переписывает ключи JSON-ответа в
+// camelCase. Хранимые структуры и их теги остаются в snake_case: меняется
+// только представление. Ответы JSON:API и не-JSON не трогаются.
+func jsonCaseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("case") {
+		case "", caseSnake:
+			next.ServeHTTP(w, r)
+			return
+		case caseCamel:
+		default:
+			writeError(w, http.StatusBadRequest, "invalid_query", "case must be snake or camel")
+			return
+		}
+
+		rec := &caseRecorder{h: w.Header()}
+		next.ServeHTTP(rec, r)
+		if rec.code == 0 {
+			rec.code = http.StatusOK
+		}
+
+		body := rec.buf.Bytes()
+		if mediaType, _, _ := mime.ParseMediaType(rec.h.Get("Content-Type")); mediaType == "application/json" && len(body) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			var doc interface{}
+			if err := dec.Decode(&doc); err == nil {
+				if data, err := json.Marshal(camelCaseKeys(doc)); err == nil {
+					body = append(data, '\n')
+				}
+			}
+		}
+
+		w.WriteHeader(rec.code)
+		w.Write(body)
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCamelCaseKey(t *testing.T) {
+	for in, want := range map[string]string{
+		"user_id":           "userId",
+		"reservation_token": "reservationToken",
+		"id":                "id",
+		"ttl_remaining_ms":  "ttlRemainingMs",
+	} {
+		if got := camelCaseKey(in); got != want {
+			t.Errorf("camelCaseKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func decodeObject(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return body
+}
+
+func TestJSONCase_Order(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	snake := decodeObject(t, s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))
+	if _, ok := snake["user_id"]; !ok {
+		t.Errorf("Expected snake_case by default, got: %v", snake)
+	}
+	explicit := decodeObject(t, s.serve(httptest.NewRequest(http.MethodGet, "/orders/1?case=snake", nil)))
+	if _, ok := explicit["created_at"]; !ok {
+		t.Errorf("Expected snake_case for case=snake, got: %v", explicit)
+	}
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1?case=camel", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	camel := decodeObject(t, rec)
+	if camel["userId"] != float64(1) || camel["createdAt"] == nil || camel["status"] != "pending" {
+		t.Errorf("Expected camelCase keys with unchanged values, got: %v", camel)
+	}
+	if _, ok := camel["user_id"]; ok {
+		t.Errorf("Expected no snake_case keys, got: %v", camel)
+	}
+
+	var list []map[string]interface{}
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/orders?case=camel&max_qty=1", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(list) != 1 || list[0]["userId"] != float64(1) {
+		t.Errorf("Expected camelCase keys in list, got: %v", list)
+	}
+}
+
+func TestJSONCase_ErrorsAndValidation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1?case=camel", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got: %d", rec.Code)
+	}
+	if body := decodeObject(t, rec); body["error"] == nil {
+		t.Errorf("Expected error envelope to survive, got: %v", body)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?case=kebab", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown case, got: %d", rec.Code)
+	}
+}
+
+func TestJSONCase_KeepsDataKeys(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	// Имена метрик - данные, а не поля
+	vars := decodeObject(t, s.serve(httptest.NewRequest(http.MethodGet, "/debug/vars?case=camel", nil)))
+	if _, ok := vars["orders_served_degraded_total"]; !ok {
+		t.Errorf("Expected metric names to stay as is, got keys: %v", vars)
+	}
+
+	report := decodeObject(t, s.serve(httptest.NewRequest(http.MethodGet, "/healthz?case=camel", nil)))
+	checks, _ := report["checks"].(map[string]interface{})
+	if _, ok := checks["user_service"]; !ok {
+		t.Errorf("Expected check names to stay as is, got: %v", report["checks"])
+	}
+
+	stats := decodeObject(t, s.serve(httptest.NewRequest(http.MethodGet, "/orders/stats?case=camel", nil)))
+	if _, ok := stats["byStatus"]; !ok {
+		t.Errorf("Expected struct fields in camelCase, got: %v", stats)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Каталог допустимых товаров. По умолчанию выключен, и заказ принимает
+// любую строку в product; с каталогом опечатки в названии отклоняются
+// при создании и замене заказа.
+
+// loadProductCatalog читает каталог из PRODUCT_CATALOG (названия через
+// запятую) или PRODUCT_CATALOG_FILE (по названию в строке, строки с # -
+// комментарии). nil - каталог выключен.
+func loadProductCatalog(getenv func(string) string) ([]string, error) {
+	inline, path := getenv("PRODUCT_CATALOG"), getenv("PRODUCT_CATALOG_FILE")
+	switch {
+	case inline != "" && path != "":
+		return nil, fmt.Errorf("set either PRODUCT_CATALOG or PRODUCT_CATALOG_FILE, not both")
+	case inline != "":
+		products := parseProductList(strings.Split(inline, ","))
+		if len(products) == 0 {
+			return nil, fmt.Errorf("PRODUCT_CATALOG must list at least one product, got %q", inline)
+		}
+		return products, nil
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("PRODUCT_CATALOG_FILE: %w", err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		products := parseProductList(lines)
+		if len(products) == 0 {
+			return nil, fmt.Errorf("PRODUCT_CATALOG_FILE %s lists no products", path)
+		}
+		return products, nil
+	default:
+		return nil, nil
+	}
+}
+
+// parseProductList обрезает пробелы и убирает пустые названия и повторы.
+func parseProductList(names []string) []string {
+	var products []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		products = append(products, name)
+	}
+	return products
+}
+
+func newProductCatalog(products []string) map[string]bool {
+	if len(products) == 0 {
+		return nil
+	}
+	catalog := make(map[string]bool, len(products))
+	for _, product := range products {
+		catalog[product] = true
+	}
+	return catalog
+}
+
+// checkProduct проверяет товар по каталогу. Сравнение точное: у остатков
+// и цен ключ - название как есть.
+func (s *server) checkProduct(product string) error {
+	if s.catalog == nil || s.catalog[product] {
+		return nil
+	}
+	return fmt.Errorf("unknown product %q", product)
+}
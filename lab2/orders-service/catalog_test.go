@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateOrder_ProductCatalog(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	s.catalog = newProductCatalog([]string{"Laptop", "Mouse"})
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Laptop","quantity":1}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a catalog product, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	// Сравнение точное: регистр и опечатки не прощаются
+	for _, product := range []string{"Lapotp", "laptop", "Pen"} {
+		rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"`+product+`","quantity":1}`))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got: %d", product, rec.Code)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), "unknown product") {
+			t.Errorf("Expected unknown product error for %q, got: %s", product, rec.Body)
+		}
+	}
+	if len(s.orders) != 1 {
+		t.Errorf("Expected only the catalog order to be stored, got: %d", len(s.orders))
+	}
+
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Keyboard","quantity":1}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when replacing with an unknown product, got: %d", rec.Code)
+	}
+}
+
+func TestCreateOrder_ProductCatalogDisabled(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Anything at all","quantity":1}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 without a catalog, got: %d (%s)", rec.Code, rec.Body)
+	}
+}
+
+func TestLoadProductCatalog(t *testing.T) {
+	products, err := loadProductCatalog(envMap(map[string]string{"PRODUCT_CATALOG": " Laptop, Mouse,,Laptop "}))
+	if err != nil || !reflect.DeepEqual(products, []string{"Laptop", "Mouse"}) {
+		t.Errorf("Expected [Laptop Mouse], got: %v, %v", products, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "catalog.txt")
+	if err := os.WriteFile(path, []byte("# товары склада\nLaptop\n\n  Wireless Mouse  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	products, err = loadProductCatalog(envMap(map[string]string{"PRODUCT_CATALOG_FILE": path}))
+	if err != nil || !reflect.DeepEqual(products, []string{"Laptop", "Wireless Mouse"}) {
+		t.Errorf("Expected [Laptop Wireless Mouse], got: %v, %v", products, err)
+	}
+
+	if products, err := loadProductCatalog(envMap(nil)); err != nil || products != nil {
+		t.Errorf("Expected disabled catalog by default, got: %v, %v", products, err)
+	}
+}
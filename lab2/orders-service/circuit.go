@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUserServiceCircuitOpen возвращается без обращения к сети, пока
+// автомат разомкнут.
+var ErrUserServiceCircuitOpen = errors.New("user service circuit open")
+
+// Состояния автомата.
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// CircuitBreakerConfig - настройки автомата перед user-service. После
+// FailureThreshold ошибок подряд автомат размыкается и OpenTimeout
+// отклоняет запросы сразу, затем пропускает один пробный запрос.
+type CircuitBreakerConfig struct {
+	// FailureThreshold - число ошибок подряд до размыкания (0 - автомат выключен).
+	FailureThreshold int `json:"failure_threshold"`
+	// OpenTimeout - сколько автомат остается разомкнутым до пробного запроса.
+	OpenTimeout time.Duration `json:"open_timeout"`
+}
+
+const defaultCircuitOpenTimeout = 30 * time.Second
+
+// loadCircuitBreakerConfig читает USER_SERVICE_BREAKER_THRESHOLD и
+// USER_SERVICE_BREAKER_OPEN_TIMEOUT.
+func loadCircuitBreakerConfig(getenv func(string) string) (CircuitBreakerConfig, error) {
+	cfg := CircuitBreakerConfig{OpenTimeout: defaultCircuitOpenTimeout}
+	if raw := getenv("USER_SERVICE_BREAKER_THRESHOLD"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_BREAKER_THRESHOLD must be a non-negative integer, got %q", raw)
+		}
+		cfg.FailureThreshold = v
+	}
+	if raw := getenv("USER_SERVICE_BREAKER_OPEN_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_BREAKER_OPEN_TIMEOUT must be a positive duration, got %q", raw)
+		}
+		cfg.OpenTimeout = d
+	}
+	return cfg, nil
+}
+
+// circuitBreaker - автомат с тремя состояниями. В half-open пропускается
+// ровно один пробный запрос; его исход замыкает или снова размыкает автомат.
+type circuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg, state: circuitClosed}
+}
+
+// Allow решает, пропустить ли запрос. Разомкнутый автомат по истечении
+// OpenTimeout переходит в half-open и пропускает первый запрос как пробный.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen && !now().Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+		b.setState(circuitHalfOpen)
+	}
+	switch b.state {
+	case circuitOpen:
+		return false
+	case circuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// Success отмечает удачный запрос: автомат замыкается.
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.setState(circuitClosed)
+}
+
+// Failure отмечает ошибку user-service. Неудачная проба размыкает автомат
+// сразу, в замкнутом состоянии - по достижении порога.
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == circuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.openedAt = now()
+		b.setState(circuitOpen)
+	}
+}
+
+// Abort освобождает пробу, если запрос отменил сам вызывающий: такой
+// исход ничего не говорит о user-service.
+func (b *circuitBreaker) Abort() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) setState(state string) {
+	if b.state != state {
+		log.Printf("User service circuit: %s -> %s", b.state, state)
+		b.state = state
+	}
+}
+
+// circuitSnapshot - ответ GET /debug/circuit.
+type circuitSnapshot struct {
+	State            string `json:"state"`
+	Failures         int    `json:"failures"`
+	FailureThreshold int    `json:"failure_threshold"`
+	// NextProbeInMS - через сколько разомкнутый автомат пропустит пробу
+	NextProbeInMS int64 `json:"next_probe_in_ms"`
+}
+
+func (b *circuitBreaker) Snapshot() circuitSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snap := circuitSnapshot{State: b.state, Failures: b.failures, FailureThreshold: b.cfg.FailureThreshold}
+	if b.state == circuitOpen {
+		if left := b.openedAt.Add(b.cfg.OpenTimeout).Sub(now()); left > 0 {
+			snap.NextProbeInMS = left.Milliseconds()
+		}
+	}
+	return snap
+}
+
+// getCircuit обрабатывает GET /debug/circuit. Без автомата отдает
+// состояние "disabled".
+func (s *server) getCircuit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	snap := circuitSnapshot{State: "disabled"}
+	if b := s.userClient.Breaker; b != nil {
+		snap = b.Snapshot()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snap)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getCircuitState(t *testing.T, s *server) circuitSnapshot {
+	t.Helper()
+	s.adminEnabled = true
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/debug/circuit", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	var snap circuitSnapshot
+	if err := json.NewDecoder(rec.Body).Decode(&snap); err != nil {
+		t.Fatalf("Failed to decode circuit state: %v", err)
+	}
+	return snap
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	clock := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if !b.Allow() || b.Snapshot().State != circuitClosed {
+		t.Fatal("Expected success to reset the failure count")
+	}
+	b.Failure()
+	if b.Allow() || b.Snapshot().State != circuitOpen {
+		t.Fatal("Expected circuit to open after threshold failures in a row")
+	}
+
+	clock.Set(clock.Now().Add(time.Minute))
+	if !b.Allow() {
+		t.Fatal("Expected a probe after the open timeout")
+	}
+	if b.Allow() {
+		t.Error("Expected only one probe in half-open state")
+	}
+	b.Failure()
+	if b.Snapshot().State != circuitOpen {
+		t.Fatal("Expected a failed probe to reopen the circuit")
+	}
+
+	clock.Set(clock.Now().Add(time.Minute))
+	b.Allow()
+	b.Abort()
+	if !b.Allow() {
+		t.Fatal("Expected an aborted probe to free the slot")
+	}
+	b.Success()
+	if snap := b.Snapshot(); snap.State != circuitClosed || snap.Failures != 0 {
+		t.Errorf("Expected a successful probe to close the circuit, got: %+v", snap)
+	}
+}
+
+func TestDebugCircuit_ReflectsOpenBreaker(t *testing.T) {
+	clock := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newTestServer(t, nil)
+	var calls atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	s.userClient.Breaker = newCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second})
+
+	if snap := getCircuitState(t, s); snap.State != circuitClosed || snap.Failures != 0 {
+		t.Fatalf("Expected a closed circuit, got: %+v", snap)
+	}
+
+	for i := 0; i < 3; i++ {
+		s.userClient.FetchUserByID(context.Background(), 1)
+	}
+	_, err := s.userClient.FetchUserByID(context.Background(), 1)
+	if !errors.Is(err, ErrUserServiceCircuitOpen) {
+		t.Fatalf("Expected ErrUserServiceCircuitOpen, got: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected the open circuit to skip the network, got %d calls", n)
+	}
+
+	clock.Set(clock.Now().Add(10 * time.Second))
+	snap := getCircuitState(t, s)
+	if snap.State != circuitOpen || snap.Failures != 3 || snap.FailureThreshold != 3 {
+		t.Errorf("Expected an open circuit with 3 failures, got: %+v", snap)
+	}
+	if snap.NextProbeInMS != 20000 {
+		t.Errorf("Expected next probe in 20000ms, got: %d", snap.NextProbeInMS)
+	}
+}
+
+func TestDebugCircuit_Disabled(t *testing.T) {
+	s := newTestServer(t, nil)
+	if snap := getCircuitState(t, s); snap.State != "disabled" {
+		t.Errorf("Expected disabled state without a breaker, got: %+v", snap)
+	}
+}
+
+func TestDebugCircuit_AdminOnly(t *testing.T) {
+	s := newTestServer(t, nil)
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/debug/circuit", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 with admin disabled, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrUserNotFound возвращается, когда user-service ответил 404.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserServiceTimeout возвращается, если контекст истек или отменен
+// еще до отправки запроса. Исходная ошибка контекста тоже доступна через errors.Is.
+var ErrUserServiceTimeout = errors.New("user service timeout")
+
+// onBehalfOfHeader несет идентификатор вызывающего пользователя в user-service.
+const onBehalfOfHeader = "X-On-Behalf-Of"
+
+// serviceName представляет сервис в исходящих запросах: по умолчанию это
+// User-Agent и X-Service-Name.
+const serviceName = "orders-service"
+
+const defaultUserAgent = serviceName
+
+type UserServiceClient struct {
+	BaseURL string
+	Client  *http.Client
+
+	// MaxRetries - число повторных попыток при сетевых ошибках и 5xx (0 - без повторов).
+	MaxRetries int
+	// RetryBackoff - базовая задержка между попытками, удваивается с каждой попыткой.
+	RetryBackoff time.Duration
+	// MaxBackoff ограничивает одну паузу между попытками (0 - без ограничения).
+	MaxBackoff time.Duration
+	// Jitter - стратегия разброса пауз: none (по умолчанию), full или decorrelated.
+	Jitter string
+	jitter backoffRand
+	// RetryBudget, если задан, ограничивает повторы всех вызовов вместе:
+	// когда он исчерпан, вызов возвращает ошибку без повтора.
+	RetryBudget *retryBudget
+	// TotalTimeout - общий бюджет времени на все попытки вместе.
+	// Если 0, бюджет ограничен только дедлайном переданного контекста.
+	TotalTimeout time.Duration
+
+	// Trace включает httptrace для исходящих запросов. Выключен по
+	// умолчанию: сбор таймингов добавляет накладные расходы.
+	Trace bool
+	// SlowThreshold - порог, после которого тайминги запроса пишутся в лог.
+	SlowThreshold time.Duration
+	// TraceHook, если задан, получает тайминги каждого трассируемого запроса.
+	TraceHook func(userID int, timings TraceTimings)
+
+	// Cache, если задан, хранит пользователей, полученных по ID.
+	Cache *userCache
+
+	// Shedder, если задан, отбрасывает часть запросов локально, пока
+	// user-service отвечает ошибками или слишком медленно.
+	Shedder *loadShedder
+
+	// MaxConcurrent ограничивает число одновременных запросов к
+	// user-service (0 - без ограничения).
+	MaxConcurrent int
+	// OverloadPolicy - что делать, когда лимит исчерпан: queue (по
+	// умолчанию) ждет слота, reject сразу возвращает ErrUserServiceOverloaded.
+	OverloadPolicy string
+	limiter        upstreamLimiter
+
+	// Breaker, если задан, после серии ошибок на время перестает
+	// обращаться к user-service.
+	Breaker *circuitBreaker
+
+	// DialTimeout, TLSHandshakeTimeout и ResponseHeaderTimeout ограничивают
+	// отдельные фазы запроса: установку соединения, TLS-рукопожатие и
+	// ожидание заголовков ответа (0 - без отдельного ограничения). Чтение
+	// тела ограничено только Client.Timeout.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	phases                phaseClient
+
+	// UserAgent заменяет User-Agent Go по умолчанию, а DefaultHeaders
+	// добавляются к каждому запросу, чтобы в логах user-service было
+	// видно, чей это трафик. Заголовки конкретного запроса
+	// (X-On-Behalf-Of и т.п.) ставятся поверх них.
+	UserAgent      string
+	DefaultHeaders map[string]string
+
+	// Loader, если задан, собирает одиночные GetUserByID в пакетные
+	// запросы GetUsersByIDs.
+	Loader *userLoader
+
+	// Shards, если задан, направляет запросы по ID пользователя в шард,
+	// которому этот ID принадлежит. Поиск по email и имени и проверка
+	// здоровья по-прежнему идут на BaseURL.
+	Shards *hashRing
+}
+
+// userBaseURL возвращает адрес шарда для userID или BaseURL без шардов.
+func (c *UserServiceClient) userBaseURL(userID int) string {
+	if c.Shards != nil {
+		if endpoint := c.Shards.Endpoint(userID); endpoint != "" {
+			return endpoint
+		}
+	}
+	return c.BaseURL
+}
+
+// GetUserByID возвращает пользователя из кэша или запрашивает user-service.
+// С Loader одиночные запросы, пришедшие в пределах его окна, уходят в
+// user-service одним пакетным.
+func (c *UserServiceClient) GetUserByID(ctx context.Context, userID int) (*User, error) {
+	if c.Cache != nil {
+		if user, ok := c.Cache.Get(userID); ok {
+			return user, nil
+		}
+	}
+	if c.Loader != nil {
+		return c.Loader.Load(ctx, userID)
+	}
+	return c.FetchUserByID(ctx, userID)
+}
+
+// FetchUserByID всегда идет в user-service, минуя кэш, и обновляет его.
+func (c *UserServiceClient) FetchUserByID(ctx context.Context, userID int) (*User, error) {
+	user, err := c.getUser(ctx, fmt.Sprintf("%s/users/%d", c.userBaseURL(userID), userID), userID)
+	if c.Cache != nil {
+		switch {
+		case err == nil:
+			c.Cache.Put(*user)
+		case errors.Is(err, ErrUserNotFound):
+			c.Cache.Delete(userID)
+		}
+	}
+	return user, err
+}
+
+// UserExists проверяет пользователя через GET /users/{id}/exists: ответ
+// без тела дешевле полного пользователя. Пользователь из кэша считается
+// существующим без запроса.
+func (c *UserServiceClient) UserExists(ctx context.Context, userID int) (bool, error) {
+	if c.Cache != nil {
+		if _, ok := c.Cache.Get(userID); ok {
+			return true, nil
+		}
+	}
+	err := c.getJSON(ctx, fmt.Sprintf("%s/users/%d/exists", c.userBaseURL(userID), userID), userID, nil)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrUserNotFound):
+		if c.Cache != nil {
+			c.Cache.Delete(userID)
+		}
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// GetUserByEmail ищет пользователя по email (без учета регистра).
+func (c *UserServiceClient) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return c.getUser(ctx, c.BaseURL+"/users/by-email?email="+url.QueryEscape(email), 0)
+}
+
+// SearchUsers ищет пользователей по началу имени или слова в нем через
+// GET /users/search, результаты отсортированы по имени. limit <= 0 -
+// предел user-service по умолчанию. Пустой результат - не ошибка.
+func (c *UserServiceClient) SearchUsers(ctx context.Context, query string, limit int) ([]User, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	var found []User
+	if err := c.getJSON(ctx, c.BaseURL+"/users/search?"+params.Encode(), 0, &found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// getUser выполняет GET target с повторами и возвращает пользователя из
+// ответа. traceID попадает в TraceHook.
+func (c *UserServiceClient) getUser(ctx context.Context, target string, traceID int) (*User, error) {
+	var user User
+	if err := c.getJSON(ctx, target, traceID, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getJSON выполняет GET target с повторами и декодирует ответ 200 в out.
+// out == nil - ответ без тела: успехом тогда считается 204 (так отвечает
+// /users/{id}/exists). Для остальных запросов 204 - неожиданный статус.
+func (c *UserServiceClient) getJSON(ctx context.Context, target string, traceID int, out interface{}) error {
+	// С истекшим контекстом запрос заведомо не удастся - не тратим на него соединение
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrUserServiceTimeout, err)
+	}
+	if c.Shedder != nil && c.Shedder.Reject() {
+		return ErrUserServiceOverloaded
+	}
+	if c.Breaker != nil && !c.Breaker.Allow() {
+		return ErrUserServiceCircuitOpen
+	}
+
+	parent := ctx
+	if c.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.TotalTimeout)
+		defer cancel()
+	}
+
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		if err := spendUpstreamCall(ctx); err != nil {
+			// Лимит входящего запроса ничего не говорит о состоянии user-service
+			if c.Breaker != nil {
+				c.Breaker.Abort()
+			}
+			return err
+		}
+		release, err := c.acquireSlot(ctx)
+		if err != nil {
+			// Локальный лимит ничего не говорит о состоянии user-service
+			if c.Breaker != nil {
+				c.Breaker.Abort()
+			}
+			return err
+		}
+		start := time.Now()
+		retryable, err := c.getOnce(ctx, target, traceID, out)
+		release()
+		if c.Shedder != nil {
+			// Ошибкой сервиса считается то же, что и повод для повтора:
+			// сеть и 5xx. 404 - нормальный ответ
+			c.Shedder.Record(err != nil && retryable, time.Since(start))
+		}
+		if err == nil || !retryable || attempt >= c.MaxRetries {
+			c.recordCircuit(parent, err, retryable)
+			return err
+		}
+
+		if c.RetryBudget != nil && !c.RetryBudget.Withdraw() {
+			userRetriesThrottled.Add(1)
+			c.recordCircuit(parent, err, retryable)
+			return err
+		}
+
+		// Бюджет общий для всех попыток: если его не хватает на паузу,
+		// возвращаем последнюю ошибку вместо новой попытки
+		delay = c.retryDelay(attempt, delay)
+		if !sleepWithinBudget(ctx, delay) {
+			c.recordCircuit(parent, err, retryable)
+			return err
+		}
+	}
+}
+
+// recordCircuit передает автомату исход вызова после всех повторов.
+// Ошибкой считается то же, что и повод для повтора; отмена запроса
+// вызывающим - не ошибка user-service.
+func (c *UserServiceClient) recordCircuit(parent context.Context, err error, retryable bool) {
+	switch {
+	case c.Breaker == nil:
+	case err == nil || !retryable:
+		c.Breaker.Success()
+	case parent.Err() != nil:
+		c.Breaker.Abort()
+	default:
+		c.Breaker.Failure()
+	}
+}
+
+// getOnce делает одну попытку GET target. Первое значение - стоит ли
+// повторять запрос при ошибке.
+func (c *UserServiceClient) getOnce(ctx context.Context, target string, traceID int, out interface{}) (bool, error) {
+	if c.Trace {
+		rec := newTraceRecorder()
+		ctx = httptrace.WithClientTrace(ctx, rec.clientTrace())
+		defer func() { c.reportTrace(traceID, rec.finish()) }()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return false, err
+	}
+	c.setDefaultHeaders(req)
+	// Передаем, от чьего имени идет запрос, чтобы user-service мог это
+	// учесть. Без идентификатора в контексте заголовок не ставится.
+	if caller := callerFromContext(ctx); caller != "" {
+		req.Header.Set(onBehalfOfHeader, caller)
+	}
+	propagateEmbedDepth(req)
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to connect to user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, ErrUserNotFound
+	}
+	// 204 отвечает /users/{id}/exists: пользователь есть, тела нет
+	if resp.StatusCode == http.StatusNoContent && out == nil {
+		return false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode >= 500, fmt.Errorf("user service returned status: %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// setDefaultHeaders ставит на исходящий запрос DefaultHeaders и UserAgent.
+func (c *UserServiceClient) setDefaultHeaders(req *http.Request) {
+	for name, value := range c.DefaultHeaders {
+		req.Header.Set(name, value)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+}
+
+// sleepWithinBudget ждет d, если до дедлайна ctx на это хватает времени.
+func sleepWithinBudget(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -116,3 +119,396 @@ func TestUserServiceClient_GetUserByID_NetworkError(t *testing.T) {
 		t.Error("Should not return 'user not found' for network error")
 	}
 }
+
+func TestUserServiceClient_GetUserByID_RetriesUntilSuccess(t *testing.T) {
+	var calls int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "name": "Alice Johnson", "email": "alice@example.com"}`))
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL:      mockServer.URL,
+		Client:       &http.Client{Timeout: 1 * time.Second},
+		MaxRetries:   3,
+		RetryBackoff: 10 * time.Millisecond,
+	}
+
+	user, err := client.GetUserByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if user.ID != 1 {
+		t.Errorf("Expected user 1, got: %+v", user)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", got)
+	}
+}
+
+func TestUserServiceClient_GetUserByID_NotFoundIsNotRetried(t *testing.T) {
+	var calls int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL:      mockServer.URL,
+		Client:       &http.Client{Timeout: 1 * time.Second},
+		MaxRetries:   3,
+		RetryBackoff: 10 * time.Millisecond,
+	}
+
+	_, err := client.GetUserByID(context.Background(), 999)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Expected ErrUserNotFound, got: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected a single attempt, got: %d", got)
+	}
+}
+
+func TestUserServiceClient_GetUserByID_TotalTimeoutBudget(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Каждая попытка медленная и заканчивается 503
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client: &http.Client{
+			Timeout: 1 * time.Second, // Без бюджета каждая попытка получила бы целую секунду
+		},
+		MaxRetries:   10,
+		RetryBackoff: 20 * time.Millisecond,
+		TotalTimeout: 400 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := client.GetUserByID(context.Background(), 1)
+	duration := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected error after budget exhaustion, got nil")
+	}
+
+	if duration > 550*time.Millisecond {
+		t.Errorf("Retries exceeded the total budget: %v", duration)
+	}
+}
+
+func TestUserServiceClient_GetUserByID_TraceHook(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "name": "Alice Johnson", "email": "alice@example.com"}`))
+	}))
+	defer mockServer.Close()
+
+	var got []TraceTimings
+	client := &UserServiceClient{
+		BaseURL:       mockServer.URL,
+		Client:        &http.Client{Timeout: 1 * time.Second},
+		Trace:         true,
+		SlowThreshold: 10 * time.Millisecond,
+		TraceHook: func(userID int, timings TraceTimings) {
+			got = append(got, timings)
+		},
+	}
+
+	if _, err := client.GetUserByID(context.Background(), 1); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("Expected trace hook to fire once, got: %d", len(got))
+	}
+
+	timings := got[0]
+	if timings.Connect <= 0 {
+		t.Errorf("Expected connect phase to be recorded, got: %+v", timings)
+	}
+	if timings.FirstByte < 20*time.Millisecond || timings.Total < timings.FirstByte {
+		t.Errorf("Expected first byte after server delay and total >= first byte, got: %+v", timings)
+	}
+}
+
+func TestUserServiceClient_GetUserByEmail(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/by-email" || r.URL.Query().Get("email") != "alice+tag@example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "name": "Alice Johnson", "email": "alice+tag@example.com"}`))
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}
+
+	user, err := client.GetUserByEmail(context.Background(), "alice+tag@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Expected user 1, got: %+v", user)
+	}
+
+	if _, err := client.GetUserByEmail(context.Background(), "bob@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+}
+
+func TestUserServiceClient_SearchUsers(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if len(r.URL.Query().Get("q")) < 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("q") != "al ice" || r.URL.Query().Get("limit") != "5" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "name": "Al Ice"}, {"id": 3, "name": "Alice Johnson"}]`))
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}
+
+	found, err := client.SearchUsers(context.Background(), "al ice", 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != 1 || found[1].ID != 3 {
+		t.Errorf("Expected users 1 and 3, got: %+v", found)
+	}
+
+	if found, err := client.SearchUsers(context.Background(), "al ice", 0); err != nil || len(found) != 0 {
+		t.Errorf("Expected empty result without limit, got: %+v, %v", found, err)
+	}
+	if _, err := client.SearchUsers(context.Background(), "a", 0); err == nil {
+		t.Error("Expected error for a rejected query")
+	}
+}
+
+func TestUserServiceClient_GetUserByID_ExpiredContext(t *testing.T) {
+	var calls atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL:    mockServer.URL,
+		Client:     &http.Client{Timeout: 1 * time.Second},
+		MaxRetries: 3,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GetUserByID(ctx, 1)
+	if !errors.Is(err, ErrUserServiceTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ErrUserServiceTimeout wrapping context.Canceled, got: %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected no request to reach the server, got: %d", calls.Load())
+	}
+
+	deadline, cancelDeadline := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelDeadline()
+	if _, err := client.GetUserByID(deadline, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected no request to reach the server, got: %d", calls.Load())
+	}
+}
+
+func TestUserServiceClient_ForwardsCaller(t *testing.T) {
+	headers := make(chan http.Header, 2)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		w.Write([]byte(`{"id": 1, "name": "Alice Johnson"}`))
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}
+
+	if _, err := client.GetUserByID(withCaller(context.Background(), "42"), 1); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := (<-headers).Get("X-On-Behalf-Of"); got != "42" {
+		t.Errorf("Expected X-On-Behalf-Of 42, got: %q", got)
+	}
+
+	if _, err := client.GetUserByID(context.Background(), 1); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if h := <-headers; len(h.Values("X-On-Behalf-Of")) != 0 {
+		t.Errorf("Expected no X-On-Behalf-Of without identity, got: %q", h.Get("X-On-Behalf-Of"))
+	}
+}
+
+func TestUserServiceClient_UserExists(t *testing.T) {
+	var paths []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/users/1/exists":
+			w.WriteHeader(http.StatusNoContent)
+		case "/users/2/exists":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{BaseURL: mockServer.URL, Client: &http.Client{Timeout: 5 * time.Second}}
+	ctx := context.Background()
+
+	if exists, err := client.UserExists(ctx, 1); err != nil || !exists {
+		t.Errorf("Expected user 1 to exist, got: %v, %v", exists, err)
+	}
+	if exists, err := client.UserExists(ctx, 2); err != nil || exists {
+		t.Errorf("Expected user 2 to be missing without error, got: %v, %v", exists, err)
+	}
+	if _, err := client.UserExists(ctx, 3); err == nil {
+		t.Error("Expected an error for a failing user service")
+	}
+
+	// Пользователь из кэша считается существующим без запроса
+	client.Cache = newUserCache(time.Minute)
+	client.Cache.Put(User{ID: 5, Name: "Cached"})
+	paths = nil
+	if exists, err := client.UserExists(ctx, 5); err != nil || !exists || len(paths) != 0 {
+		t.Errorf("Expected a cache hit without requests, got: %v, %v, %v", exists, err, paths)
+	}
+}
+
+func TestUserServiceClient_NoContentOnlyForExists(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{BaseURL: mockServer.URL, Client: &http.Client{Timeout: 5 * time.Second}}
+	ctx := context.Background()
+
+	// Без тела пользователя нет: вызывающие не должны получить nil, nil
+	if user, err := client.GetUserByID(ctx, 1); err == nil || user != nil {
+		t.Errorf("Expected an error for 204 on GET /users/1, got: %+v, %v", user, err)
+	}
+	if user, err := client.GetUserByEmail(ctx, "ann@example.com"); err == nil || user != nil {
+		t.Errorf("Expected an error for 204 on GET /users/by-email, got: %+v, %v", user, err)
+	}
+	if exists, err := client.UserExists(ctx, 1); err != nil || !exists {
+		t.Errorf("Expected 204 to mean the user exists, got: %v, %v", exists, err)
+	}
+}
+
+func TestCreateOrder_ChecksUserExistence(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	var paths []string
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/users/2/exists" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		userServiceStub(w, r)
+	})
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":2,"product":"Pen","quantity":1}`)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a missing user, got: %d", rec.Code)
+	}
+	if len(paths) != 2 || paths[0] != "/users/1/exists" || paths[1] != "/users/2/exists" {
+		t.Errorf("Expected only existence checks, got: %v", paths)
+	}
+
+	// Для проверки подтверждения email нужен пользователь целиком
+	s.setFlags(func(f *Flags) { f.RequireVerifiedUsers = true })
+	paths = nil
+	s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`))
+	if len(paths) != 1 || paths[0] != "/users/1" {
+		t.Errorf("Expected a full user fetch with verification required, got: %v", paths)
+	}
+}
+
+func TestUserServiceClient_DefaultHeaders(t *testing.T) {
+	var mu sync.Mutex
+	var seen []http.Header
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		mu.Unlock()
+		userServiceStub(w, r)
+	}))
+	defer mockServer.Close()
+
+	cfg, err := LoadConfig(envMap(map[string]string{
+		"USER_SERVICE_URL":     mockServer.URL,
+		"USER_SERVICE_HEADERS": "X-Team=checkout",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := newServerFromConfig(cfg).userClient
+
+	if _, err := client.GetUserByID(withCaller(context.Background(), "42"), 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("Expected 2 upstream requests, got: %d", len(seen))
+	}
+	for i, h := range seen {
+		if got := h.Get("User-Agent"); got != "orders-service" {
+			t.Errorf("Request %d: expected User-Agent orders-service, got: %q", i, got)
+		}
+		if h.Get("X-Service-Name") != "orders-service" || h.Get("X-Team") != "checkout" {
+			t.Errorf("Request %d: expected default headers, got: %v", i, h)
+		}
+	}
+	// Заголовки запроса не затираются заголовками по умолчанию
+	if got := seen[0].Get(onBehalfOfHeader); got != "42" {
+		t.Errorf("Expected %s: 42, got: %q", onBehalfOfHeader, got)
+	}
+}
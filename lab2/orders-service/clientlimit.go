@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Что делать с запросом к user-service, когда все слоты заняты.
+const (
+	// overloadQueue - ждать свободного слота, пока не истечет контекст.
+	overloadQueue = "queue"
+	// overloadReject - сразу вернуть ErrUserServiceOverloaded.
+	overloadReject = "reject"
+)
+
+func validOverloadPolicy(policy string) error {
+	switch policy {
+	case "", overloadQueue, overloadReject:
+		return nil
+	default:
+		return fmt.Errorf("unknown overload policy %q: expected queue or reject", policy)
+	}
+}
+
+// upstreamLimiter - семафор на одновременные запросы клиента к
+// user-service. enrichPool ограничивает только обогащение в обработчиках,
+// а клиент вызывают и другие места; лимит здесь общий для всех.
+// Создается при первом использовании по MaxConcurrent.
+type upstreamLimiter struct {
+	once  sync.Once
+	slots chan struct{}
+}
+
+// acquireSlot занимает слот на одну попытку запроса. Возвращенную
+// функцию нужно вызвать по завершении попытки. Без MaxConcurrent лимита нет.
+func (c *UserServiceClient) acquireSlot(ctx context.Context) (func(), error) {
+	if c.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	c.limiter.once.Do(func() {
+		c.limiter.slots = make(chan struct{}, c.MaxConcurrent)
+	})
+	release := func() { <-c.limiter.slots }
+
+	select {
+	case c.limiter.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if c.OverloadPolicy == overloadReject {
+		return nil, ErrUserServiceOverloaded
+	}
+	select {
+	case c.limiter.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrUserServiceTimeout, ctx.Err())
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingUserService считает одновременные запросы и держит каждый,
+// пока не закрыт release.
+func blockingUserService(t *testing.T, release <-chan struct{}) (*UserServiceClient, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var current, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		userServiceStub(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return &UserServiceClient{BaseURL: ts.URL, Client: &http.Client{Timeout: 5 * time.Second}}, &current, &peak
+}
+
+func TestUserServiceClient_MaxConcurrentQueues(t *testing.T) {
+	release := make(chan struct{})
+	client, current, peak := blockingUserService(t, release)
+	client.MaxConcurrent = 3
+
+	const calls = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			_, err := client.FetchUserByID(context.Background(), id)
+			errs <- err
+		}(i + 1)
+	}
+
+	// Ждем, пока слоты заполнятся, и отпускаем запросы
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected queued calls to succeed, got: %v", err)
+		}
+	}
+	if p := peak.Load(); p != 3 {
+		t.Errorf("Expected at most 3 concurrent upstream calls, peak was %d", p)
+	}
+}
+
+func TestUserServiceClient_MaxConcurrentRejects(t *testing.T) {
+	release := make(chan struct{})
+	client, current, _ := blockingUserService(t, release)
+	client.MaxConcurrent = 1
+	client.OverloadPolicy = overloadReject
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.FetchUserByID(context.Background(), 1)
+		done <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := client.FetchUserByID(context.Background(), 2); !errors.Is(err, ErrUserServiceOverloaded) {
+		t.Errorf("Expected ErrUserServiceOverloaded while saturated, got: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the in-flight call to succeed, got: %v", err)
+	}
+	if _, err := client.FetchUserByID(context.Background(), 2); err != nil {
+		t.Errorf("Expected a free slot after release, got: %v", err)
+	}
+}
+
+func TestUserServiceClient_QueueHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	client, current, _ := blockingUserService(t, release)
+	client.MaxConcurrent = 1
+
+	go client.FetchUserByID(context.Background(), 1)
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchUserByID(ctx, 2); !errors.Is(err, ErrUserServiceTimeout) {
+		t.Errorf("Expected ErrUserServiceTimeout while waiting for a slot, got: %v", err)
+	}
+}
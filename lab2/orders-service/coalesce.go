@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Склейка одинаковых обогащений. Когда много клиентов одновременно читают
+// один заказ, каждый GET /orders/{id} запрашивал бы у user-service одного и
+// того же пользователя. Вместо этого первый запрос (ведущий) идет в
+// user-service, а остальные, пришедшие до его ответа, ждут и получают тот
+// же результат. Ответ каждый обработчик пишет сам: общий только
+// пользователь, которого обработчики не меняют (маскирование работает с
+// копией).
+
+// coalescedFetchTimeout ограничивает общий запрос пользователя. Он не
+// зависит от отмены ведущего запроса: если его клиент отключится,
+// остальные все равно дождутся результата.
+const coalescedFetchTimeout = 3 * time.Second
+
+// userFlight - запрос пользователя, который сейчас выполняется.
+type userFlight struct {
+	done chan struct{}
+	user *User
+	err  error
+}
+
+// userFlights - выполняющиеся запросы пользователей по ID.
+type userFlights struct {
+	mu    sync.Mutex
+	calls map[int]*userFlight
+}
+
+func newUserFlights() *userFlights {
+	return &userFlights{calls: map[int]*userFlight{}}
+}
+
+// Do выполняет fetch для userID, если такой запрос еще не выполняется, и
+// иначе ждет результата уже начатого. shared сообщает, что результат
+// получен чужим запросом.
+func (g *userFlights) Do(ctx context.Context, userID int, fetch func(context.Context) (*User, error)) (user *User, shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[userID]; ok {
+		g.mu.Unlock()
+		userFetchesCoalesced.Add(1)
+		select {
+		case <-call.done:
+			return call.user, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	call := &userFlight{done: make(chan struct{})}
+	g.calls[userID] = call
+	g.mu.Unlock()
+
+	flightCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), coalescedFetchTimeout)
+	defer cancel()
+	call.user, call.err = fetch(flightCtx)
+
+	g.mu.Lock()
+	delete(g.calls, userID)
+	g.mu.Unlock()
+	close(call.done)
+	return call.user, false, call.err
+}
+
+// fetchUserCoalesced запрашивает пользователя для встраивания в заказ,
+// склеивая одновременные запросы одного и того же пользователя.
+func (s *server) fetchUserCoalesced(ctx context.Context, userID int) (*User, error) {
+	user, _, err := s.userFlights.Do(ctx, userID, func(ctx context.Context) (*User, error) {
+		res := <-s.fetchUserAsync(ctx, userID)
+		return res.user, res.err
+	})
+	return user, err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrder_CoalescesUserFetches(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	var calls atomic.Int64
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Медленный ответ, чтобы запросы успели наложиться
+		time.Sleep(200 * time.Millisecond)
+		userServiceStub(w, r)
+	})
+
+	const requests = 50
+	recs := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			target := "/orders/1?fields=id,user"
+			if i%2 == 1 {
+				target += "&mask_email=true"
+			}
+			recs[i] = s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, rec := range recs {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got: %d (%s)", i, rec.Code, rec.Body)
+		}
+		order := decodeOrder(t, rec)
+		if order.User == nil || order.User.ID != 1 {
+			t.Fatalf("Request %d: expected embedded user 1, got: %+v", i, order.User)
+		}
+		// Маскирование одного запроса не должно задевать общий результат
+		if masked := strings.Contains(order.User.Email, "***"); masked != (i%2 == 1) {
+			t.Errorf("Request %d: unexpected email %q", i, order.User.Email)
+		}
+	}
+	if got := calls.Load(); got > requests/10 {
+		t.Errorf("Expected far fewer user-service calls than %d requests, got: %d", requests, got)
+	}
+}
+
+func TestUserFlights_FollowerCancellation(t *testing.T) {
+	g := newUserFlights()
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(context.Background(), 1, func(ctx context.Context) (*User, error) {
+			close(started)
+			<-release
+			return &User{ID: 1}, nil
+		})
+		leader <- err
+	}()
+	<-started
+
+	// Отмена ждущего запроса не отменяет общий
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, shared, err := g.Do(ctx, 1, nil); !shared || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled follower, got: shared=%v err=%v", shared, err)
+	}
+
+	close(release)
+	if err := <-leader; err != nil {
+		t.Fatalf("Leader failed: %v", err)
+	}
+
+	// После завершения следующий запрос снова идет сам
+	user, shared, err := g.Do(context.Background(), 1, func(ctx context.Context) (*User, error) {
+		return &User{ID: 1, Name: "fresh"}, nil
+	})
+	if err != nil || shared || user.Name != "fresh" {
+		t.Errorf("Expected a new fetch after the flight ended, got: %+v shared=%v err=%v", user, shared, err)
+	}
+}
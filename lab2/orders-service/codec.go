@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// msgpackMediaType - MessagePack для внутренних клиентов, которым важна
+// скорость разбора. Остальные получают JSON.
+const msgpackMediaType = "application/msgpack"
+
+// Codec сериализует тела запросов и ответов в одном формате.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string                        { return msgpackMediaType }
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpackMarshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpackUnmarshal(data, v) }
+
+// isMsgpackContentType принимает application/msgpack и устаревший
+// application/x-msgpack.
+func isMsgpackContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == msgpackMediaType || mediaType == "application/x-msgpack")
+}
+
+// requestCodec выбирает формат тела запроса по Content-Type.
+func requestCodec(r *http.Request) Codec {
+	if isMsgpackContentType(r.Header.Get("Content-Type")) {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+// responseCodec выбирает формат ответа по Accept. MessagePack отдается,
+// только если клиент явно его перечислил; иначе JSON.
+func responseCodec(r *http.Request) Codec {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if isMsgpackContentType(strings.TrimSpace(part)) {
+			return msgpackCodec{}
+		}
+	}
+	return jsonCodec{}
+}
+
+// decodeBody читает тело запроса в формате из Content-Type. Тело JSON
+// должно содержать одно значение.
+func decodeBody(r *http.Request, v interface{}) error {
+	codec := requestCodec(r)
+	if _, ok := codec.(jsonCodec); ok {
+		return decodeSingleJSON(r.Body, v)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// jsonBody возвращает тело запроса в JSON, переводя из MessagePack при
+// необходимости. Нужен обработчикам, которые разбирают JSON сами.
+func jsonBody(r *http.Request) (io.Reader, error) {
+	if _, ok := requestCodec(r).(msgpackCodec); !ok {
+		return r.Body, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = msgpackToJSON(data); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// writeBody отвечает в формате, выбранном по Accept. Ошибки остаются в
+// JSON: их разбирают и люди, и клиенты без MessagePack.
+func writeBody(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	codec := responseCodec(r)
+	data, err := codec.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	if _, ok := codec.(jsonCodec); ok {
+		// Как у json.Encoder: ответ заканчивается переводом строки
+		data = append(data, '\n')
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(data)
+}
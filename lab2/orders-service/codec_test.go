@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func msgpackRequest(t *testing.T, method, target string, v interface{}) *http.Request {
+	t.Helper()
+	body, err := msgpackMarshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	r.Header.Set("Content-Type", msgpackMediaType)
+	r.Header.Set("Accept", msgpackMediaType)
+	return r
+}
+
+func decodeMsgpack(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != msgpackMediaType {
+		t.Fatalf("Expected MessagePack response, got Content-Type %q (%s)", ct, rec.Body)
+	}
+	if err := msgpackUnmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to decode MessagePack response: %v", err)
+	}
+}
+
+func TestMsgpack_OrderRoundTrip(t *testing.T) {
+	setClock(t, time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(msgpackRequest(t, http.MethodPost, "/orders", Order{UserID: 1, Product: "Pen", Quantity: 3, Tags: []string{"gift"}}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var created Order
+	decodeMsgpack(t, rec, &created)
+	if created.ID == 0 || created.Product != "Pen" || created.Quantity != 3 || !reflect.DeepEqual(created.Tags, []string{"gift"}) {
+		t.Errorf("Unexpected created order: %+v", created)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders/1?fields=id,order_number,user_id,product,quantity,status,created_at,tags", nil)
+	r.Header.Set("Accept", msgpackMediaType)
+	rec = s.serve(r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var fetched Order
+	decodeMsgpack(t, rec, &fetched)
+	if !reflect.DeepEqual(fetched, s.orders[created.ID]) {
+		t.Errorf("Expected stored order %+v, got: %+v", s.orders[created.ID], fetched)
+	}
+}
+
+func TestMsgpack_FallsBackToJSON(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set("Accept", "text/html, */*")
+	rec := s.serve(r)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON without msgpack in Accept, got: %q", ct)
+	}
+
+	// Ошибки всегда в JSON
+	r = msgpackRequest(t, http.MethodPost, "/orders", map[string]interface{}{"quantity": "many"})
+	rec = s.serve(r)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON 400 for invalid MessagePack order, got: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestMsgpack_Encoding(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		want  []byte
+	}{
+		{map[string]interface{}{"a": 1}, []byte{0x81, 0xa1, 'a', 0x01}},
+		{[]interface{}{nil, true, false}, []byte{0x93, 0xc0, 0xc3, 0xc2}},
+		{-1, []byte{0xff}},
+		{-100, []byte{0xd0, 0x9c}},
+		{300, []byte{0xd1, 0x01, 0x2c}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{strings.Repeat("x", 40), append([]byte{0xd9, 40}, strings.Repeat("x", 40)...)},
+	} {
+		got, err := msgpackMarshal(tc.value)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%v: expected % x, got: % x", tc.value, tc.want, got)
+		}
+	}
+}
+
+func TestMsgpack_DecodingErrors(t *testing.T) {
+	var v interface{}
+	for name, data := range map[string][]byte{
+		"truncated string": {0xa5, 'a'},
+		"huge array":       {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"integer key":      {0x81, 0x01, 0x01},
+		"trailing bytes":   {0x01, 0x02},
+		"unsupported type": {0xd4, 0x00, 0x00},
+	} {
+		if err := msgpackUnmarshal(data, &v); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	var n struct {
+		A int64   `json:"a"`
+		B float64 `json:"b"`
+		C string  `json:"c"`
+	}
+	// int16, float32 и str8 из сторонних кодировщиков
+	data := []byte{0x83, 0xa1, 'a', 0xd1, 0xfc, 0x18, 0xa1, 'b', 0xca, 0x3f, 0xc0, 0, 0, 0xa1, 'c', 0xd9, 2, 'h', 'i'}
+	if err := msgpackUnmarshal(data, &n); err != nil || n.A != -1000 || n.B != 1.5 || n.C != "hi" {
+		t.Errorf("Unexpected decode result: %+v (%v)", n, err)
+	}
+}
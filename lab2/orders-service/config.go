@@ -0,0 +1,533 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config - конфигурация сервиса. Заполняется LoadConfig из переменных
+// окружения и передается в сервер целиком.
+//
+// Поля с тегом secret не попадают в /debug/config как есть:
+// secret:"true" заменяется на redactedValue (у карт - каждое значение,
+// ключи видны), secret:"url" - URL со скрытым паролем.
+type Config struct {
+	Addr               string               `json:"addr"`
+	UserServiceURL     string               `json:"user_service_url" secret:"url"`
+	UserServiceShards  []string             `json:"user_service_shards" secret:"url"`
+	UserServiceTimeout time.Duration        `json:"user_service_timeout"`
+	UserDialTimeout    time.Duration        `json:"user_service_dial_timeout"`
+	UserTLSTimeout     time.Duration        `json:"user_service_tls_handshake_timeout"`
+	UserHeaderTimeout  time.Duration        `json:"user_service_response_header_timeout"`
+	UserCacheTTL       time.Duration        `json:"user_cache_ttl"`
+	UserMaxRetries     int                  `json:"user_service_max_retries"`
+	UserRetryBackoff   time.Duration        `json:"user_service_retry_backoff"`
+	UserMaxBackoff     time.Duration        `json:"user_service_max_backoff"`
+	UserRetryJitter    string               `json:"user_service_retry_jitter"`
+	UserMaxConcurrent  int                  `json:"user_service_max_concurrent"`
+	UserOverloadPolicy string               `json:"user_service_overload_policy"`
+	UserAgent          string               `json:"user_service_user_agent"`
+	UserBatchWindow    time.Duration        `json:"user_service_batch_window"`
+	UserBatchMax       int                  `json:"user_service_batch_max"`
+	UserHeaders        map[string]string    `json:"user_service_headers" secret:"true"`
+	MaxUpstreamCalls   int                  `json:"max_upstream_calls_per_request"`
+	ProductsServiceURL string               `json:"products_service_url" secret:"url"`
+	PriceCacheTTL      time.Duration        `json:"price_cache_ttl"`
+	LoadShed           LoadShedConfig       `json:"load_shed"`
+	LoadShedEnabled    bool                 `json:"load_shed_enabled"`
+	RetryBudget        RetryBudgetConfig    `json:"user_service_retry_budget"`
+	CircuitBreaker     CircuitBreakerConfig `json:"circuit_breaker"`
+
+	Server              ServerTimeouts `json:"server"`
+	MaxHeaderBytes      int            `json:"max_header_bytes"`
+	MaxDecompressedBody int64          `json:"max_decompressed_body"`
+	MaxRequestBody      int64          `json:"max_request_body"`
+	RequestTimeout      time.Duration  `json:"request_timeout"`
+	// RouteTimeouts переопределяет RequestTimeout для отдельных маршрутов
+	RouteTimeouts map[string]time.Duration `json:"route_timeouts"`
+	DrainTimeout  time.Duration            `json:"drain_timeout"`
+	CORS          CORSConfig               `json:"cors"`
+	Headers       ResponseHeaders          `json:"response_headers"`
+	Log           LogConfig                `json:"log"`
+	EventQueue    EventQueueConfig         `json:"event_queue"`
+
+	IDStrategy          string        `json:"id_strategy"`
+	AdminEnabled        bool          `json:"admin_enabled"`
+	AuthSecret          string        `json:"auth_jwt_secret" secret:"true"`
+	MaxOrderQuantity    int           `json:"max_order_quantity"`
+	DefaultOrderStatus  string        `json:"default_order_status"`
+	UnknownStatusPolicy string        `json:"unknown_status_policy"`
+	StatusFallback      string        `json:"unknown_status_fallback"`
+	HealthTimeout       time.Duration `json:"health_timeout"`
+	UserServiceCritical bool          `json:"health_user_service_critical"`
+	MaxOrders           int           `json:"max_orders"`
+	MaxRecentOrders     int           `json:"max_recent_orders"`
+	InventoryEnabled    bool          `json:"inventory_enabled"`
+	ReservationTTL      time.Duration `json:"reservation_ttl"`
+	// StartupWait - не принимать трафик, пока зависимости не ответят
+	StartupWait        bool          `json:"startup_wait"`
+	StartupWaitTimeout time.Duration `json:"startup_wait_timeout"`
+	// OrphanCheckInterval - период сверки заказов с user-service; 0 - выключена
+	OrphanCheckInterval time.Duration     `json:"orphan_check_interval"`
+	OrderNumbers        OrderNumberFormat `json:"order_number"`
+	TagLimits           TagLimits         `json:"tag_limits"`
+	ProductCatalog      []string          `json:"product_catalog"`
+
+	// Flags - начальные значения флагов; текущие /debug/config отдает отдельно
+	Flags Flags `json:"-"`
+}
+
+// LoadConfig собирает конфигурацию из переменных окружения, подставляя
+// значения по умолчанию. Возвращает ошибку на первом неверном значении,
+// чтобы сервис не стартовал с частично примененной конфигурацией.
+func LoadConfig(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Addr:                ":8082",
+		UserServiceURL:      "http://localhost:8081",
+		UserServiceTimeout:  5 * time.Second,
+		UserRetryBackoff:    100 * time.Millisecond,
+		UserRetryJitter:     jitterNone,
+		UserOverloadPolicy:  overloadQueue,
+		UserAgent:           defaultUserAgent,
+		UserBatchMax:        defaultUserBatchMax,
+		UserHeaders:         map[string]string{"X-Service-Name": serviceName},
+		ProductsServiceURL:  "http://localhost:8083",
+		PriceCacheTTL:       defaultPriceCacheTTL,
+		MaxDecompressedBody: defaultMaxDecompressedBody,
+		RequestTimeout:      defaultRequestTimeout,
+		IDStrategy:          "sequential",
+		MaxOrderQuantity:    defaultMaxOrderQuantity,
+		MaxRecentOrders:     defaultMaxRecentOrders,
+		DefaultOrderStatus:  "pending",
+		UnknownStatusPolicy: unknownStatusPassthrough,
+		StatusFallback:      "pending",
+		HealthTimeout:       defaultHealthTimeout,
+		ReservationTTL:      defaultReservationTTL,
+		StartupWaitTimeout:  defaultStartupWaitTimeout,
+	}
+
+	var err error
+	if cfg.Flags, err = loadFlags(getenv); err != nil {
+		return cfg, fmt.Errorf("feature flags: %w", err)
+	}
+	if cfg.LoadShed, cfg.LoadShedEnabled, err = loadLoadShedConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("load shedding: %w", err)
+	}
+	if cfg.OrderNumbers, err = loadOrderNumberFormat(getenv); err != nil {
+		return cfg, fmt.Errorf("order numbers: %w", err)
+	}
+	if cfg.ProductCatalog, err = loadProductCatalog(getenv); err != nil {
+		return cfg, fmt.Errorf("product catalog: %w", err)
+	}
+	if cfg.AuthSecret, err = loadAuthSecret(getenv); err != nil {
+		return cfg, fmt.Errorf("auth: %w", err)
+	}
+	if cfg.Flags.RequireAuth && cfg.AuthSecret == "" {
+		return cfg, errors.New("auth: REQUIRE_AUTH needs AUTH_JWT_SECRET")
+	}
+	if cfg.TagLimits, err = loadTagLimits(getenv); err != nil {
+		return cfg, fmt.Errorf("tags: %w", err)
+	}
+	if cfg.RetryBudget, err = loadRetryBudgetConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("retry budget: %w", err)
+	}
+	if cfg.CircuitBreaker, err = loadCircuitBreakerConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("circuit breaker: %w", err)
+	}
+	if cfg.Server, err = loadServerTimeouts(getenv); err != nil {
+		return cfg, fmt.Errorf("server timeouts: %w", err)
+	}
+	if cfg.MaxHeaderBytes, err = loadMaxHeaderBytes(getenv); err != nil {
+		return cfg, fmt.Errorf("header limit: %w", err)
+	}
+	if cfg.MaxRequestBody, err = loadMaxRequestBody(getenv); err != nil {
+		return cfg, fmt.Errorf("body limit: %w", err)
+	}
+	if cfg.DrainTimeout, err = loadDrainTimeout(getenv); err != nil {
+		return cfg, fmt.Errorf("shutdown: %w", err)
+	}
+	if cfg.CORS, err = loadCORSConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("CORS: %w", err)
+	}
+	if cfg.Headers, err = loadResponseHeaders(getenv); err != nil {
+		return cfg, fmt.Errorf("response headers: %w", err)
+	}
+	if cfg.Log, err = loadLogConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("logging: %w", err)
+	}
+	if cfg.EventQueue, err = loadEventQueueConfig(getenv); err != nil {
+		return cfg, fmt.Errorf("event queue: %w", err)
+	}
+
+	if raw := getenv("ROUTE_TIMEOUTS"); raw != "" {
+		if cfg.RouteTimeouts, err = parseRouteTimeouts(raw); err != nil {
+			return cfg, fmt.Errorf("ROUTE_TIMEOUTS: %w", err)
+		}
+	}
+	if raw := getenv("ORDERS_ADDR"); raw != "" {
+		if _, port, err := net.SplitHostPort(raw); err != nil || port == "" {
+			return cfg, fmt.Errorf("ORDERS_ADDR must be host:port or :port, got %q", raw)
+		}
+		cfg.Addr = raw
+	}
+	for _, item := range []struct {
+		key string
+		dst *string
+	}{
+		{"USER_SERVICE_URL", &cfg.UserServiceURL},
+		{"PRODUCTS_SERVICE_URL", &cfg.ProductsServiceURL},
+	} {
+		raw := getenv(item.key)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return cfg, fmt.Errorf("%s must be an absolute http(s) URL, got %q", item.key, raw)
+		}
+		*item.dst = strings.TrimSuffix(raw, "/")
+	}
+	if raw := getenv("USER_SERVICE_SHARDS"); raw != "" {
+		for _, shard := range strings.Split(raw, ",") {
+			shard = strings.TrimSpace(shard)
+			u, err := url.Parse(shard)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return cfg, fmt.Errorf("USER_SERVICE_SHARDS must be a comma-separated list of absolute http(s) URLs, got %q", shard)
+			}
+			cfg.UserServiceShards = append(cfg.UserServiceShards, strings.TrimSuffix(shard, "/"))
+		}
+	}
+	if raw := getenv("USER_SERVICE_USER_AGENT"); raw != "" {
+		cfg.UserAgent = strings.TrimSpace(raw)
+	}
+	for _, item := range splitHeaderList(getenv("USER_SERVICE_HEADERS")) {
+		name, value, err := parseHeaderItem(item)
+		if err != nil {
+			return cfg, fmt.Errorf("USER_SERVICE_HEADERS: %w", err)
+		}
+		if value == "" {
+			delete(cfg.UserHeaders, name)
+			continue
+		}
+		cfg.UserHeaders[name] = value
+	}
+	if raw := getenv("ORDERS_ID_STRATEGY"); raw != "" {
+		if _, err := newIDGenerator(raw); err != nil {
+			return cfg, fmt.Errorf("ORDERS_ID_STRATEGY: %w", err)
+		}
+		cfg.IDStrategy = raw
+	}
+	if raw := getenv("USER_SERVICE_RETRY_JITTER"); raw != "" {
+		if err := validJitter(raw); err != nil {
+			return cfg, fmt.Errorf("USER_SERVICE_RETRY_JITTER: %w", err)
+		}
+		cfg.UserRetryJitter = raw
+	}
+	if raw := getenv("USER_SERVICE_MAX_CONCURRENT"); raw != "" {
+		if cfg.UserMaxConcurrent, err = strconv.Atoi(raw); err != nil || cfg.UserMaxConcurrent < 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_MAX_CONCURRENT must be a non-negative integer, got %q", raw)
+		}
+	}
+	if raw := getenv("USER_SERVICE_BATCH_MAX"); raw != "" {
+		if cfg.UserBatchMax, err = strconv.Atoi(raw); err != nil || cfg.UserBatchMax <= 0 || cfg.UserBatchMax > userLookupMaxIDs {
+			return cfg, fmt.Errorf("USER_SERVICE_BATCH_MAX must be an integer between 1 and %d, got %q", userLookupMaxIDs, raw)
+		}
+	}
+	if raw := getenv("USER_SERVICE_OVERLOAD_POLICY"); raw != "" {
+		if err := validOverloadPolicy(raw); err != nil {
+			return cfg, fmt.Errorf("USER_SERVICE_OVERLOAD_POLICY: %w", err)
+		}
+		cfg.UserOverloadPolicy = raw
+	}
+	if raw := getenv("USER_SERVICE_MAX_RETRIES"); raw != "" {
+		if cfg.UserMaxRetries, err = strconv.Atoi(raw); err != nil || cfg.UserMaxRetries < 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_MAX_RETRIES must be a non-negative integer, got %q", raw)
+		}
+	}
+	if raw := getenv("DEFAULT_ORDER_STATUS"); raw != "" {
+		if !allowedStatuses[raw] {
+			return cfg, fmt.Errorf("DEFAULT_ORDER_STATUS %q is not an allowed status", raw)
+		}
+		cfg.DefaultOrderStatus = raw
+	}
+	if raw := getenv("UNKNOWN_STATUS_POLICY"); raw != "" {
+		if err := validUnknownStatusPolicy(raw); err != nil {
+			return cfg, fmt.Errorf("UNKNOWN_STATUS_POLICY: %w", err)
+		}
+		cfg.UnknownStatusPolicy = raw
+	}
+	if raw := getenv("UNKNOWN_STATUS_FALLBACK"); raw != "" {
+		if !allowedStatuses[raw] {
+			return cfg, fmt.Errorf("UNKNOWN_STATUS_FALLBACK %q is not an allowed status", raw)
+		}
+		cfg.StatusFallback = raw
+	}
+
+	for _, item := range []struct {
+		key       string
+		dst       *time.Duration
+		allowZero bool
+	}{
+		{"USER_SERVICE_TIMEOUT", &cfg.UserServiceTimeout, false},
+		{"USER_SERVICE_DIAL_TIMEOUT", &cfg.UserDialTimeout, true},
+		{"USER_SERVICE_TLS_HANDSHAKE_TIMEOUT", &cfg.UserTLSTimeout, true},
+		{"USER_SERVICE_RESPONSE_HEADER_TIMEOUT", &cfg.UserHeaderTimeout, true},
+		{"USER_SERVICE_BATCH_WINDOW", &cfg.UserBatchWindow, true},
+		{"USER_CACHE_TTL", &cfg.UserCacheTTL, true},
+		{"USER_SERVICE_RETRY_BACKOFF", &cfg.UserRetryBackoff, false},
+		{"USER_SERVICE_MAX_BACKOFF", &cfg.UserMaxBackoff, true},
+		{"PRICE_CACHE_TTL", &cfg.PriceCacheTTL, true},
+		{"REQUEST_TIMEOUT", &cfg.RequestTimeout, false},
+		{"HEALTH_CHECK_TIMEOUT", &cfg.HealthTimeout, false},
+		{"RESERVATION_TTL", &cfg.ReservationTTL, false},
+		{"ORPHAN_CHECK_INTERVAL", &cfg.OrphanCheckInterval, true},
+		{"STARTUP_WAIT_TIMEOUT", &cfg.StartupWaitTimeout, false},
+	} {
+		raw := getenv(item.key)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 || (d == 0 && !item.allowZero) {
+			kind := "a positive"
+			if item.allowZero {
+				kind = "a non-negative"
+			}
+			return cfg, fmt.Errorf("%s must be %s duration, got %q", item.key, kind, raw)
+		}
+		*item.dst = d
+	}
+
+	for _, item := range []struct {
+		key string
+		dst *bool
+	}{
+		{"ADMIN_ENABLED", &cfg.AdminEnabled},
+		{"HEALTH_USER_SERVICE_CRITICAL", &cfg.UserServiceCritical},
+		{"INVENTORY_ENABLED", &cfg.InventoryEnabled},
+		{"STARTUP_WAIT_FOR_DEPENDENCIES", &cfg.StartupWait},
+	} {
+		raw := getenv(item.key)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("%s must be a boolean, got %q", item.key, raw)
+		}
+		*item.dst = v
+	}
+
+	if raw := getenv("MAX_ORDER_QUANTITY"); raw != "" {
+		if cfg.MaxOrderQuantity, err = strconv.Atoi(raw); err != nil || cfg.MaxOrderQuantity <= 0 {
+			return cfg, fmt.Errorf("MAX_ORDER_QUANTITY must be a positive integer, got %q", raw)
+		}
+	}
+	if raw := getenv("ORDERS_MAX_COUNT"); raw != "" {
+		if cfg.MaxOrders, err = strconv.Atoi(raw); err != nil || cfg.MaxOrders < 0 {
+			return cfg, fmt.Errorf("ORDERS_MAX_COUNT must be a non-negative integer, got %q", raw)
+		}
+	}
+	if raw := getenv("MAX_UPSTREAM_CALLS_PER_REQUEST"); raw != "" {
+		if cfg.MaxUpstreamCalls, err = strconv.Atoi(raw); err != nil || cfg.MaxUpstreamCalls < 0 {
+			return cfg, fmt.Errorf("MAX_UPSTREAM_CALLS_PER_REQUEST must be a non-negative integer, got %q", raw)
+		}
+	}
+	if raw := getenv("ORDERS_RECENT_MAX"); raw != "" {
+		if cfg.MaxRecentOrders, err = strconv.Atoi(raw); err != nil || cfg.MaxRecentOrders <= 0 {
+			return cfg, fmt.Errorf("ORDERS_RECENT_MAX must be a positive integer, got %q", raw)
+		}
+	}
+	if raw := getenv("MAX_DECOMPRESSED_BODY_BYTES"); raw != "" {
+		if cfg.MaxDecompressedBody, err = strconv.ParseInt(raw, 10, 64); err != nil || cfg.MaxDecompressedBody <= 0 {
+			return cfg, fmt.Errorf("MAX_DECOMPRESSED_BODY_BYTES must be a positive integer, got %q", raw)
+		}
+	}
+
+	return cfg, nil
+}
+
+// newServerFromConfig собирает сервер по конфигурации. Очередь событий и
+// журнал запросов настраиваются в main: они нужны только живому процессу.
+func newServerFromConfig(cfg Config) *server {
+	s := newServer(&UserServiceClient{
+		BaseURL: cfg.UserServiceURL,
+		Client: &http.Client{
+			Timeout: cfg.UserServiceTimeout,
+		},
+		MaxRetries:   cfg.UserMaxRetries,
+		RetryBackoff: cfg.UserRetryBackoff,
+		MaxBackoff:   cfg.UserMaxBackoff,
+		Jitter:       cfg.UserRetryJitter,
+
+		MaxConcurrent:  cfg.UserMaxConcurrent,
+		OverloadPolicy: cfg.UserOverloadPolicy,
+
+		DialTimeout:           cfg.UserDialTimeout,
+		TLSHandshakeTimeout:   cfg.UserTLSTimeout,
+		ResponseHeaderTimeout: cfg.UserHeaderTimeout,
+
+		UserAgent:      cfg.UserAgent,
+		DefaultHeaders: cfg.UserHeaders,
+	}, cfg.Flags)
+
+	if len(cfg.UserServiceShards) > 0 {
+		s.userClient.Shards = newHashRing(cfg.UserServiceShards)
+	}
+	// Окно 0 - без пакетной загрузки: каждый GetUserByID идет сам
+	if cfg.UserBatchWindow > 0 {
+		s.userClient.Loader = newUserLoader(cfg.UserBatchWindow, cfg.UserBatchMax, s.userClient.GetUsersByIDs)
+	}
+	if cfg.LoadShedEnabled {
+		s.userClient.Shedder = newLoadShedder(cfg.LoadShed, time.Now().UnixNano())
+	}
+	if cfg.RetryBudget.Retries > 0 {
+		s.userClient.RetryBudget = newRetryBudget(cfg.RetryBudget)
+	}
+	if cfg.CircuitBreaker.FailureThreshold > 0 {
+		s.userClient.Breaker = newCircuitBreaker(cfg.CircuitBreaker)
+	}
+	if cfg.UserCacheTTL > 0 {
+		s.userClient.Cache = newUserCache(cfg.UserCacheTTL)
+	}
+	s.productClient = &ProductServiceClient{
+		BaseURL: cfg.ProductsServiceURL,
+		Client:  &http.Client{Timeout: cfg.UserServiceTimeout},
+	}
+	if cfg.PriceCacheTTL > 0 {
+		s.productClient.Cache = newPriceCache(cfg.PriceCacheTTL)
+	}
+	// Стратегия уже проверена в LoadConfig
+	s.ids, _ = newIDGenerator(cfg.IDStrategy)
+	s.numbers = newOrderNumbers(cfg.OrderNumbers)
+	if cfg.MaxOrders > 0 {
+		s.limit = newOrderLRU(cfg.MaxOrders)
+	}
+	if cfg.InventoryEnabled {
+		s.stock = seedStock()
+	}
+
+	s.adminEnabled = cfg.AdminEnabled
+	s.authSecret = []byte(cfg.AuthSecret)
+	s.maxQuantity = cfg.MaxOrderQuantity
+	s.defaultStatus = cfg.DefaultOrderStatus
+	s.unknownStatusPolicy = cfg.UnknownStatusPolicy
+	s.unknownStatusFallback = cfg.StatusFallback
+	s.maxRecent = cfg.MaxRecentOrders
+	s.tagLimits = cfg.TagLimits
+	s.catalog = newProductCatalog(cfg.ProductCatalog)
+	s.maxUpstreamCalls = cfg.MaxUpstreamCalls
+	s.healthTimeout = cfg.HealthTimeout
+	s.userServiceCritical = cfg.UserServiceCritical
+	s.reservationTTL = cfg.ReservationTTL
+	s.config = cfg
+	return s
+}
+
+// redactedValue подставляется вместо секретов.
+const redactedValue = "[REDACTED]"
+
+// debugValue готовит значение конфигурации к выводу: скрывает секреты и
+// печатает длительности в виде "1.5s" вместо наносекунд.
+func debugValue(v reflect.Value) interface{} {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			out[name] = redact(field.Tag.Get("secret"), v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = debugValue(iter.Value())
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = debugValue(v.Index(i))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// redact скрывает значение поля согласно тегу secret. Пустые значения
+// остаются пустыми, чтобы было видно, что секрет не задан.
+func redact(kind string, v reflect.Value) interface{} {
+	switch {
+	case kind == "" || v.IsZero():
+		return debugValue(v)
+	case v.Kind() == reflect.Slice:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = redact(kind, v.Index(i))
+		}
+		return out
+	case v.Kind() == reflect.Map:
+		// Имена заголовков не секретны, а по ним видно, что настроено
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redact(kind, iter.Value())
+		}
+		return out
+	case kind == "url":
+		if u, err := url.Parse(v.String()); err == nil {
+			return u.Redacted()
+		}
+		return redactedValue
+	default:
+		return redactedValue
+	}
+}
+
+// debugConfigResponse - ответ GET /debug/config. Флаги отдаются текущие:
+// их можно поменять без перезапуска через /admin/flags.
+type debugConfigResponse struct {
+	Config interface{} `json:"config"`
+	Flags  Flags       `json:"flags"`
+}
+
+// getDebugConfig обрабатывает GET /debug/config.
+func (s *server) getDebugConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debugConfigResponse{
+		Config: debugValue(reflect.ValueOf(s.config)),
+		Flags:  s.flags.Get(),
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// isJSONContentType принимает application/json и application/*+json
+// с любыми параметрами (например, charset).
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// requireJSONContentType отвечает 415 на POST/PUT/PATCH с телом не в JSON
+// и не в MessagePack.
+// Запросы без тела (например, POST /orders/{id}/restore) пропускаются.
+// enabled проверяется на каждый запрос, чтобы проверку можно было
+// выключить для нестрогих клиентов без перезапуска.
+func requireJSONContentType(enabled func() bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			ct := r.Header.Get("Content-Type")
+			if r.ContentLength != 0 && enabled() && !isJSONContentType(ct) && !isMsgpackContentType(ct) {
+				writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json or application/msgpack")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
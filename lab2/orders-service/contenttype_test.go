@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveChecked прогоняет запрос через проверки тела и роутер, как main.
+func (s *server) serveChecked(r *http.Request) *httptest.ResponseRecorder {
+	cfg := Config{MaxRequestBody: defaultMaxRequestBody, MaxDecompressedBody: defaultMaxDecompressedBody}
+	rec := httptest.NewRecorder()
+	s.bodyChecks(cfg, s.routes()).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestCreateOrder_ContentType(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	const body = `{"user_id":1,"product":"Pen","quantity":1}`
+	for contentType, want := range map[string]int{
+		"":                                  http.StatusUnsupportedMediaType,
+		"text/plain":                        http.StatusUnsupportedMediaType,
+		"application/x-www-form-urlencoded": http.StatusUnsupportedMediaType,
+		"application/json":                  http.StatusCreated,
+		"application/json; charset=utf-8":   http.StatusCreated,
+		"application/merge-patch+json":      http.StatusCreated,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if rec := s.serveChecked(req); rec.Code != want {
+			t.Errorf("Content-Type %q: expected status %d, got: %d", contentType, want, rec.Code)
+		}
+	}
+}
+
+func TestStrictContentType_CanBeDisabled(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	s.setFlags(func(f *Flags) { f.StrictContentType = false })
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"user_id":1,"product":"Pen","quantity":1}`))
+	req.Header.Set("Content-Type", "text/plain")
+	if rec := s.serveChecked(req); rec.Code != http.StatusCreated {
+		t.Errorf("Expected lenient mode to accept the body, got: %d", rec.Code)
+	}
+}
+
+func TestStrictContentType_BodylessPostAllowed(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	if rec := s.serveChecked(httptest.NewRequest(http.MethodPost, "/orders/1/duplicate", nil)); rec.Code != http.StatusCreated {
+		t.Errorf("Expected POST without body to skip the check, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig - настройки CORS. Пустой AllowedOrigins отключает CORS.
+type CORSConfig struct {
+	AllowedOrigins []string
+	// MaxAge - сколько браузер может кешировать ответ на preflight (0 - не отправлять заголовок).
+	MaxAge time.Duration
+	// AllowCredentials разрешает cookies/Authorization в кросс-доменных запросах.
+	// Несовместим с "*": браузер требует конкретный origin.
+	AllowCredentials bool
+}
+
+// loadCORSConfig читает CORS_ALLOWED_ORIGINS, CORS_MAX_AGE (секунды)
+// и CORS_ALLOW_CREDENTIALS.
+func loadCORSConfig(getenv func(string) string) (CORSConfig, error) {
+	var cfg CORSConfig
+	for _, origin := range strings.Split(getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+		}
+	}
+
+	if raw := getenv("CORS_MAX_AGE"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			return cfg, fmt.Errorf("CORS_MAX_AGE must be a non-negative number of seconds, got %q", raw)
+		}
+		cfg.MaxAge = time.Duration(seconds) * time.Second
+	}
+
+	if raw := getenv("CORS_ALLOW_CREDENTIALS"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("CORS_ALLOW_CREDENTIALS must be a boolean, got %q", raw)
+		}
+		cfg.AllowCredentials = v
+	}
+
+	return cfg, cfg.validate()
+}
+
+func (c CORSConfig) validate() error {
+	if c.AllowCredentials && c.allowsAnyOrigin() {
+		return errors.New("CORS credentials cannot be combined with wildcard origin \"*\"")
+	}
+	return nil
+}
+
+func (c CORSConfig) allowsAnyOrigin() bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CORSConfig) allows(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func corsMiddleware(cfg CORSConfig, next http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !cfg.allows(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		if cfg.AllowCredentials || !cfg.allowsAnyOrigin() {
+			// Ответ зависит от Origin, поэтому кеши должны это учитывать
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+		} else {
+			h.Set("Access-Control-Allow-Origin", "*")
+		}
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Preflight отвечаем сами, не доходя до обработчиков
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
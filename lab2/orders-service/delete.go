@@ -0,0 +1,64 @@
+package main
+
+import "net/http"
+
+func parseIncludeDeleted(r *http.Request) (bool, error) {
+	q := newQueryParams(r)
+	v := q.Bool("include_deleted")
+	return v, q.Err()
+}
+
+func (s *server) deleteOrder(w http.ResponseWriter, r *http.Request, id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[id]
+	if !exists || order.DeletedAt != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+	// If-Match защищает от удаления заказа, измененного после того, как
+	// клиент его прочитал. Без заголовка удаление безусловное.
+	if !checkPreconditions(w, r, orderETag(order), true) {
+		return
+	}
+
+	// Удаленный заказ больше не держит товар
+	s.releaseStock(order)
+
+	if s.flags.Get().SoftDelete {
+		deletedAt := now()
+		order.DeletedAt = &deletedAt
+		s.storeOrder(order)
+	} else {
+		s.removeOrder(id)
+	}
+	s.recordOrderAction(id, "deleted", callerFromContext(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) restoreOrder(w http.ResponseWriter, r *http.Request, id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+
+	// Повторное восстановление ничего не меняет и просто возвращает заказ
+	if order.DeletedAt != nil {
+		if err := s.reserveStock(order.Product, order.Quantity); err != nil {
+			writeInsufficientStock(w)
+			return
+		}
+		order.DeletedAt = nil
+		s.storeOrder(order)
+		s.holdStock(id, order.Quantity)
+		s.recordOrderAction(id, "restored", callerFromContext(r.Context()))
+	}
+
+	writeBody(w, r, http.StatusOK, order)
+}
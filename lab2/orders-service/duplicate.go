@@ -0,0 +1,21 @@
+package main
+
+import "net/http"
+
+// duplicateOrder создает копию существующего заказа с новым ID и статусом
+// "pending". Пользователь и остатки проверяются заново, как при обычном создании.
+func (s *server) duplicateOrder(w http.ResponseWriter, r *http.Request, id int) {
+	found, _ := s.findOrders([]int{id})
+	if len(found) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+	source := found[0]
+
+	s.insertOrder(w, r, Order{
+		UserID:   source.UserID,
+		Product:  source.Product,
+		Quantity: source.Quantity,
+		Status:   "pending",
+	}, "")
+}
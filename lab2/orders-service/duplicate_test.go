@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDuplicateOrder(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/2/duplicate", ""))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	order := decodeOrder(t, rec)
+	if order.ID == 2 || order.UserID != 1 || order.Product != "Pen" || order.Quantity != 1 {
+		t.Errorf("Expected a copy of order 2 with a fresh ID, got: %+v", order)
+	}
+	if order.Status != "pending" {
+		t.Errorf("Expected status to be reset to pending, got: %q", order.Status)
+	}
+	if got := s.stockOf("Pen"); got != 9 {
+		t.Errorf("Expected duplicate to reserve stock, got: %d left", got)
+	}
+	if source := s.orders[2]; source.Status != "shipped" {
+		t.Errorf("Expected source order to stay untouched, got: %+v", source)
+	}
+}
+
+func TestDuplicateOrder_MissingSource(t *testing.T) {
+	s := newInventoryServer(t, nil)
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders/99/duplicate", "")); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+
+	// Удаленный заказ findOrders считает отсутствующим
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected delete to succeed, got: %d", rec.Code)
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders/1/duplicate", "")); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a deleted source, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "context"
+
+type userResult struct {
+	user *User
+	err  error
+}
+
+// fetchUserAsync запускает запрос пользователя в фоне, заняв слот в
+// enrichPool. Результат читается из возвращенного канала ровно один раз.
+func (s *server) fetchUserAsync(ctx context.Context, userID int) <-chan userResult {
+	result := make(chan userResult, 1)
+	client := s.userClient
+
+	go func() {
+		select {
+		case s.enrichPool <- struct{}{}:
+		case <-ctx.Done():
+			result <- userResult{err: ctx.Err()}
+			return
+		}
+		defer func() { <-s.enrichPool }()
+
+		user, err := client.GetUserByID(ctx, userID)
+		result <- userResult{user: user, err: err}
+	}()
+
+	return result
+}
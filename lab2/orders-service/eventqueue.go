@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Политики переполнения очереди событий.
+const (
+	// queuePolicyBlock ждет места в очереди не дольше BlockTimeout.
+	queuePolicyBlock = "block"
+	// queuePolicyDropOldest вытесняет самое старое событие из очереди.
+	queuePolicyDropOldest = "drop-oldest"
+	// queuePolicyDropNew отбрасывает новое событие.
+	queuePolicyDropNew = "drop-new"
+)
+
+// EventQueueConfig - настройки очереди публикации событий.
+type EventQueueConfig struct {
+	Size         int
+	Policy       string
+	BlockTimeout time.Duration
+}
+
+var defaultEventQueueConfig = EventQueueConfig{
+	Size:         256,
+	Policy:       queuePolicyBlock,
+	BlockTimeout: 100 * time.Millisecond,
+}
+
+// loadEventQueueConfig читает EVENT_QUEUE_SIZE, EVENT_QUEUE_POLICY и
+// EVENT_QUEUE_BLOCK_TIMEOUT.
+func loadEventQueueConfig(getenv func(string) string) (EventQueueConfig, error) {
+	cfg := defaultEventQueueConfig
+	if raw := getenv("EVENT_QUEUE_SIZE"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("EVENT_QUEUE_SIZE must be a positive integer, got %q", raw)
+		}
+		cfg.Size = n
+	}
+	if raw := getenv("EVENT_QUEUE_POLICY"); raw != "" {
+		switch raw {
+		case queuePolicyBlock, queuePolicyDropOldest, queuePolicyDropNew:
+			cfg.Policy = raw
+		default:
+			return cfg, fmt.Errorf("EVENT_QUEUE_POLICY must be one of %s, %s, %s, got %q",
+				queuePolicyBlock, queuePolicyDropOldest, queuePolicyDropNew, raw)
+		}
+	}
+	if raw := getenv("EVENT_QUEUE_BLOCK_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("EVENT_QUEUE_BLOCK_TIMEOUT must be a positive duration, got %q", raw)
+		}
+		cfg.BlockTimeout = d
+	}
+	return cfg, nil
+}
+
+// eventQueue - ограниченная очередь перед медленным издателем. События
+// доставляет одна горутина-диспетчер в порядке поступления; при
+// переполнении срабатывает политика из конфигурации, а каждое
+// потерянное событие учитывается в events_dropped_total.
+type eventQueue struct {
+	cfg  EventQueueConfig
+	next EventPublisher
+	ch   chan Event
+	done chan struct{}
+
+	// mu защищает closed: Publish держит его на чтение, чтобы Close не
+	// закрыл канал посреди отправки.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newEventQueue(cfg EventQueueConfig, next EventPublisher) *eventQueue {
+	q := &eventQueue{
+		cfg:  cfg,
+		next: next,
+		ch:   make(chan Event, cfg.Size),
+		done: make(chan struct{}),
+	}
+	go q.dispatch()
+	return q
+}
+
+func (q *eventQueue) dispatch() {
+	defer close(q.done)
+	for e := range q.ch {
+		q.next.Publish(e)
+	}
+}
+
+// Publish ставит событие в очередь. Блокироваться дольше BlockTimeout
+// он не может, поэтому его можно вызывать прямо из обработчика.
+func (q *eventQueue) Publish(e Event) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		eventsDropped.Add(1)
+		return
+	}
+
+	switch q.cfg.Policy {
+	case queuePolicyDropNew:
+		select {
+		case q.ch <- e:
+		default:
+			eventsDropped.Add(1)
+		}
+	case queuePolicyDropOldest:
+		for {
+			select {
+			case q.ch <- e:
+				return
+			default:
+			}
+			// Место могли освободить диспетчер или другой издатель -
+			// тогда вытеснять нечего
+			select {
+			case <-q.ch:
+				eventsDropped.Add(1)
+			default:
+			}
+		}
+	default:
+		timer := time.NewTimer(q.cfg.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case q.ch <- e:
+		case <-timer.C:
+			eventsDropped.Add(1)
+		}
+	}
+}
+
+// Close перестает принимать события и ждет, пока диспетчер доставит
+// оставшиеся в очереди. Если ctx истекает раньше, возвращает его ошибку.
+func (q *eventQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedPublisher не доставляет события, пока не открыт gate, и
+// запоминает порядок доставки.
+type gatedPublisher struct {
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+
+	mu  sync.Mutex
+	ids []int
+}
+
+func newGatedPublisher() *gatedPublisher {
+	return &gatedPublisher{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (p *gatedPublisher) Publish(e Event) {
+	p.once.Do(func() { close(p.started) })
+	<-p.gate
+	p.mu.Lock()
+	p.ids = append(p.ids, e.OrderID)
+	p.mu.Unlock()
+}
+
+func (p *gatedPublisher) delivered() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.ids...)
+}
+
+// fillQueue публикует первое событие и ждет, пока диспетчер застрянет на
+// нем, после чего заполняет буфер событиями 2..size+1.
+func fillQueue(t *testing.T, q *eventQueue, p *gatedPublisher, size int) {
+	t.Helper()
+	q.Publish(Event{OrderID: 1})
+	select {
+	case <-p.started:
+	case <-time.After(time.Second):
+		t.Fatal("Dispatcher did not pick up the first event")
+	}
+	for id := 2; id <= size+1; id++ {
+		q.Publish(Event{OrderID: id})
+	}
+}
+
+func closeQueue(t *testing.T, q *eventQueue, p *gatedPublisher) {
+	t.Helper()
+	close(p.gate)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := q.Close(ctx); err != nil {
+		t.Fatalf("Failed to flush queue: %v", err)
+	}
+}
+
+func assertDelivered(t *testing.T, p *gatedPublisher, want ...int) {
+	t.Helper()
+	got := p.delivered()
+	if len(got) != len(want) {
+		t.Fatalf("Expected delivered %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected delivered %v, got: %v", want, got)
+		}
+	}
+}
+
+func TestEventQueue_DropNew(t *testing.T) {
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 2, Policy: queuePolicyDropNew}, p)
+	before := eventsDropped.Value()
+
+	fillQueue(t, q, p, 2)
+	q.Publish(Event{OrderID: 4})
+
+	if dropped := eventsDropped.Value() - before; dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got: %d", dropped)
+	}
+	closeQueue(t, q, p)
+	assertDelivered(t, p, 1, 2, 3)
+}
+
+func TestEventQueue_DropOldest(t *testing.T) {
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 2, Policy: queuePolicyDropOldest}, p)
+	before := eventsDropped.Value()
+
+	fillQueue(t, q, p, 2)
+	q.Publish(Event{OrderID: 4})
+	q.Publish(Event{OrderID: 5})
+
+	if dropped := eventsDropped.Value() - before; dropped != 2 {
+		t.Errorf("Expected 2 dropped events, got: %d", dropped)
+	}
+	closeQueue(t, q, p)
+	assertDelivered(t, p, 1, 4, 5)
+}
+
+func TestEventQueue_BlockTimesOut(t *testing.T) {
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 1, Policy: queuePolicyBlock, BlockTimeout: 20 * time.Millisecond}, p)
+	before := eventsDropped.Value()
+
+	fillQueue(t, q, p, 1)
+	start := time.Now()
+	q.Publish(Event{OrderID: 3})
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("Expected publisher to wait for the timeout, waited: %v", waited)
+	}
+
+	if dropped := eventsDropped.Value() - before; dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got: %d", dropped)
+	}
+	closeQueue(t, q, p)
+	assertDelivered(t, p, 1, 2)
+}
+
+func TestEventQueue_BlockWaitsForRoom(t *testing.T) {
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 1, Policy: queuePolicyBlock, BlockTimeout: time.Second}, p)
+	before := eventsDropped.Value()
+
+	fillQueue(t, q, p, 1)
+	time.AfterFunc(20*time.Millisecond, func() { close(p.gate) })
+	q.Publish(Event{OrderID: 3})
+
+	if dropped := eventsDropped.Value() - before; dropped != 0 {
+		t.Errorf("Expected no dropped events, got: %d", dropped)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to flush queue: %v", err)
+	}
+	assertDelivered(t, p, 1, 2, 3)
+}
+
+func TestStoreOrder_EvictionDoesNotWaitForFullQueue(t *testing.T) {
+	s := newTestServer(t, nil)
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 1, Policy: queuePolicyBlock, BlockTimeout: time.Second}, p)
+	s.events = q
+	s.limit = newOrderLRU(1)
+	fillQueue(t, q, p, 1)
+
+	// Очередь полна: публикация под s.mu держала бы блокировку до BlockTimeout
+	start := time.Now()
+	s.mu.Lock()
+	s.storeOrder(Order{ID: 10, UserID: 1, Product: "Pen", Quantity: 1})
+	s.storeOrder(Order{ID: 11, UserID: 1, Product: "Pen", Quantity: 1})
+	s.mu.Unlock()
+	if held := time.Since(start); held > 500*time.Millisecond {
+		t.Errorf("Expected eviction not to wait for the event queue, held s.mu for %v", held)
+	}
+
+	// Событие вытеснения доставляется, когда в очереди появляется место
+	close(p.gate)
+	deadline := time.Now().Add(time.Second)
+	for len(p.delivered()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assertDelivered(t, p, 1, 2, 10)
+	q.Close(context.Background())
+}
+
+func TestEventQueue_CloseFlushesAndRejects(t *testing.T) {
+	p := newGatedPublisher()
+	q := newEventQueue(EventQueueConfig{Size: 8, Policy: queuePolicyDropNew}, p)
+
+	fillQueue(t, q, p, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); err == nil {
+		t.Error("Expected Close to report unflushed events while the publisher is stuck")
+	}
+
+	before := eventsDropped.Value()
+	q.Publish(Event{OrderID: 99})
+	if dropped := eventsDropped.Value() - before; dropped != 1 {
+		t.Errorf("Expected event after close to be dropped, got: %d", dropped)
+	}
+
+	closeQueue(t, q, p)
+	assertDelivered(t, p, 1, 2, 3, 4, 5, 6)
+}
+
+func TestLoadEventQueueConfig(t *testing.T) {
+	cfg, err := loadEventQueueConfig(envMap(map[string]string{
+		"EVENT_QUEUE_SIZE":          "16",
+		"EVENT_QUEUE_POLICY":        "drop-oldest",
+		"EVENT_QUEUE_BLOCK_TIMEOUT": "1s",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Size != 16 || cfg.Policy != queuePolicyDropOldest || cfg.BlockTimeout != time.Second {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	for _, env := range []map[string]string{
+		{"EVENT_QUEUE_SIZE": "0"},
+		{"EVENT_QUEUE_POLICY": "drop-all"},
+		{"EVENT_QUEUE_BLOCK_TIMEOUT": "soon"},
+	} {
+		if _, err := loadEventQueueConfig(envMap(env)); err == nil {
+			t.Errorf("%v: expected error", env)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Event - доменное событие сервиса заказов.
+type Event struct {
+	Type      string    `json:"type"`
+	OrderID   int       `json:"order_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventPublisher доставляет события подписчикам.
+type EventPublisher interface {
+	Publish(Event)
+}
+
+// logPublisher пишет события в лог; используется, пока нет брокера.
+type logPublisher struct{}
+
+func (logPublisher) Publish(e Event) {
+	data, _ := json.Marshal(e)
+	log.Printf("Event: %s", data)
+}
+
+// publishEvent отправляет событие, не блокируя обработчик надолго:
+// очередь сама ограничивает ожидание, остальным издателям нужна горутина.
+func (s *server) publishEvent(eventType string, orderID int) {
+	e := Event{Type: eventType, OrderID: orderID, Timestamp: now()}
+	publisher := s.events
+	if q, ok := publisher.(*eventQueue); ok {
+		q.Publish(e)
+		return
+	}
+	go publisher.Publish(e)
+}
+
+// publishDetached отправляет события по порядку из отдельной горутины.
+// Нужен, когда вызывающий держит s.mu: с политикой block очередь может
+// ждать места до BlockTimeout, и под s.mu это ожидание остановило бы все
+// чтения и записи заказов.
+func (s *server) publishDetached(eventType string, orderIDs []int) {
+	events := make([]Event, len(orderIDs))
+	for i, id := range orderIDs {
+		events[i] = Event{Type: eventType, OrderID: id, Timestamp: now()}
+	}
+	publisher := s.events
+	go func() {
+		for _, e := range events {
+			publisher.Publish(e)
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expandable - связи заказа, которые можно встроить через ?expand=.
+var expandable = map[string]bool{"user": true}
+
+// parseExpand читает ?expand=user и проверяет имена связей.
+func parseExpand(r *http.Request) (map[string]bool, error) {
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return nil, nil
+	}
+	expand := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !expandable[name] {
+			return nil, fmt.Errorf("unknown expand %q", name)
+		}
+		expand[name] = true
+	}
+	return expand, nil
+}
+
+// unresolvedUsersHeader перечисляет ID пользователей, которых user-service
+// не нашел при встраивании: такие заказы отдаются с "user": null.
+const unresolvedUsersHeader = "X-Unresolved-Users"
+
+// embedUsers встраивает пользователей в заказы списка. Каждый пользователь
+// запрашивается один раз, запросы идут параллельно в пределах enrichPool.
+// Заказы, для которых пользователя получить не удалось, отдаются без него.
+// Возвращает отсортированные ID пользователей, которых больше не существует,
+// и ошибки по ID пользователей, которых встроить не удалось.
+func (s *server) embedUsers(ctx context.Context, list []Order) ([]int, map[int]error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	pending := map[int]<-chan userResult{}
+	for _, order := range list {
+		if _, ok := pending[order.UserID]; !ok {
+			pending[order.UserID] = s.fetchUserAsync(ctx, order.UserID)
+		}
+	}
+
+	users := make(map[int]*User, len(pending))
+	failures := map[int]error{}
+	var unresolved []int
+	for id, ch := range pending {
+		res := <-ch
+		if res.err != nil {
+			failures[id] = res.err
+		}
+		if errors.Is(res.err, ErrUserNotFound) {
+			unresolved = append(unresolved, id)
+			continue
+		}
+		if res.err != nil {
+			log.Printf("Warning: failed to get user %d: %v", id, res.err)
+			continue
+		}
+		users[id] = res.user
+	}
+	sort.Ints(unresolved)
+
+	fallback := s.flags.Get().FallbackUser
+	for i := range list {
+		id := list[i].UserID
+		if user, ok := users[id]; ok {
+			list[i].User = user
+			continue
+		}
+		ordersServedDegraded.Add(1)
+		if err := failures[id]; fallback && !errors.Is(err, ErrUserNotFound) {
+			list[i].User = fallbackUser(id)
+		}
+	}
+	return unresolved, failures
+}
+
+// fallbackUser - заглушка вместо пользователя, которого не удалось
+// получить при включенном флаге fallback_user.
+func fallbackUser(id int) *User {
+	return &User{ID: id}
+}
+
+// expandedOrder - заказ с запрошенной связью user: в отличие от Order,
+// поле присутствует всегда и равно null, если пользователя получить не удалось.
+type expandedOrder struct {
+	Order
+	User      *User            `json:"user"`
+	UserError *userLookupError `json:"user_error,omitempty"`
+}
+
+// projectOrder оставляет в заказе только выбранные поля, в том числе
+// выбранные поля встроенного пользователя. userErr, если задана,
+// добавляется к заказу независимо от выбранных полей.
+func projectOrder(order Order, fields []string, nested map[string][]string, expanded bool, userErr *userLookupError) (interface{}, error) {
+	var v interface{} = order
+	if expanded {
+		v = expandedOrder{Order: order, User: order.User, UserError: userErr}
+	}
+	if fields == nil {
+		return v, nil
+	}
+	body, err := selectFields(v, fields)
+	if err != nil {
+		return nil, err
+	}
+	if expanded && userErr != nil {
+		body["user_error"] = userErr
+	}
+	if userFields, ok := nested["user"]; ok && order.User != nil {
+		if body["user"], err = selectFields(order.User, userFields); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// joinIDs собирает ID через запятую для заголовков ответа.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
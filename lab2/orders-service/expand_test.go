@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func listOrderMaps(t *testing.T, s *server, target string) []map[string]interface{} {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got: %d (%s)", target, rec.Code, rec.Body)
+	}
+	var list []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode orders: %v", err)
+	}
+	return list
+}
+
+func TestGetOrders_NestedUserFieldImpliesExpand(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	var calls atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		userServiceStub(w, r)
+	})
+
+	list := listOrderMaps(t, s, "/orders?fields=id,user.name&min_qty=10")
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 orders, got: %d", len(list))
+	}
+	for _, order := range list {
+		if got := sortedKeys(order); len(got) != 2 || got[0] != "id" || got[1] != "user" {
+			t.Errorf("Expected only id and user, got: %v", got)
+		}
+		user, _ := order["user"].(map[string]interface{})
+		if len(user) != 1 || user["name"] != "User 2" {
+			t.Errorf("Expected user with only name, got: %v", order["user"])
+		}
+	}
+	// Оба заказа принадлежат пользователю 2 - он запрашивается один раз
+	if calls.Load() != 1 {
+		t.Errorf("Expected one user lookup, got: %d", calls.Load())
+	}
+}
+
+func TestGetOrders_ExpandWithFields(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	list := listOrderMaps(t, s, "/orders?expand=user&fields=id,user&max_qty=1")
+	if len(list) != 1 {
+		t.Fatalf("Expected 1 order, got: %d", len(list))
+	}
+	user, _ := list[0]["user"].(map[string]interface{})
+	if user["id"] != float64(1) || user["email"] != "user1@example.com" {
+		t.Errorf("Expected the whole embedded user, got: %v", list[0]["user"])
+	}
+
+	// ?expand без ?fields встраивает пользователя в полный заказ
+	list = listOrderMaps(t, s, "/orders?expand=user&max_qty=1")
+	if _, ok := list[0]["product"]; !ok || list[0]["user"] == nil {
+		t.Errorf("Expected full order with user, got: %v", list[0])
+	}
+
+	// Без expand и без user в fields пользователь не запрашивается
+	list = listOrderMaps(t, s, "/orders?fields=id,user_id&max_qty=1")
+	if _, ok := list[0]["user"]; ok {
+		t.Errorf("Expected no user without expand, got: %v", list[0])
+	}
+}
+
+func TestGetOrders_InvalidFieldsAndExpand(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	for _, target := range []string{
+		"/orders?fields=user.password",
+		"/orders?fields=product.name",
+		"/orders?fields=nope",
+		"/orders?expand=product",
+	} {
+		if rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", target, rec.Code)
+		}
+	}
+}
+
+func TestGetOrders_ExpandWithDeletedUser(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		userServiceStub(w, r)
+	})
+
+	for _, target := range []string{"/orders?expand=user", "/orders?fields=id,user_id,user.name"} {
+		rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got: %d (%s)", target, rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get(unresolvedUsersHeader); got != "2" {
+			t.Errorf("%s: expected %s: 2, got: %q", target, unresolvedUsersHeader, got)
+		}
+
+		var list []map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("Failed to decode orders: %v", err)
+		}
+		if len(list) != 4 {
+			t.Fatalf("%s: expected all 4 orders, got: %d", target, len(list))
+		}
+		for _, order := range list {
+			user, present := order["user"]
+			if !present {
+				t.Errorf("%s: expected user key on order %v", target, order["id"])
+			}
+			if order["user_id"] == float64(2) && user != nil {
+				t.Errorf("%s: expected user null for deleted user, got: %v", target, user)
+			}
+			if order["user_id"] == float64(1) && user == nil {
+				t.Errorf("%s: expected user 1 to be embedded", target)
+			}
+		}
+	}
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if got := rec.Header().Get(unresolvedUsersHeader); got != "" {
+		t.Errorf("Expected no header without expand, got: %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Проверки по заголовкам до чтения тела. Клиент с Expect: 100-continue
+// отправляет тело только после ответа 100 Continue, а net/http отвечает
+// им при первом чтении r.Body. Если отказать раньше, большое тело вообще
+// не будет загружено. Файл одинаков в обоих сервисах.
+
+// defaultMaxRequestBody - предел объявленного размера тела запроса.
+const defaultMaxRequestBody = 1 << 20
+
+// loadMaxRequestBody читает HTTP_MAX_REQUEST_BODY_BYTES.
+func loadMaxRequestBody(getenv func(string) string) (int64, error) {
+	raw := getenv("HTTP_MAX_REQUEST_BODY_BYTES")
+	if raw == "" {
+		return defaultMaxRequestBody, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("HTTP_MAX_REQUEST_BODY_BYTES must be a positive integer, got %q", raw)
+	}
+	return n, nil
+}
+
+// limitRequestBody отвечает 413 на запросы, чей Content-Length больше
+// maxBytes, не читая тело. Тело без Content-Length (chunked) обрезается
+// на maxBytes: чтение сверх предела возвращает ошибку, и обработчик
+// отклоняет запрос как неверное тело.
+func limitRequestBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "body_too_large",
+				"Request body exceeds "+strconv.FormatInt(maxBytes, 10)+" bytes")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watchedBody отмечает, читал ли кто-нибудь тело запроса.
+type watchedBody struct {
+	r    io.Reader
+	read atomic.Bool
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.r.Read(p)
+}
+
+// expectContinueRequest отправляет запрос с Expect: 100-continue и
+// возвращает статус ответа и признак того, что тело было отправлено.
+func expectContinueRequest(t *testing.T, url, contentType, body string) (int, bool) {
+	t.Helper()
+	watched := &watchedBody{r: strings.NewReader(body)}
+	req, err := http.NewRequest(http.MethodPost, url, watched)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Expect", "100-continue")
+
+	client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 5 * time.Second}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, watched.read.Load()
+}
+
+func TestExpectContinue_RejectsBeforeBody(t *testing.T) {
+	cfg, err := LoadConfig(envMap(map[string]string{"HTTP_MAX_REQUEST_BODY_BYTES": "64"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	ts := httptest.NewServer(s.bodyChecks(cfg, s.routes()))
+	defer ts.Close()
+
+	order := `{"user_id":1,"product":"Pen","quantity":1}`
+
+	status, sent := expectContinueRequest(t, ts.URL+"/orders", "application/json", order+strings.Repeat(" ", 64))
+	if status != http.StatusRequestEntityTooLarge || sent {
+		t.Errorf("Oversized body: expected 413 without upload, got: %d (body sent: %v)", status, sent)
+	}
+
+	status, sent = expectContinueRequest(t, ts.URL+"/orders", "text/plain", order)
+	if status != http.StatusUnsupportedMediaType || sent {
+		t.Errorf("Wrong Content-Type: expected 415 without upload, got: %d (body sent: %v)", status, sent)
+	}
+
+	status, sent = expectContinueRequest(t, ts.URL+"/orders", "application/json", order)
+	if status != http.StatusCreated || !sent {
+		t.Errorf("Valid request: expected 201 after upload, got: %d (body sent: %v)", status, sent)
+	}
+}
+
+func TestLoadMaxRequestBody(t *testing.T) {
+	if n, err := loadMaxRequestBody(envMap(nil)); err != nil || n != defaultMaxRequestBody {
+		t.Errorf("Expected default %d, got: %d (%v)", defaultMaxRequestBody, n, err)
+	}
+	if n, err := loadMaxRequestBody(envMap(map[string]string{"HTTP_MAX_REQUEST_BODY_BYTES": "2048"})); err != nil || n != 2048 {
+		t.Errorf("Expected 2048, got: %d (%v)", n, err)
+	}
+	for _, raw := range []string{"0", "-1", "big"} {
+		if _, err := loadMaxRequestBody(envMap(map[string]string{"HTTP_MAX_REQUEST_BODY_BYTES": raw})); err == nil {
+			t.Errorf("%q: expected error", raw)
+		}
+	}
+}
+
+func TestLimitRequestBody_ChunkedBody(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	h := s.bodyChecks(Config{MaxRequestBody: 64, MaxDecompressedBody: defaultMaxDecompressedBody}, s.routes())
+
+	// Без Content-Length размер не известен заранее: тело обрезается на пределе
+	req := jsonRequest(http.MethodPost, "/orders", strings.Repeat(" ", 64)+`{"user_id":1,"product":"Pen","quantity":1}`)
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body over the limit, got: %d", rec.Code)
+	}
+	if len(s.orders) != 0 {
+		t.Errorf("Expected no order to be created, got: %v", s.orders)
+	}
+}
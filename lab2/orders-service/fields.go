@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// parseFields читает ?fields=a,b и проверяет имена по JSON-тегам model.
+// Возвращает nil, если параметр не передан.
+func parseFields(r *http.Request, model interface{}) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(model)
+	var fields []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// parseNestedFields работает как parseFields, но допускает поля вложенных
+// объектов через точку: ?fields=id,user.name. nested задает, какие поля
+// можно раскрывать и по какой модели проверять их имена. Запрос вложенного
+// поля неявно добавляет в выборку сам объект ("user"). Возвращает поля
+// верхнего уровня и выбранные поля для каждого вложенного объекта.
+func parseNestedFields(r *http.Request, model interface{}, nested map[string]interface{}) ([]string, map[string][]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil, nil
+	}
+
+	known := jsonFieldNames(model)
+	var fields []string
+	sub := map[string][]string{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		parent, child, isNested := strings.Cut(name, ".")
+		if !isNested {
+			if !known[name] {
+				return nil, nil, fmt.Errorf("unknown field %q", name)
+			}
+			if !hasField(fields, name) {
+				fields = append(fields, name)
+			}
+			continue
+		}
+
+		nestedModel, ok := nested[parent]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q", name)
+		}
+		if !jsonFieldNames(nestedModel)[child] {
+			return nil, nil, fmt.Errorf("unknown field %q", name)
+		}
+		if !hasField(fields, parent) {
+			fields = append(fields, parent)
+		}
+		sub[parent] = append(sub[parent], child)
+	}
+	return fields, sub, nil
+}
+
+func jsonFieldNames(model interface{}) map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(model)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// selectFields оставляет в сериализованном v только перечисленные поля.
+// Поля, опущенные через omitempty, в ответ не попадают.
+func selectFields(v interface{}, fields []string) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected, nil
+}
+
+func hasField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Flags - переключатели поведения, которые можно менять без перезапуска
+// через POST /admin/flags.
+type Flags struct {
+	// RequireVerifiedUsers запрещает заказы от пользователей с
+	// неподтвержденным email.
+	RequireVerifiedUsers bool `json:"require_verified_users"`
+	// SoftDelete включает мягкое удаление: DELETE только помечает заказ
+	// через DeletedAt. При false заказ удаляется из хранилища насовсем.
+	SoftDelete bool `json:"soft_delete"`
+	// EmbedUser встраивает пользователя в GET /orders/{id}, если клиент
+	// не выбрал поля через ?fields= и не указал профиль в Accept. Раньше
+	// был включен по умолчанию; теперь пользователь встраивается только по
+	// профилю with-user, а EMBED_USER_DEFAULT=true возвращает старое поведение.
+	EmbedUser bool `json:"embed_user"`
+	// MaskEmails скрывает email встроенного пользователя в ответах,
+	// если клиент не передал ?mask_email= явно.
+	MaskEmails bool `json:"mask_emails"`
+	// RecheckUserOnCreate повторно проверяет пользователя перед вставкой
+	// заказа. Стоит лишнего запроса к user-service на каждое создание.
+	RecheckUserOnCreate bool `json:"recheck_user_on_create"`
+	// StrictContentType отвечает 415 на запись с Content-Type не JSON.
+	StrictContentType bool `json:"strict_content_type"`
+	// ShadowOrderStore дублирует записи заказов в новое хранилище и
+	// сверяет с ним чтения, не меняя ответов.
+	ShadowOrderStore bool `json:"shadow_order_store"`
+	// ProblemJSON отдает ошибки в формате application/problem+json, даже
+	// если клиент не запросил его в Accept.
+	ProblemJSON bool `json:"problem_json"`
+	// FallbackUser встраивает вместо недоступного пользователя заглушку
+	// только с ID, чтобы клиенты не обрабатывали "user": null. Не
+	// найденный в user-service пользователь заглушкой не подменяется.
+	FallbackUser bool `json:"fallback_user"`
+	// RequireAuth отвечает 401 на запросы без токена в Authorization,
+	// кроме проб. Требует AUTH_JWT_SECRET.
+	RequireAuth bool `json:"require_auth"`
+}
+
+var defaultFlags = Flags{
+	SoftDelete: true,
+
+	StrictContentType: true,
+}
+
+// loadFlags читает начальные значения флагов: REQUIRE_VERIFIED_USERS,
+// ORDERS_DELETE_MODE=soft|hard, EMBED_USER_DEFAULT, MASK_EMAILS,
+// RECHECK_USER_ON_CREATE, STRICT_CONTENT_TYPE, SHADOW_ORDER_STORE,
+// PROBLEM_JSON, FALLBACK_USER и REQUIRE_AUTH.
+func loadFlags(getenv func(string) string) (Flags, error) {
+	f := defaultFlags
+
+	switch mode := getenv("ORDERS_DELETE_MODE"); mode {
+	case "", "soft":
+		f.SoftDelete = true
+	case "hard":
+		f.SoftDelete = false
+	default:
+		return f, fmt.Errorf("ORDERS_DELETE_MODE must be soft or hard, got %q", mode)
+	}
+
+	for _, item := range []struct {
+		key string
+		dst *bool
+	}{
+		{"REQUIRE_VERIFIED_USERS", &f.RequireVerifiedUsers},
+		{"EMBED_USER_DEFAULT", &f.EmbedUser},
+		{"MASK_EMAILS", &f.MaskEmails},
+		{"RECHECK_USER_ON_CREATE", &f.RecheckUserOnCreate},
+		{"STRICT_CONTENT_TYPE", &f.StrictContentType},
+		{"SHADOW_ORDER_STORE", &f.ShadowOrderStore},
+		{"PROBLEM_JSON", &f.ProblemJSON},
+		{"FALLBACK_USER", &f.FallbackUser},
+		{"REQUIRE_AUTH", &f.RequireAuth},
+	} {
+		raw := getenv(item.key)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return f, fmt.Errorf("%s must be a boolean, got %q", item.key, raw)
+		}
+		*item.dst = v
+	}
+
+	return f, nil
+}
+
+// flagStore дает потокобезопасный доступ к текущим флагам.
+type flagStore struct {
+	mu    sync.RWMutex
+	flags Flags
+}
+
+func newFlagStore(f Flags) *flagStore {
+	return &flagStore{flags: f}
+}
+
+func (fs *flagStore) Get() Flags {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.flags
+}
+
+func (fs *flagStore) Set(f Flags) {
+	fs.mu.Lock()
+	fs.flags = f
+	fs.mu.Unlock()
+}
+
+// CompareAndSet заменяет флаги на updated, только если текущие все еще
+// равны old: так частичное обновление не затирает параллельное.
+func (fs *flagStore) CompareAndSet(old, updated Flags) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.flags != old {
+		return false
+	}
+	fs.flags = updated
+	return true
+}
+
+// adminOnly скрывает эндпоинт, если админка выключена (ADMIN_ENABLED).
+func (s *server) adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.adminEnabled {
+			notFound(w, r)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleFlags: GET возвращает текущие флаги, POST частично обновляет их.
+// В теле POST достаточно передать только меняемые флаги.
+func (s *server) handleFlags(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		// Тело читается без блокировки: медленный клиент не должен
+		// задерживать обработчики, читающие флаги
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+			return
+		}
+		for {
+			current := s.flags.Get()
+			updated := current
+			if err := decodeStrictJSON(bytes.NewReader(body), &updated); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+				return
+			}
+			if updated.RequireAuth && len(s.authSecret) == 0 {
+				writeError(w, http.StatusBadRequest, "invalid_body", "require_auth needs AUTH_JWT_SECRET")
+				return
+			}
+			if s.flags.CompareAndSet(current, updated) {
+				break
+			}
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.flags.Get())
+}
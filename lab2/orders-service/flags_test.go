@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func decodeFlags(t *testing.T, rec *httptest.ResponseRecorder) Flags {
+	t.Helper()
+	var f Flags
+	if err := json.NewDecoder(rec.Body).Decode(&f); err != nil {
+		t.Fatalf("Failed to decode flags: %v", err)
+	}
+	return f
+}
+
+func TestAdminFlags_DisabledByDefault(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/flags", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected admin endpoint to be hidden, got: %d", rec.Code)
+	}
+}
+
+func TestAdminFlags_ToggleAtRuntime(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.adminEnabled = true
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/flags", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if f := decodeFlags(t, rec); f != defaultFlags {
+		t.Errorf("Expected default flags, got: %+v", f)
+	}
+
+	rec = s.serve(jsonRequest(http.MethodPost, "/admin/flags", `{"soft_delete": false}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	want := defaultFlags
+	want.SoftDelete = false
+	if f := decodeFlags(t, rec); f != want {
+		t.Errorf("Expected only soft_delete to change, got: %+v", f)
+	}
+
+	// Обработчик сразу видит новое значение: удаление становится жестким
+	s.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if _, ids := listOrderIDs(t, s, "/orders?include_deleted=true"); len(ids) != 3 {
+		t.Errorf("Expected order to be hard-deleted after toggle, got: %v", ids)
+	}
+}
+
+func TestAdminFlags_UnknownFlag(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.adminEnabled = true
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/admin/flags", `{"no_such_flag": true}`)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	f, err := loadFlags(envMap(map[string]string{
+		"ORDERS_DELETE_MODE":     "hard",
+		"REQUIRE_VERIFIED_USERS": "true",
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if f.SoftDelete || !f.RequireVerifiedUsers || f.EmbedUser {
+		t.Errorf("Unexpected flags: %+v", f)
+	}
+
+	if _, err := loadFlags(envMap(map[string]string{"ORDERS_DELETE_MODE": "archive"})); err == nil {
+		t.Error("Expected error for invalid delete mode, got nil")
+	}
+}
+
+func TestAdminFlags_SlowBodyDoesNotBlockReaders(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.adminEnabled = true
+
+	body, writer := io.Pipe()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		r := httptest.NewRequest(http.MethodPost, "/admin/flags", body)
+		r.Header.Set("Content-Type", "application/json")
+		done <- s.serve(r)
+	}()
+	writer.Write([]byte(`{"problem_json":`))
+
+	// Тело еще не дочитано, но флаги читаются без ожидания
+	read := make(chan Flags)
+	go func() { read <- s.flags.Get() }()
+	select {
+	case f := <-read:
+		if f.ProblemJSON {
+			t.Errorf("Expected flags to stay unchanged until the body is read, got: %+v", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected flag readers not to wait for the request body")
+	}
+
+	writer.Write([]byte(` true}`))
+	writer.Close()
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if !s.flags.Get().ProblemJSON {
+		t.Error("Expected problem_json to be set")
+	}
+}
+
+func TestFlagStore_CompareAndSet(t *testing.T) {
+	fs := newFlagStore(defaultFlags)
+	stale := fs.Get()
+	updated := stale
+	updated.MaskEmails = true
+	fs.Set(updated)
+
+	other := stale
+	other.ProblemJSON = true
+	if fs.CompareAndSet(stale, other) {
+		t.Error("Expected stale update to be rejected")
+	}
+	if got := fs.Get(); !got.MaskEmails || got.ProblemJSON {
+		t.Errorf("Expected concurrent update to be kept, got: %+v", got)
+	}
+}
+
+func TestAdminFlags_FallbackUser(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.adminEnabled = true
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if order := decodeOrder(t, s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))); order.User != nil {
+		t.Fatalf("Expected no user while fallback_user is off, got: %+v", order.User)
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/admin/flags", `{"fallback_user": true}`)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	order := decodeOrder(t, s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/1", nil))))
+	if order.User == nil || order.User.ID != order.UserID || order.User.Name != "" {
+		t.Errorf("Expected placeholder user with only the ID, got: %+v", order.User)
+	}
+}
+
+func TestAdminFlags_FallbackUserSkipsMissingUsers(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.setFlags(func(f *Flags) { f.FallbackUser = true })
+	useUserService(t, s, http.NotFound)
+
+	if order := decodeOrder(t, s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))); order.User != nil {
+		t.Errorf("Expected no placeholder for a user that does not exist, got: %+v", order.User)
+	}
+}
+
+func TestAdminFlags_RequireAuth(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.adminEnabled = true
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/admin/flags", `{"require_auth": true}`)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without AUTH_JWT_SECRET, got: %d", rec.Code)
+	}
+
+	s.authSecret = []byte(testAuthSecret)
+	if rec := s.serve(jsonRequest(http.MethodPost, "/admin/flags", `{"require_auth": true}`)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	h := s.authenticate(s.routes())
+	for path, want := range map[string]int{"/orders": http.StatusUnauthorized, "/live": http.StatusOK} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got: %d", path, want, rec.Code)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set("Authorization", "Bearer "+signJWT(`{"alg":"HS256"}`, `{"sub":"42"}`, testAuthSecret))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected authenticated request to pass, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxDecompressedBody - предел размера распакованного тела запроса.
+const defaultMaxDecompressedBody = 1 << 20
+
+// gzipRequestMiddleware прозрачно распаковывает тела с Content-Encoding: gzip.
+// Тело распаковывается целиком до вызова обработчика, не больше maxBytes,
+// чтобы маленький архив не мог развернуться в гигабайты (zip bomb).
+func gzipRequestMiddleware(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeGzipError(w, err)
+			return
+		}
+		defer zr.Close()
+
+		body, err := io.ReadAll(io.LimitReader(zr, maxBytes+1))
+		if err != nil {
+			writeGzipError(w, err)
+			return
+		}
+		if int64(len(body)) > maxBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "body_too_large",
+				"Decompressed body exceeds "+strconv.FormatInt(maxBytes, 10)+" bytes")
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Del("Content-Encoding")
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeGzipError отвечает на ошибку чтения сжатого тела. Сжатое тело без
+// Content-Length (chunked) обрезает limitRequestBody, и превышение его
+// предела - 413, как для тела с большим Content-Length, а не битый gzip.
+func writeGzipError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "body_too_large",
+			"Request body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid_gzip", "Malformed gzip body")
+}
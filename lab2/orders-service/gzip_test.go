@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func gzipBody(t *testing.T, data string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("Failed to compress body: %v", err)
+	}
+	zw.Close()
+	return &buf
+}
+
+func serveGzip(s *server, maxBytes int64, body *bytes.Buffer) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	gzipRequestMiddleware(maxBytes, s.routes()).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipRequest_CreatesOrder(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	rec := serveGzip(s, defaultMaxDecompressedBody, gzipBody(t, `{"user_id":1,"product":"Pen","quantity":2,"status":"pending"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.Product != "Pen" || order.Quantity != 2 {
+		t.Errorf("Expected decompressed order to be stored, got: %+v", order)
+	}
+}
+
+func TestGzipRequest_Malformed(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec := serveGzip(s, defaultMaxDecompressedBody, bytes.NewBufferString("definitely not gzip"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+func TestGzipRequest_DecompressedSizeLimit(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	// Сжимается до сотни байт, но распаковывается в 64 КиБ
+	body := gzipBody(t, `{"product":"`+strings.Repeat("a", 64<<10)+`"}`)
+	rec := serveGzip(s, 1024, body)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got: %d", rec.Code)
+	}
+}
+
+func TestGzipRequest_ChunkedBodyOverLimit(t *testing.T) {
+	s := newTestServer(t, nil)
+	h := s.bodyChecks(Config{MaxRequestBody: 256, MaxDecompressedBody: defaultMaxDecompressedBody}, s.routes())
+
+	// Сжатое тело само больше предела, а Content-Length у него нет
+	numbers := make([]string, 1000)
+	for i := range numbers {
+		numbers[i] = strconv.Itoa(i * 7919)
+	}
+	body := gzipBody(t, `{"user_id":1,"product":"`+strings.Join(numbers, ",")+`","quantity":1}`)
+	if body.Len() <= 256 {
+		t.Fatalf("Expected compressed body over the limit, got %d bytes", body.Len())
+	}
+	req := httptest.NewRequest(http.MethodPost, "/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "body_too_large") {
+		t.Errorf("Expected status 413 body_too_large, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if len(s.orders) != 0 {
+		t.Errorf("Expected no order to be created, got: %v", s.orders)
+	}
+}
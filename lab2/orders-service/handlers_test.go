@@ -0,0 +1,759 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotFound_UnknownRoute(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t, nil).routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/does-not-exist", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got: %d", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got: %q", ct)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+
+	if resp.Error.Code != "not_found" {
+		t.Errorf("Expected code 'not_found', got: %q", resp.Error.Code)
+	}
+}
+
+func TestNotFound_DoesNotShadowOrdersPrefix(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t, nil).routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/abc", nil))
+
+	// Ответ должен прийти от обработчика /orders/, а не от catch-all
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 from /orders/ handler, got: %d", rec.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+
+	if resp.Error.Code != "invalid_id" {
+		t.Errorf("Expected code 'invalid_id', got: %q", resp.Error.Code)
+	}
+}
+
+// newTestServer создает сервер с заданными заказами и флагами по умолчанию.
+func newTestServer(t *testing.T, data map[int]Order) *server {
+	t.Helper()
+	s := newServer(&UserServiceClient{
+		BaseURL: "http://127.0.0.1:0",
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}, defaultFlags)
+	s.events = discardPublisher{}
+
+	list := make([]Order, 0, len(data))
+	for _, order := range data {
+		list = append(list, order)
+	}
+	s.loadOrders(list)
+	return s
+}
+
+// useUserService направляет userClient сервера на мок user-service.
+func useUserService(t *testing.T, s *server, handler http.HandlerFunc) {
+	t.Helper()
+	mockServer := httptest.NewServer(handler)
+	t.Cleanup(mockServer.Close)
+	s.userClient = &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}
+}
+
+// setFlags меняет флаги сервера так же, как это сделал бы POST /admin/flags.
+func (s *server) setFlags(update func(*Flags)) {
+	f := s.flags.Get()
+	update(&f)
+	s.flags.Set(f)
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(Event) {}
+
+// userServiceStub отвечает пользователем с запрошенным ID, а на
+// /users/{id}/exists - 204.
+func userServiceStub(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/exists") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/users/")
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id": %s, "name": "User %s", "email": "user%s@example.com"}`, id, id, id)
+}
+
+// serve прогоняет запрос через роутер и возвращает записанный ответ.
+func (s *server) serve(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, r)
+	return rec
+}
+
+func jsonRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func listOrderIDs(t *testing.T, s *server, target string) (int, []int) {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	var list []Order
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode orders: %v", err)
+	}
+	ids := make([]int, 0, len(list))
+	for _, o := range list {
+		ids = append(ids, o.ID)
+	}
+	sort.Ints(ids)
+	return rec.Code, ids
+}
+
+func quantityDataset() map[int]Order {
+	return map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending"},
+		2: {ID: 2, UserID: 1, Product: "Paper", Quantity: 5, Status: "pending"},
+		3: {ID: 3, UserID: 2, Product: "Ink", Quantity: 10, Status: "shipped"},
+		4: {ID: 4, UserID: 2, Product: "Desk", Quantity: 20, Status: "pending"},
+	}
+}
+
+func TestGetOrders_QuantityFilter(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"min only", "/orders?min_qty=10", []int{3, 4}},
+		{"max only", "/orders?max_qty=5", []int{1, 2}},
+		{"range", "/orders?min_qty=5&max_qty=10", []int{2, 3}},
+		{"no filter", "/orders", []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := listOrderIDs(t, s, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("Expected status 200, got: %d", code)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Expected orders %v, got: %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestGetOrders_QuantityFilterValidation(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	for _, query := range []string{
+		"/orders?min_qty=-1",
+		"/orders?max_qty=abc",
+		"/orders?min_qty=10&max_qty=5",
+	} {
+		if code, _ := listOrderIDs(t, s, query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", query, code)
+		}
+	}
+}
+
+func TestGetOrderHistory_ReflectsChanges(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	req := jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Book","quantity":1,"status":"pending"}`)
+	rec := s.serve(req.WithContext(withCaller(req.Context(), "42")))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	rec = s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Book","quantity":1,"status":"shipped"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/orders/1/history", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	var entries []OrderChange
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 history entries, got: %+v", entries)
+	}
+
+	if entries[0].Action != "created" || entries[0].Actor != "42" {
+		t.Errorf("Expected creation by actor 42, got: %+v", entries[0])
+	}
+
+	status, ok := entries[1].Changes["status"]
+	if entries[1].Action != "status_changed" || !ok || status.From != "pending" || status.To != "shipped" {
+		t.Errorf("Expected status change pending -> shipped, got: %+v", entries[1])
+	}
+
+	if entries[1].Timestamp.Before(entries[0].Timestamp) {
+		t.Error("History entries are not in chronological order")
+	}
+}
+
+func TestGetOrderHistory_UnknownOrder(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/99/history", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+}
+
+func TestDeleteOrder_SoftDeleteVisibility(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/2", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+
+	if _, ids := listOrderIDs(t, s, "/orders"); !reflect.DeepEqual(ids, []int{1, 3, 4}) {
+		t.Errorf("Expected deleted order to be hidden, got: %v", ids)
+	}
+
+	if _, ids := listOrderIDs(t, s, "/orders?include_deleted=true"); !reflect.DeepEqual(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("Expected include_deleted to reveal order 2, got: %v", ids)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/2", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for soft-deleted order, got: %d", rec.Code)
+	}
+
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/orders/2?include_deleted=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with include_deleted, got: %d", rec.Code)
+	}
+
+	var order Order
+	if err := json.NewDecoder(rec.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to decode order: %v", err)
+	}
+	if order.DeletedAt == nil {
+		t.Error("Expected deleted_at to be set")
+	}
+}
+
+func TestRestoreOrder(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	s.serve(httptest.NewRequest(http.MethodDelete, "/orders/2", nil))
+
+	rec := s.serve(httptest.NewRequest(http.MethodPost, "/orders/2/restore", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/2", nil)); rec.Code != http.StatusOK {
+		t.Errorf("Expected restored order to be visible, got: %d", rec.Code)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodPost, "/orders/99/restore", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 restoring unknown order, got: %d", rec.Code)
+	}
+}
+
+func TestDeleteOrder_HardMode(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.setFlags(func(f *Flags) { f.SoftDelete = false })
+
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/2", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+
+	if _, ids := listOrderIDs(t, s, "/orders?include_deleted=true"); !reflect.DeepEqual(ids, []int{1, 3, 4}) {
+		t.Errorf("Expected order 2 to be removed, got: %v", ids)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodPost, "/orders/2/restore", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected hard-deleted order not to be restorable, got: %d", rec.Code)
+	}
+}
+
+func decodeMap(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&m); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestGetOrderByID_SparseFields(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"/orders/3?fields=id,status", []string{"id", "status"}},
+		{"/orders/3?fields=product", []string{"product"}},
+		{"/orders/3?fields=id,user", []string{"id", "user"}},
+	}
+
+	for _, tt := range tests {
+		rec := s.serve(httptest.NewRequest(http.MethodGet, tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got: %d", tt.query, rec.Code)
+		}
+		if keys := sortedKeys(decodeMap(t, rec)); !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: expected fields %v, got: %v", tt.query, tt.want, keys)
+		}
+	}
+}
+
+func TestGetOrderByID_UnknownField(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/3?fields=id,secret", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+// fakeClock - часы с ручным управлением для тестов.
+type fakeClock struct {
+	mu      sync.Mutex
+	current time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.current = t
+	c.mu.Unlock()
+}
+
+// setClock подменяет now на управляемые часы на время теста.
+func setClock(t *testing.T, start time.Time) *fakeClock {
+	t.Helper()
+	clock := &fakeClock{current: start}
+	prev := now
+	now = clock.Now
+	t.Cleanup(func() { now = prev })
+	return clock
+}
+
+func TestGetOrders_CreatedWindowFilter(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := setClock(t, base)
+
+	for i := 0; i < 3; i++ {
+		clock.Set(base.Add(time.Duration(i) * time.Hour))
+		rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"status":"pending"}`))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"after", "/orders?created_after=2024-03-01T13:00:00Z", []int{2, 3}},
+		{"before", "/orders?created_before=2024-03-01T12:30:00Z", []int{1}},
+		{"window", "/orders?created_after=2024-03-01T12:30:00Z&created_before=2024-03-01T13:30:00Z", []int{2}},
+		{"combined with quantity", "/orders?created_after=2024-03-01T13:00:00Z&min_qty=2", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := listOrderIDs(t, s, tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("Expected status 200, got: %d", code)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Expected orders %v, got: %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestGetOrders_CreatedWindowValidation(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	for _, query := range []string{
+		"/orders?created_after=yesterday",
+		"/orders?created_before=2024-13-01T00:00:00Z",
+		"/orders?created_after=2024-03-02T00:00:00Z&created_before=2024-03-01T00:00:00Z",
+	} {
+		if code, _ := listOrderIDs(t, s, query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", query, code)
+		}
+	}
+}
+
+func TestGetOrderByID_DegradedCounter(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	before := ordersServedDegraded.Value()
+
+	rec := s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected order to be served despite user failure, got: %d", rec.Code)
+	}
+
+	if got := ordersServedDegraded.Value() - before; got != 1 {
+		t.Errorf("Expected degraded counter to increase by 1, got: %d", got)
+	}
+
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if !strings.Contains(rec.Body.String(), `"orders_served_degraded_total"`) {
+		t.Error("Expected counter to be exposed on /debug/vars")
+	}
+}
+
+func TestGetOrderByID_EmbedsUser(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	var order Order
+	if err := json.NewDecoder(rec.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to decode order: %v", err)
+	}
+
+	if order.ID != 3 || order.User == nil || order.User.ID != 2 {
+		t.Errorf("Expected order 3 with embedded user 2, got: %+v", order)
+	}
+}
+
+// recordingPublisher собирает опубликованные события.
+type recordingPublisher struct {
+	ch chan Event
+}
+
+func (p *recordingPublisher) Publish(e Event) { p.ch <- e }
+
+func setEventPublisher(t *testing.T, s *server) *recordingPublisher {
+	t.Helper()
+	p := &recordingPublisher{ch: make(chan Event, 16)}
+	s.events = p
+	return p
+}
+
+func (p *recordingPublisher) next(t *testing.T) Event {
+	t.Helper()
+	select {
+	case e := <-p.ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestOrderLimit_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+	publisher := setEventPublisher(t, s)
+
+	s.limit = newOrderLRU(2)
+
+	create := func() {
+		rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"status":"pending"}`))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+		}
+	}
+
+	create() // 1
+	create() // 2
+
+	// Обращение к заказу 1 делает самым давним заказ 2
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	create() // 3, вытесняет 2
+
+	if _, ids := listOrderIDs(t, s, "/orders"); !reflect.DeepEqual(ids, []int{1, 3}) {
+		t.Errorf("Expected orders [1 3] after eviction, got: %v", ids)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/2", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected evicted order to be gone, got: %d", rec.Code)
+	}
+
+	if e := publisher.next(t); e.Type != "order.evicted" || e.OrderID != 2 {
+		t.Errorf("Expected order.evicted for order 2, got: %+v", e)
+	}
+}
+
+func TestCreateOrder_RejectsUnverifiedUser(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "name": "Ann", "email": "ann@example.com", "verified": false}`))
+	})
+	body := `{"user_id":1,"product":"Pen","quantity":1,"status":"pending"}`
+
+	// По умолчанию проверка подтверждения выключена
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", body)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 with verification check disabled, got: %d", rec.Code)
+	}
+
+	s.setFlags(func(f *Flags) { f.RequireVerifiedUsers = true })
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", body))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 for unverified user, got: %d", rec.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.Code != "user_not_verified" {
+		t.Errorf("Expected code 'user_not_verified', got: %q", resp.Error.Code)
+	}
+}
+
+// withUserProfile запрашивает заказ со встроенным пользователем.
+func withUserProfile(r *http.Request) *http.Request {
+	r.Header.Set("Accept", `application/json; profile="with-user"`)
+	return r
+}
+
+func decodeOrder(t *testing.T, rec *httptest.ResponseRecorder) Order {
+	t.Helper()
+	var order Order
+	if err := json.NewDecoder(rec.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to decode order: %v", err)
+	}
+	return order
+}
+
+func TestPutOrder_CreatesWithGivenID(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/10", `{"user_id":1,"product":"Lamp","quantity":2,"status":"pending"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.ID != 10 || order.Product != "Lamp" {
+		t.Errorf("Expected order 10 to be created, got: %+v", order)
+	}
+
+	// Повтор того же PUT идемпотентен и заменяет заказ
+	rec = s.serve(jsonRequest(http.MethodPut, "/orders/10", `{"user_id":1,"product":"Lamp","quantity":2,"status":"pending"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on repeated PUT, got: %d", rec.Code)
+	}
+
+	// Следующий POST не должен столкнуться с явно заданным ID
+	rec = s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"status":"pending"}`))
+	if order := decodeOrder(t, rec); order.ID != 11 {
+		t.Errorf("Expected next generated ID to be 11, got: %d", order.ID)
+	}
+}
+
+func TestPutOrder_ReplacesExisting(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending", CreatedAt: created},
+	})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Pen","quantity":4,"status":"shipped"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	order := decodeOrder(t, rec)
+	if order.Quantity != 4 || order.Status != "shipped" || !order.CreatedAt.Equal(created) {
+		t.Errorf("Expected replaced order with original created_at, got: %+v", order)
+	}
+
+	s.mu.RLock()
+	entries := s.history[1]
+	s.mu.RUnlock()
+	if len(entries) != 1 || entries[0].Action != "updated" {
+		t.Errorf("Expected an 'updated' history entry, got: %+v", entries)
+	}
+}
+
+func TestPutOrder_ValidatesBody(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	for _, body := range []string{
+		`{"user_id":1,"product":"","quantity":1}`,
+		`{"user_id":1,"product":"Pen","quantity":0}`,
+		`{"user_id":1,"product":"Pen","quantity":1,"status":"lost"}`,
+		`not json`,
+	} {
+		if rec := s.serve(jsonRequest(http.MethodPut, "/orders/1", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", body, rec.Code)
+		}
+	}
+}
+
+func TestOrderRoutes_RejectsNonPositiveIDs(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+	body := `{"user_id":1,"product":"Pen","quantity":1}`
+
+	for _, id := range []string{"0", "-5", "+0"} {
+		rec := s.serve(jsonRequest(http.MethodPut, "/orders/"+id, body))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_id") {
+			t.Errorf("PUT /orders/%s: expected 400 invalid_id, got: %d (%s)", id, rec.Code, rec.Body)
+		}
+		if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/"+id, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /orders/%s: expected status 400, got: %d", id, rec.Code)
+		}
+	}
+	for id := range s.orders {
+		if id <= 0 {
+			t.Errorf("Expected no order with ID %d", id)
+		}
+	}
+}
+
+func TestCreateOrder_PreferReturn(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+	body := `{"user_id":1,"product":"Pen","quantity":1}`
+
+	req := jsonRequest(http.MethodPost, "/orders", body)
+	req.Header.Set("Prefer", "return=minimal")
+	rec := s.serve(req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected empty body with return=minimal, got: %s", rec.Body)
+	}
+	if rec.Header().Get("Location") != "/orders/1" || rec.Header().Get("Preference-Applied") != "return=minimal" {
+		t.Errorf("Expected Location and Preference-Applied, got: %v", rec.Header())
+	}
+	if _, ok := s.orders[1]; !ok {
+		t.Error("Expected the order to be created")
+	}
+
+	req = jsonRequest(http.MethodPost, "/orders", body)
+	req.Header.Set("Prefer", "return=representation")
+	rec = s.serve(req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Preference-Applied") != "return=representation" {
+		t.Fatalf("Expected 201 with return=representation applied, got: %d %v", rec.Code, rec.Header())
+	}
+	if order := decodeOrder(t, rec); order.ID != 2 || order.Product != "Pen" {
+		t.Errorf("Expected the created order in the body, got: %+v", order)
+	}
+
+	// Без предпочтения - прежнее поведение
+	rec = s.serve(jsonRequest(http.MethodPost, "/orders", body))
+	if rec.Header().Get("Preference-Applied") != "" {
+		t.Errorf("Expected no Preference-Applied without Prefer, got: %v", rec.Header())
+	}
+	if order := decodeOrder(t, rec); order.ID != 3 {
+		t.Errorf("Expected the created order in the body, got: %+v", order)
+	}
+}
+
+func TestCreateOrder_FieldProjection(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+	body := `{"user_id":1,"product":"Pen","quantity":2,"tags":["gift"]}`
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders?fields=id", body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var projected map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&projected); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(projected) != 1 || projected["id"] != float64(1) {
+		t.Errorf("Expected only the id, got: %v", projected)
+	}
+	if rec.Header().Get("Location") != "/orders/1" {
+		t.Errorf("Expected Location /orders/1, got: %q", rec.Header().Get("Location"))
+	}
+	if stored := s.orders[1]; stored.Product != "Pen" || stored.Quantity != 2 {
+		t.Errorf("Expected the full order to be stored, got: %+v", stored)
+	}
+
+	// Без ?fields= - полный заказ
+	rec = s.serve(jsonRequest(http.MethodPost, "/orders", body))
+	if order := decodeOrder(t, rec); order.ID != 2 || order.Product != "Pen" || order.OrderNumber == "" || len(order.Tags) != 1 {
+		t.Errorf("Expected the full created order, got: %+v", order)
+	}
+
+	// Неизвестное поле отклоняется до создания заказа
+	rec = s.serve(jsonRequest(http.MethodPost, "/orders?fields=id,secret", body))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown field, got: %d", rec.Code)
+	}
+	if len(s.orders) != 2 {
+		t.Errorf("Expected no order created for invalid fields, got: %d orders", len(s.orders))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ringReplicas - сколько точек на кольце у одного адреса. Чем их больше,
+// тем ровнее пользователи делятся между шардами.
+const ringReplicas = 160
+
+// hashRing распределяет ID пользователей по шардам user-service
+// консистентным хешированием: при добавлении или удалении шарда к другому
+// шарду переходят только ID, которые принадлежали измененному.
+type hashRing struct {
+	mu        sync.RWMutex
+	endpoints []string
+	points    []uint32          // отсортированные хеши точек
+	owners    map[uint32]string // хеш точки -> адрес шарда
+}
+
+func newHashRing(endpoints []string) *hashRing {
+	r := &hashRing{}
+	r.Set(endpoints)
+	return r
+}
+
+// Set заменяет набор шардов и перестраивает кольцо. Повторы адресов
+// игнорируются.
+func (r *hashRing) Set(endpoints []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rebuild(endpoints)
+}
+
+// Add добавляет шард и перестраивает кольцо. Чтение набора и перестройка
+// идут под одной блокировкой, иначе параллельные Add и Remove теряли бы
+// изменения друг друга.
+func (r *hashRing) Add(endpoint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rebuild(append(slices.Clone(r.endpoints), endpoint))
+}
+
+// Remove убирает шард и перестраивает кольцо.
+func (r *hashRing) Remove(endpoint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rebuild(slices.DeleteFunc(slices.Clone(r.endpoints), func(e string) bool { return e == endpoint }))
+}
+
+// rebuild строит кольцо по endpoints. Вызывается под r.mu.
+func (r *hashRing) rebuild(endpoints []string) {
+	unique := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if !slices.Contains(unique, endpoint) {
+			unique = append(unique, endpoint)
+		}
+	}
+
+	points := make([]uint32, 0, len(unique)*ringReplicas)
+	owners := make(map[uint32]string, len(unique)*ringReplicas)
+	for _, endpoint := range unique {
+		for i := 0; i < ringReplicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + endpoint))
+			owner, taken := owners[h]
+			if !taken {
+				points = append(points, h)
+			}
+			// При совпадении хешей точка достается меньшему адресу, чтобы
+			// кольцо не зависело от порядка шардов
+			if !taken || endpoint < owner {
+				owners[h] = endpoint
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+
+	r.endpoints, r.points, r.owners = unique, points, owners
+}
+
+// Endpoints возвращает копию текущего набора шардов.
+func (r *hashRing) Endpoints() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.endpoints)
+}
+
+// Endpoint возвращает адрес шарда, которому принадлежит userID, или "",
+// если шардов нет.
+func (r *hashRing) Endpoint(userID int) string {
+	h := crc32.ChecksumIEEE([]byte(strconv.Itoa(userID)))
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		return ""
+	}
+	// Первая точка по часовой стрелке от хеша; за последней идет первая
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
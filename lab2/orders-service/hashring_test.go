@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+var testShards = []string{"http://users-0:8081", "http://users-1:8081", "http://users-2:8081"}
+
+func TestHashRing_StableMapping(t *testing.T) {
+	ring := newHashRing(testShards)
+	// Порядок шардов в конфигурации не влияет на распределение
+	reordered := newHashRing([]string{testShards[2], testShards[0], testShards[1]})
+
+	for id := 1; id <= 1000; id++ {
+		first := ring.Endpoint(id)
+		if first == "" {
+			t.Fatalf("Expected an endpoint for user %d", id)
+		}
+		if again := ring.Endpoint(id); again != first {
+			t.Fatalf("User %d mapped to %s, then to %s", id, first, again)
+		}
+		if other := reordered.Endpoint(id); other != first {
+			t.Fatalf("User %d mapped to %s, but to %s with reordered shards", id, first, other)
+		}
+	}
+}
+
+func TestHashRing_Distribution(t *testing.T) {
+	ring := newHashRing(testShards)
+
+	const users = 10000
+	counts := map[string]int{}
+	for id := 1; id <= users; id++ {
+		counts[ring.Endpoint(id)]++
+	}
+
+	if len(counts) != len(testShards) {
+		t.Fatalf("Expected users on all %d shards, got: %v", len(testShards), counts)
+	}
+	// Идеально - треть на шард; допускаем отклонение до 30%
+	ideal := users / len(testShards)
+	for shard, n := range counts {
+		if n < ideal*7/10 || n > ideal*13/10 {
+			t.Errorf("Shard %s got %d users, expected about %d: %v", shard, n, ideal, counts)
+		}
+	}
+}
+
+func TestHashRing_AddRemove(t *testing.T) {
+	ring := newHashRing(testShards)
+	before := make(map[int]string)
+	for id := 1; id <= 1000; id++ {
+		before[id] = ring.Endpoint(id)
+	}
+
+	added := "http://users-3:8081"
+	ring.Add(added)
+	for id, old := range before {
+		// Пользователь либо остался на своем шарде, либо ушел на новый
+		if now := ring.Endpoint(id); now != old && now != added {
+			t.Errorf("User %d moved from %s to %s, expected only moves to the new shard", id, old, now)
+		}
+	}
+
+	ring.Remove(added)
+	for id, old := range before {
+		if now := ring.Endpoint(id); now != old {
+			t.Errorf("User %d mapped to %s after removing the new shard, expected %s", id, now, old)
+		}
+	}
+
+	for _, shard := range testShards {
+		ring.Remove(shard)
+	}
+	if got := ring.Endpoint(1); got != "" {
+		t.Errorf("Expected no endpoint for an empty ring, got: %q", got)
+	}
+}
+
+func TestHashRing_ConcurrentAddRemove(t *testing.T) {
+	ring := newHashRing(testShards)
+
+	added := make([]string, 20)
+	for i := range added {
+		added[i] = "http://users-" + strconv.Itoa(len(testShards)+i) + ":8081"
+	}
+	// Все горутины стартуют разом, чтобы изменения наверняка пересеклись
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, shard := range added {
+		wg.Add(1)
+		go func(shard string) {
+			defer wg.Done()
+			<-start
+			ring.Add(shard)
+		}(shard)
+	}
+	for _, shard := range testShards {
+		wg.Add(1)
+		go func(shard string) {
+			defer wg.Done()
+			<-start
+			ring.Remove(shard)
+		}(shard)
+	}
+	close(start)
+	wg.Wait()
+
+	// Изменения разных шардов не мешают друг другу: ни одно не потеряно
+	got := ring.Endpoints()
+	sort.Strings(got)
+	want := slices.Clone(added)
+	sort.Strings(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected shards %v after concurrent updates, got: %v", want, got)
+	}
+}
+
+func TestUserServiceClient_RoutesToShard(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string][]string{}
+	shards := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		var srv *httptest.Server
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[srv.URL] = append(hits[srv.URL], r.URL.Path)
+			mu.Unlock()
+			userServiceStub(w, r)
+		}))
+		t.Cleanup(srv.Close)
+		shards = append(shards, srv.URL)
+	}
+
+	client := &UserServiceClient{BaseURL: "http://unused.invalid", Client: http.DefaultClient, Shards: newHashRing(shards)}
+	for id := 1; id <= 20; id++ {
+		if _, err := client.GetUserByID(context.Background(), id); err != nil {
+			t.Fatalf("GetUserByID(%d): %v", id, err)
+		}
+		if exists, err := client.UserExists(context.Background(), id); err != nil || !exists {
+			t.Fatalf("UserExists(%d) = %v, %v", id, exists, err)
+		}
+
+		owner := client.Shards.Endpoint(id)
+		want := "/users/" + strconv.Itoa(id)
+		mu.Lock()
+		got := hits[owner]
+		mu.Unlock()
+		if len(got) < 2 || got[len(got)-2] != want || got[len(got)-1] != want+"/exists" {
+			t.Fatalf("Expected user %d requests on its shard %s, got: %v", id, owner, got)
+		}
+	}
+}
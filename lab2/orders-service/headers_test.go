@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseHeadersMiddleware_Defaults(t *testing.T) {
+	h, err := loadResponseHeaders(envMap(nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handler := responseHeadersMiddleware(h, http.HandlerFunc(healthCheck))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	for name, want := range map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Cache-Control":          "no-cache",
+	} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s: expected %q, got: %q", name, want, got)
+		}
+	}
+}
+
+func TestResponseHeadersMiddleware_Overrides(t *testing.T) {
+	h, err := loadResponseHeaders(envMap(map[string]string{
+		"CACHE_CONTROL":    "private, max-age=0",
+		"RESPONSE_HEADERS": "referrer-policy=no-referrer; X-Frame-Options=",
+		"ROUTE_HEADERS":    "/health:Cache-Control=max-age=30, public;/static/:Cache-Control=max-age=3600;/static/live:Cache-Control=",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handler := responseHeadersMiddleware(h, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Обработчик может выставить свое значение поверх настроенного
+		if r.URL.Path == "/custom" {
+			w.Header().Set("Cache-Control", "max-age=5")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		path, cacheControl string
+	}{
+		{"/users", "private, max-age=0"},
+		{"/health", "max-age=30, public"},
+		{"/static/app.js", "max-age=3600"},
+		{"/static/live", ""},
+		{"/custom", "max-age=5"},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tc.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got: %q", tc.path, tc.cacheControl, got)
+		}
+		if got := rec.Header().Get("Referrer-Policy"); got != "no-referrer" {
+			t.Errorf("%s: expected added Referrer-Policy, got: %q", tc.path, got)
+		}
+		if _, ok := rec.Header()["X-Frame-Options"]; ok {
+			t.Errorf("%s: expected X-Frame-Options to be removed", tc.path)
+		}
+	}
+}
+
+func TestLoadResponseHeaders_Invalid(t *testing.T) {
+	for key, raw := range map[string]string{
+		"RESPONSE_HEADERS": "X-Frame-Options",
+		"ROUTE_HEADERS":    "health:Cache-Control=no-cache",
+	} {
+		if _, err := loadResponseHeaders(envMap(map[string]string{key: raw})); err == nil {
+			t.Errorf("%s=%q: expected error", key, raw)
+		}
+	}
+}
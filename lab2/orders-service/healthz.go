@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// dependencyCheck - проверка одной зависимости для /healthz.
+type dependencyCheck struct {
+	Name string
+	// Critical - без этой зависимости сервис не работает: ее отказ дает
+	// статус down, а отказ некритичной - degraded.
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+const defaultHealthTimeout = 2 * time.Second
+
+// Ping проверяет, что user-service отвечает 200 на /health.
+func (c *UserServiceClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	c.setDefaultHeaders(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// dependencyChecks - зависимости, которые опрашивает /healthz.
+func (s *server) dependencyChecks() []dependencyCheck {
+	return []dependencyCheck{{
+		Name:     "user_service",
+		Critical: s.userServiceCritical,
+		Probe: func(ctx context.Context) error {
+			return s.userClient.Ping(ctx)
+		},
+	}}
+}
+
+// healthz опрашивает все зависимости и отдает сводный отчет.
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, s.checkDependencies(r.Context()))
+}
+
+// writeHealthReport отвечает отчетом; статус down дает 503.
+func writeHealthReport(w http.ResponseWriter, report healthReport) {
+	status := http.StatusOK
+	if report.Status == "down" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkDependencies опрашивает все зависимости параллельно, каждую со
+// своим коротким таймаутом, и сводит результаты в общий статус.
+func (s *server) checkDependencies(ctx context.Context) healthReport {
+	checks := s.dependencyChecks()
+	results := make([]checkResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check dependencyCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, s.healthTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Probe(ctx)
+			results[i] = checkResult{Status: "ok", LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Status = "down"
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := healthReport{Status: "ok", Checks: make(map[string]checkResult, len(checks))}
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status == "ok" {
+			continue
+		}
+		if check.Critical {
+			report.Status = "down"
+		} else if report.Status == "ok" {
+			report.Status = "degraded"
+		}
+	}
+	return report
+}
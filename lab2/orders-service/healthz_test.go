@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getHealthz(t *testing.T, s *server) (int, healthReport) {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var report healthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode health report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestHealthz_AllHealthy(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	code, report := getHealthz(t, s)
+	if code != http.StatusOK || report.Status != "ok" {
+		t.Fatalf("Expected 200 ok, got: %d %+v", code, report)
+	}
+	if check, ok := report.Checks["user_service"]; !ok || check.Status != "ok" {
+		t.Errorf("Expected user_service check to pass, got: %+v", report.Checks)
+	}
+}
+
+func TestHealthz_FailingDependency(t *testing.T) {
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	s := newTestServer(t, nil)
+	useUserService(t, s, failing)
+	code, report := getHealthz(t, s)
+	if code != http.StatusOK || report.Status != "degraded" {
+		t.Errorf("Expected non-critical failure to give 200 degraded, got: %d %+v", code, report)
+	}
+	if report.Checks["user_service"].Error == "" {
+		t.Error("Expected failing check to carry an error")
+	}
+
+	s.userServiceCritical = true
+	code, report = getHealthz(t, s)
+	if code != http.StatusServiceUnavailable || report.Status != "down" {
+		t.Errorf("Expected critical failure to give 503 down, got: %d %+v", code, report)
+	}
+}
+
+func TestHealthz_ProbeTimeout(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	s.healthTimeout = 50 * time.Millisecond
+	s.userServiceCritical = true
+
+	start := time.Now()
+	code, report := getHealthz(t, s)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected probe to be cut off by its timeout, took: %v", elapsed)
+	}
+	if code != http.StatusServiceUnavailable || report.Checks["user_service"].Status != "down" {
+		t.Errorf("Expected slow dependency to be reported down, got: %d %+v", code, report)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// OrderChange - запись журнала изменений заказа.
+type OrderChange struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type callerKey struct{}
+
+// withCaller кладет в контекст идентификатор вызывающего пользователя.
+func withCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFromContext возвращает идентификатор вызывающего или "", если его нет.
+func callerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// recordOrderChange дописывает в журнал разницу между old и updated.
+// old == nil означает создание заказа. Вызывать под s.mu.Lock.
+func (s *server) recordOrderChange(old *Order, updated Order, actor string) {
+	change := OrderChange{
+		Timestamp: now(),
+		Action:    "created",
+		Actor:     actor,
+	}
+
+	if old != nil {
+		change.Action = "updated"
+		change.Changes = diffOrders(*old, updated)
+		if len(change.Changes) == 0 {
+			return
+		}
+		if _, ok := change.Changes["status"]; ok && len(change.Changes) == 1 {
+			change.Action = "status_changed"
+		}
+	}
+
+	s.history[updated.ID] = append(s.history[updated.ID], change)
+}
+
+// recordOrderAction дописывает в журнал действие без изменения полей
+// (удаление, восстановление). Вызывать под s.mu.Lock.
+func (s *server) recordOrderAction(id int, action, actor string) {
+	s.history[id] = append(s.history[id], OrderChange{
+		Timestamp: now(),
+		Action:    action,
+		Actor:     actor,
+	})
+}
+
+func diffOrders(old, updated Order) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	if old.UserID != updated.UserID {
+		changes["user_id"] = FieldChange{From: old.UserID, To: updated.UserID}
+	}
+	if old.Product != updated.Product {
+		changes["product"] = FieldChange{From: old.Product, To: updated.Product}
+	}
+	if old.Quantity != updated.Quantity {
+		changes["quantity"] = FieldChange{From: old.Quantity, To: updated.Quantity}
+	}
+	if old.Status != updated.Status {
+		changes["status"] = FieldChange{From: old.Status, To: updated.Status}
+	}
+	if !slices.Equal(old.Tags, updated.Tags) {
+		changes["tags"] = FieldChange{From: old.Tags, To: updated.Tags}
+	}
+	return changes
+}
+
+func (s *server) getOrderHistory(w http.ResponseWriter, r *http.Request, id int) {
+	s.mu.RLock()
+	_, exists := s.orders[id]
+	// Копируем, чтобы не отдавать encoder'у срез, который может расти
+	entries := append([]OrderChange{}, s.history[id]...)
+	s.mu.RUnlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectIDs параллельно запрашивает n ID и проверяет, что повторов нет.
+func collectIDs(t *testing.T, g IDGenerator, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i] = g.Next()
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool, n)
+	for _, id := range ids {
+		if id <= 0 {
+			t.Fatalf("Expected positive ID, got: %d", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID: %d", id)
+		}
+		seen[id] = true
+	}
+	return ids
+}
+
+func TestSequentialIDs(t *testing.T) {
+	g := newSequentialIDs()
+	collectIDs(t, g, 1000)
+	if id := g.Next(); id != 1001 {
+		t.Errorf("Expected 1001 after 1000 IDs, got: %d", id)
+	}
+
+	g.Observe(5000)
+	if id := g.Next(); id != 5001 {
+		t.Errorf("Expected generator to skip past observed ID, got: %d", id)
+	}
+}
+
+func TestUUIDIDs(t *testing.T) {
+	for _, id := range collectIDs(t, uuidIDs{}, 10000) {
+		if id >= 1<<53 {
+			t.Fatalf("Expected ID to fit in 53 bits, got: %d", id)
+		}
+	}
+}
+
+func TestSortableIDs_Monotonic(t *testing.T) {
+	clock := setClock(t, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := &sortableIDs{}
+
+	// Больше 4096 ID в одну миллисекунду
+	prev := 0
+	for i := 0; i < 5000; i++ {
+		id := g.Next()
+		if id <= prev {
+			t.Fatalf("Expected strictly increasing IDs, got %d after %d", id, prev)
+		}
+		prev = id
+	}
+
+	// Часы отстали - порядок сохраняется
+	clock.Set(time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC))
+	if id := g.Next(); id <= prev {
+		t.Errorf("Expected ID after clock skew to exceed %d, got: %d", prev, id)
+	}
+
+	// Более поздние ID сортируются после ранних
+	clock.Set(time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC))
+	later := g.Next()
+	if later>>sortableSeqBits <= prev>>sortableSeqBits {
+		t.Errorf("Expected later timestamp to dominate ordering, got %d vs %d", later, prev)
+	}
+
+	collectIDs(t, g, 1000)
+}
+
+func TestNewIDGenerator_UnknownStrategy(t *testing.T) {
+	if _, err := newIDGenerator("snowflake"); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+}
+
+func TestCreateOrder_UsesIDGenerator(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+	s.ids = uuidIDs{}
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	if order := decodeOrder(t, rec); order.ID <= len(quantityDataset()) {
+		t.Errorf("Expected random ID from generator, got: %d", order.ID)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout - сколько при остановке ждать завершения начатых
+// запросов, прежде чем закрыть соединения принудительно.
+const defaultDrainTimeout = 10 * time.Second
+
+// loadDrainTimeout читает SHUTDOWN_DRAIN_TIMEOUT.
+func loadDrainTimeout(getenv func(string) string) (time.Duration, error) {
+	raw := getenv("SHUTDOWN_DRAIN_TIMEOUT")
+	if raw == "" {
+		return defaultDrainTimeout, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("SHUTDOWN_DRAIN_TIMEOUT must be a positive duration, got %q", raw)
+	}
+	return d, nil
+}
+
+// inflightRequest - запрос, который сейчас обрабатывается.
+type inflightRequest struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// inflightTracker ведет учет выполняющихся запросов.
+type inflightTracker struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]inflightRequest
+}
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{active: map[uint64]inflightRequest{}}
+}
+
+// middleware отмечает запрос на время обработки. Ставится внутри
+// журнала запросов, чтобы X-Request-ID уже был в контексте.
+func (t *inflightTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.nextID++
+		id := t.nextID
+		t.active[id] = inflightRequest{
+			RequestID: requestIDFromContext(r.Context()),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			StartedAt: now(),
+		}
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *inflightTracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// Snapshot возвращает выполняющиеся запросы, начиная с самых давних.
+func (t *inflightTracker) Snapshot() []inflightRequest {
+	t.mu.Lock()
+	list := make([]inflightRequest, 0, len(t.active))
+	for _, req := range t.active {
+		list = append(list, req)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+type inflightResponse struct {
+	Count    int               `json:"count"`
+	Requests []inflightRequest `json:"requests"`
+}
+
+// getInflight обрабатывает GET /debug/inflight.
+func (s *server) getInflight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	list := s.inflight.Snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inflightResponse{Count: len(list), Requests: list})
+}
+
+// drainServer останавливает прием соединений и ждет начатые запросы не
+// дольше timeout. Если они не успели, соединения закрываются, а брошенные
+// запросы пишутся в лог. Возвращает ошибку, если пришлось закрывать силой.
+func drainServer(srv *http.Server, tracker *inflightTracker, timeout time.Duration, logger *log.Logger) error {
+	logger.Printf("Shutting down: %d request(s) in flight, waiting up to %v", tracker.Count(), timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	abandoned := tracker.Snapshot()
+	for _, req := range abandoned {
+		logger.Printf("Abandoned request %s %s (request_id=%s, running %v)",
+			req.Method, req.Path, req.RequestID, now().Sub(req.StartedAt).Round(time.Millisecond))
+	}
+	srv.Close()
+	return fmt.Errorf("drain timeout exceeded, %d request(s) abandoned", len(abandoned))
+}
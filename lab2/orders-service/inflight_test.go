@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startSlowServer поднимает сервер, обработчик которого ждет release.
+// Возвращает сервер после того, как запрос к нему начал выполняться.
+func startSlowServer(t *testing.T, tracker *inflightTracker, release <-chan struct{}) *http.Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := &http.Server{Handler: tracker.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	go http.Get("http://" + ln.Addr().String() + "/slow")
+
+	deadline := time.Now().Add(time.Second)
+	for tracker.Count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Request never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return srv
+}
+
+func TestDrainServer_WaitsForInflightRequest(t *testing.T) {
+	tracker := newInflightTracker()
+	release := make(chan struct{})
+	srv := startSlowServer(t, tracker, release)
+
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	var logs bytes.Buffer
+	start := time.Now()
+	if err := drainServer(srv, tracker, 2*time.Second, log.New(&logs, "", 0)); err != nil {
+		t.Fatalf("Expected clean drain, got: %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Expected shutdown to wait for the request, waited: %v", waited)
+	}
+	if !strings.Contains(logs.String(), "1 request(s) in flight") {
+		t.Errorf("Expected in-flight count in log, got: %s", logs.String())
+	}
+}
+
+func TestDrainServer_AbandonsAfterDeadline(t *testing.T) {
+	tracker := newInflightTracker()
+	release := make(chan struct{})
+	defer close(release)
+	srv := startSlowServer(t, tracker, release)
+
+	var logs bytes.Buffer
+	start := time.Now()
+	err := drainServer(srv, tracker, 100*time.Millisecond, log.New(&logs, "", 0))
+	waited := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected drain timeout error")
+	}
+	if waited < 100*time.Millisecond || waited > time.Second {
+		t.Errorf("Expected shutdown to last about the deadline, got: %v", waited)
+	}
+	if !strings.Contains(logs.String(), "Abandoned request GET /slow") {
+		t.Errorf("Expected abandoned request in log, got: %s", logs.String())
+	}
+}
+
+func TestGetInflight(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.adminEnabled = true
+	release := make(chan struct{})
+	handler := s.inflight.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+			return
+		}
+		s.routes().ServeHTTP(w, r)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/slow", nil))
+		close(done)
+	}()
+	for s.inflight.Count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/inflight", nil))
+	var body inflightResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// Сам запрос к /debug/inflight тоже выполняется
+	if body.Count != 2 || body.Requests[0].Path != "/slow" || body.Requests[0].Method != http.MethodPost {
+		t.Errorf("Unexpected in-flight list: %+v", body)
+	}
+
+	close(release)
+	<-done
+	if got := s.inflight.Count(); got != 0 {
+		t.Errorf("Expected no requests in flight, got: %d", got)
+	}
+
+	// Пути и ID запросов видны только при включенной админке
+	s.adminEnabled = false
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/inflight", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 with admin disabled, got: %d", rec.Code)
+	}
+}
+
+func TestLoadDrainTimeout(t *testing.T) {
+	if d, err := loadDrainTimeout(envMap(nil)); err != nil || d != defaultDrainTimeout {
+		t.Errorf("Expected default, got: %v, %v", d, err)
+	}
+	if d, err := loadDrainTimeout(envMap(map[string]string{"SHUTDOWN_DRAIN_TIMEOUT": "30s"})); err != nil || d != 30*time.Second {
+		t.Errorf("Expected 30s, got: %v, %v", d, err)
+	}
+	if _, err := loadDrainTimeout(envMap(map[string]string{"SHUTDOWN_DRAIN_TIMEOUT": "-1s"})); err == nil {
+		t.Error("Expected error for negative timeout")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrInsufficientStock - на складе не хватает товара для заказа.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// seedStock - демонстрационные остатки для INVENTORY_ENABLED=true.
+func seedStock() map[string]int {
+	return map[string]int{
+		"Laptop":   10,
+		"Mouse":    50,
+		"Keyboard": 30,
+	}
+}
+
+// reserveStock списывает qty единиц товара (отрицательное qty возвращает
+// их на склад). Без учета остатков (s.stock == nil) ничего не делает.
+// Вызывать под s.mu.Lock.
+func (s *server) reserveStock(product string, qty int) error {
+	if s.stock == nil {
+		return nil
+	}
+	if qty > 0 && s.stock[product] < qty {
+		return ErrInsufficientStock
+	}
+	s.stock[product] -= qty
+	return nil
+}
+
+// holdCreating учитывает qty единиц товара, списанных под заказ, который
+// еще создается: между списанием и сохранением заказа insertOrder
+// отпускает s.mu, и снимок возвращает такой товар в остатки так же, как
+// резервы. Отрицательное qty снимает учет. Вызывать под s.mu.Lock.
+func (s *server) holdCreating(product string, qty int) {
+	if s.creating[product] += qty; s.creating[product] == 0 {
+		delete(s.creating, product)
+	}
+}
+
+// holdStock отмечает, что заказ id держит qty единиц своего товара.
+// Без учета остатков ничего не делает. Вызывать под s.mu.Lock.
+func (s *server) holdStock(id, qty int) {
+	if s.stock == nil {
+		return
+	}
+	if qty > 0 {
+		s.held[id] = qty
+	} else {
+		delete(s.held, id)
+	}
+}
+
+// releaseStock возвращает на склад товар, который держит заказ, и снимает
+// учет. Возвращается только списанное: заказы, загруженные при старте,
+// склад не уменьшали и при удалении его не увеличивают. Вызывать под s.mu.Lock.
+func (s *server) releaseStock(order Order) {
+	if qty, ok := s.held[order.ID]; ok {
+		s.reserveStock(order.Product, -qty)
+		delete(s.held, order.ID)
+	}
+}
+
+// adjustStock меняет товар, который держит заказ, на delta единиц. Вернуть
+// можно не больше, чем заказ держит. При нехватке остатков ничего не
+// меняет. Вызывать под s.mu.Lock.
+func (s *server) adjustStock(order Order, delta int) error {
+	held := s.held[order.ID]
+	if delta < -held {
+		delta = -held
+	}
+	if err := s.reserveStock(order.Product, delta); err != nil {
+		return err
+	}
+	s.holdStock(order.ID, held+delta)
+	return nil
+}
+
+// swapStock атомарно переносит резерв с заказа old на заказ updated.
+// nil означает отсутствие заказа. При нехватке остатков ничего не меняет.
+// Вызывать под s.mu.Lock.
+func (s *server) swapStock(old, updated *Order) error {
+	var released int
+	if old != nil {
+		released = s.held[old.ID]
+		s.reserveStock(old.Product, -released)
+	}
+	if updated == nil {
+		if old != nil {
+			delete(s.held, old.ID)
+		}
+		return nil
+	}
+	if err := s.reserveStock(updated.Product, updated.Quantity); err != nil {
+		if old != nil {
+			s.reserveStock(old.Product, released)
+		}
+		return err
+	}
+	if old != nil {
+		delete(s.held, old.ID)
+	}
+	s.holdStock(updated.ID, updated.Quantity)
+	return nil
+}
+
+func writeInsufficientStock(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, "insufficient_stock", "Not enough stock for this order")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Стратегии разброса пауз между повторами. Без разброса клиенты, упавшие
+// вместе с user-service, повторяют запросы синхронно и снова его роняют.
+const (
+	// jitterNone - чистая экспонента: base, 2*base, 4*base...
+	jitterNone = "none"
+	// jitterFull - случайная пауза от 0 до экспоненциальной границы.
+	jitterFull = "full"
+	// jitterDecorrelated - случайная пауза от base до утроенной
+	// предыдущей, не больше MaxBackoff.
+	jitterDecorrelated = "decorrelated"
+)
+
+func validJitter(strategy string) error {
+	switch strategy {
+	case "", jitterNone, jitterFull, jitterDecorrelated:
+		return nil
+	default:
+		return fmt.Errorf("unknown jitter strategy %q: expected none, full or decorrelated", strategy)
+	}
+}
+
+// backoffRand - генератор для разброса пауз. У каждого клиента свой,
+// засеянный при первом использовании, чтобы паузы разных клиентов не совпадали.
+type backoffRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// int63n возвращает случайное число из [0, n).
+func (b *backoffRand) int63n(n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rng == nil {
+		b.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return b.rng.Int63n(n)
+}
+
+// maxRetryDelay - предел паузы без MaxBackoff. На нем экспонента
+// останавливается, а не переполняет time.Duration в отрицательную паузу
+// после нескольких десятков повторов. Запас в единицу нужен для int63n(ceiling+1).
+const maxRetryDelay = time.Duration(math.MaxInt64 - 1)
+
+// backoffCeiling возвращает base * 2^attempt, но не больше maxRetryDelay.
+func backoffCeiling(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	if attempt >= 63 || base > maxRetryDelay>>attempt {
+		return maxRetryDelay
+	}
+	return base << attempt
+}
+
+// retryDelay считает паузу перед повтором номер attempt (с нуля). prev -
+// предыдущая пауза, нужна для decorrelated.
+func (c *UserServiceClient) retryDelay(attempt int, prev time.Duration) time.Duration {
+	base := max(c.RetryBackoff, 0)
+	ceiling := backoffCeiling(base, attempt)
+	if c.MaxBackoff > 0 && ceiling > c.MaxBackoff {
+		ceiling = c.MaxBackoff
+	}
+
+	switch c.Jitter {
+	case jitterFull:
+		return time.Duration(c.jitter.int63n(int64(ceiling) + 1))
+	case jitterDecorrelated:
+		if prev < base {
+			prev = base
+		}
+		upper := maxRetryDelay
+		if prev < maxRetryDelay/3 {
+			upper = 3 * prev
+		}
+		if c.MaxBackoff > 0 && upper > c.MaxBackoff {
+			upper = c.MaxBackoff
+		}
+		if upper <= base {
+			return upper
+		}
+		return base + time.Duration(c.jitter.int63n(int64(upper-base)+1))
+	default:
+		return ceiling
+	}
+}
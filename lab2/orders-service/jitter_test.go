@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay_NoJitterIsExponential(t *testing.T) {
+	c := &UserServiceClient{RetryBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	var prev time.Duration
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		prev = c.retryDelay(attempt, prev)
+		if prev != want*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got: %v", attempt, want*time.Millisecond, prev)
+		}
+	}
+}
+
+func TestRetryDelay_LargeAttemptDoesNotOverflow(t *testing.T) {
+	for _, jitter := range []string{jitterNone, jitterFull, jitterDecorrelated} {
+		c := &UserServiceClient{RetryBackoff: 100 * time.Millisecond, Jitter: jitter}
+
+		var prev time.Duration
+		for _, attempt := range []int{30, 40, 62, 63, 64, 100, 1000} {
+			d := c.retryDelay(attempt, prev)
+			if d < 0 || d > maxRetryDelay {
+				t.Fatalf("%s, attempt %d: expected a non-negative delay, got: %v", jitter, attempt, d)
+			}
+			prev = d
+		}
+		if jitter == jitterNone && prev != maxRetryDelay {
+			t.Errorf("Expected the exponent to saturate at %v, got: %v", maxRetryDelay, prev)
+		}
+	}
+}
+
+func TestRetryDelay_FullJitter(t *testing.T) {
+	c := &UserServiceClient{RetryBackoff: 10 * time.Millisecond, Jitter: jitterFull}
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 200; i++ {
+		d := c.retryDelay(2, 0)
+		if d < 0 || d > 40*time.Millisecond {
+			t.Fatalf("Expected delay within [0, 40ms], got: %v", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("Expected delays to vary, got %d distinct values", len(seen))
+	}
+}
+
+func TestRetryDelay_DecorrelatedJitter(t *testing.T) {
+	base, ceiling := 10*time.Millisecond, 200*time.Millisecond
+	c := &UserServiceClient{RetryBackoff: base, MaxBackoff: ceiling, Jitter: jitterDecorrelated}
+
+	seen := map[time.Duration]bool{}
+	for run := 0; run < 50; run++ {
+		var prev time.Duration
+		for attempt := 0; attempt < 6; attempt++ {
+			upper := 3 * prev
+			if upper < 3*base {
+				upper = 3 * base
+			}
+			if upper > ceiling {
+				upper = ceiling
+			}
+			d := c.retryDelay(attempt, prev)
+			if d < base || d > upper {
+				t.Fatalf("attempt %d after %v: expected delay within [%v, %v], got: %v", attempt, prev, base, upper, d)
+			}
+			seen[d] = true
+			prev = d
+		}
+	}
+	if len(seen) < 10 {
+		t.Errorf("Expected delays to vary, got %d distinct values", len(seen))
+	}
+}
+
+func TestRetryDelay_ClientsUseSeparateGenerators(t *testing.T) {
+	a := &UserServiceClient{RetryBackoff: time.Second, Jitter: jitterFull}
+	b := &UserServiceClient{RetryBackoff: time.Second, Jitter: jitterFull}
+
+	same := 0
+	for i := 0; i < 20; i++ {
+		if a.retryDelay(3, 0) == b.retryDelay(3, 0) {
+			same++
+		}
+	}
+	if same == 20 {
+		t.Error("Expected two clients to produce different delay sequences")
+	}
+}
+
+func TestLoadConfig_RetryJitter(t *testing.T) {
+	cfg, err := LoadConfig(envMap(map[string]string{
+		"USER_SERVICE_MAX_RETRIES":   "3",
+		"USER_SERVICE_RETRY_BACKOFF": "50ms",
+		"USER_SERVICE_MAX_BACKOFF":   "1s",
+		"USER_SERVICE_RETRY_JITTER":  "decorrelated",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c := newServerFromConfig(cfg).userClient
+	if c.MaxRetries != 3 || c.RetryBackoff != 50*time.Millisecond || c.MaxBackoff != time.Second || c.Jitter != jitterDecorrelated {
+		t.Errorf("Expected retry settings on the client, got: %+v", c)
+	}
+
+	if _, err := LoadConfig(envMap(map[string]string{"USER_SERVICE_RETRY_JITTER": "random"})); err == nil {
+		t.Error("Expected error for unknown jitter strategy")
+	}
+}
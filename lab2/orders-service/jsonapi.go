@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIDocument - документ верхнего уровня в формате JSON:API.
+type jsonAPIDocument struct {
+	Data     interface{}       `json:"data"`
+	Included []jsonAPIResource `json:"included,omitempty"`
+}
+
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]json.RawMessage     `json:"attributes"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+}
+
+type jsonAPIRelationship struct {
+	Data jsonAPIResourceID `json:"data"`
+}
+
+type jsonAPIResourceID struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// wantsJSONAPI сообщает, запросил ли клиент JSON:API через Accept.
+func wantsJSONAPI(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == jsonAPIMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// newJSONAPIResource превращает сериализованный v в ресурс: поле id
+// выносится наверх, остальные поля становятся атрибутами. fields, если
+// задан, ограничивает набор атрибутов.
+func newJSONAPIResource(resourceType string, id int, v interface{}, fields []string) (jsonAPIResource, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return jsonAPIResource{}, err
+	}
+	delete(attributes, "id")
+
+	if fields != nil {
+		for name := range attributes {
+			if !hasField(fields, name) {
+				delete(attributes, name)
+			}
+		}
+	}
+
+	return jsonAPIResource{Type: resourceType, ID: strconv.Itoa(id), Attributes: attributes}, nil
+}
+
+// orderResource сериализует заказ; встроенный пользователь становится
+// связью и возвращается отдельно для секции included.
+func orderResource(order Order, fields []string) (jsonAPIResource, *jsonAPIResource, error) {
+	user := order.User
+	order.User = nil
+
+	res, err := newJSONAPIResource("orders", order.ID, order, fields)
+	if err != nil || user == nil {
+		return res, nil, err
+	}
+
+	userRes, err := newJSONAPIResource("users", user.ID, user, nil)
+	if err != nil {
+		return res, nil, err
+	}
+	res.Relationships = map[string]jsonAPIRelationship{
+		"user": {Data: jsonAPIResourceID{Type: userRes.Type, ID: userRes.ID}},
+	}
+	return res, &userRes, nil
+}
+
+// orderDocument собирает JSON:API документ для одного заказа.
+func orderDocument(order Order, fields []string) (jsonAPIDocument, error) {
+	res, included, err := orderResource(order, fields)
+	if err != nil {
+		return jsonAPIDocument{}, err
+	}
+
+	doc := jsonAPIDocument{Data: res}
+	if included != nil {
+		doc.Included = []jsonAPIResource{*included}
+	}
+	return doc, nil
+}
+
+// ordersDocument собирает JSON:API документ для коллекции заказов.
+func ordersDocument(list []Order) (jsonAPIDocument, error) {
+	data := make([]jsonAPIResource, 0, len(list))
+	var included []jsonAPIResource
+	seenUsers := map[string]bool{}
+
+	for _, order := range list {
+		res, user, err := orderResource(order, nil)
+		if err != nil {
+			return jsonAPIDocument{}, err
+		}
+		data = append(data, res)
+		if user != nil && !seenUsers[user.ID] {
+			seenUsers[user.ID] = true
+			included = append(included, *user)
+		}
+	}
+
+	return jsonAPIDocument{Data: data, Included: included}, nil
+}
+
+func writeJSONAPI(w http.ResponseWriter, status int, doc jsonAPIDocument) {
+	w.Header().Set("Content-Type", jsonAPIMediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(doc)
+}
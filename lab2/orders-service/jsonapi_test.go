@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func jsonAPIRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Accept", jsonAPIMediaType)
+	return r
+}
+
+func TestJSONAPI_SingleOrder(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	r := jsonAPIRequest("/orders/3")
+	r.Header.Set("Accept", jsonAPIMediaType+`; profile="with-user"`)
+	rec := s.serve(r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != jsonAPIMediaType {
+		t.Errorf("Expected content type %q, got: %q", jsonAPIMediaType, ct)
+	}
+
+	var doc struct {
+		Data     jsonAPIResource   `json:"data"`
+		Included []jsonAPIResource `json:"included"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if doc.Data.Type != "orders" || doc.Data.ID != "3" {
+		t.Errorf("Expected orders/3, got: %s/%s", doc.Data.Type, doc.Data.ID)
+	}
+	if _, ok := doc.Data.Attributes["id"]; ok {
+		t.Error("Expected id to be moved out of attributes")
+	}
+	if string(doc.Data.Attributes["product"]) != `"Ink"` {
+		t.Errorf("Expected product attribute, got: %s", doc.Data.Attributes["product"])
+	}
+	if rel := doc.Data.Relationships["user"].Data; rel.Type != "users" || rel.ID != "2" {
+		t.Errorf("Expected relationship to users/2, got: %+v", rel)
+	}
+	if len(doc.Included) != 1 || doc.Included[0].ID != "2" {
+		t.Errorf("Expected user 2 in included, got: %+v", doc.Included)
+	}
+}
+
+func TestJSONAPI_OrderCollection(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	rec := s.serve(jsonAPIRequest("/orders?min_qty=10"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	var doc struct {
+		Data []jsonAPIResource `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if len(doc.Data) != 2 {
+		t.Fatalf("Expected 2 resources, got: %d", len(doc.Data))
+	}
+	for _, res := range doc.Data {
+		if res.Type != "orders" || res.ID == "" || res.Attributes["quantity"] == nil {
+			t.Errorf("Malformed resource: %+v", res)
+		}
+	}
+}
+
+func TestJSONAPI_DefaultFormatUnchanged(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?min_qty=10", nil))
+	var list []Order
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Expected plain array by default, got error: %v", err)
+	}
+}
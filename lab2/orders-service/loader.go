@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Пакетная загрузка пользователей. GET /users?ids= отдает нескольких
+// пользователей одним запросом; userLoader копит одиночные GetUserByID в
+// течение короткого окна и отправляет их одним таким запросом (паттерн
+// dataloader). Выгоден, когда много обработчиков одновременно запрашивают
+// разных пользователей по одному.
+
+// userLookupMaxIDs - сколько ID user-service принимает в одном ?ids=.
+const userLookupMaxIDs = 100
+
+const defaultUserBatchMax = 50
+
+// GetUsersByIDs запрашивает пользователей пакетно, по userLookupMaxIDs за
+// запрос и с шардами - отдельно у каждого шарда. Ненайденные ID в ответе
+// отсутствуют. Результат обновляет кэш так же, как FetchUserByID.
+func (c *UserServiceClient) GetUsersByIDs(ctx context.Context, ids []int) (map[int]*User, error) {
+	groups := map[string][]int{}
+	for _, id := range ids {
+		base := c.userBaseURL(id)
+		if !slices.Contains(groups[base], id) {
+			groups[base] = append(groups[base], id)
+		}
+	}
+
+	found := make(map[int]*User, len(ids))
+	for base, group := range groups {
+		sort.Ints(group)
+		for start := 0; start < len(group); start += userLookupMaxIDs {
+			end := min(start+userLookupMaxIDs, len(group))
+			chunk := group[start:end]
+
+			var page map[int]User
+			if err := c.getJSON(ctx, fmt.Sprintf("%s/users?ids=%s", base, joinIDs(chunk)), 0, &page); err != nil {
+				return nil, err
+			}
+			for _, id := range chunk {
+				user, ok := page[id]
+				if !ok {
+					if c.Cache != nil {
+						c.Cache.Delete(id)
+					}
+					continue
+				}
+				found[id] = &user
+				if c.Cache != nil {
+					c.Cache.Put(user)
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// userBatchTimeout ограничивает загрузку одного пакета: контекстов
+// ждущих запросов у нее нет, а без дедлайна она зависела бы только от
+// Client.Timeout.
+const userBatchTimeout = 3 * time.Second
+
+// userLoader копит ID в течение window или пока их не наберется maxBatch
+// и загружает их одним вызовом fetch.
+type userLoader struct {
+	window   time.Duration
+	maxBatch int
+	fetch    func(ctx context.Context, ids []int) (map[int]*User, error)
+
+	mu      sync.Mutex
+	pending map[batchKey]*userBatch
+}
+
+// batchKey - значения входящего запроса, которые уходят в user-service
+// вместе с исходящим: X-On-Behalf-Of и X-Embed-Depth. Запросы с разными
+// значениями не делят пакет, иначе user-service увидел бы чужого вызывающего.
+type batchKey struct {
+	caller string
+	depth  int
+}
+
+func batchKeyFrom(ctx context.Context) batchKey {
+	return batchKey{caller: callerFromContext(ctx), depth: embedDepthFromContext(ctx)}
+}
+
+// context возвращает контекст загрузки пакета с этими значениями.
+func (k batchKey) context(parent context.Context) context.Context {
+	ctx := context.WithValue(parent, embedDepthKey{}, k.depth)
+	if k.caller != "" {
+		ctx = withCaller(ctx, k.caller)
+	}
+	return ctx
+}
+
+// userBatch - ID, ждущие одной загрузки. done закрывается, когда users и
+// err заполнены.
+type userBatch struct {
+	key   batchKey
+	ids   []int
+	timer *time.Timer
+	done  chan struct{}
+	users map[int]*User
+	err   error
+}
+
+func newUserLoader(window time.Duration, maxBatch int, fetch func(context.Context, []int) (map[int]*User, error)) *userLoader {
+	if maxBatch <= 0 {
+		maxBatch = defaultUserBatchMax
+	}
+	return &userLoader{window: window, maxBatch: maxBatch, fetch: fetch, pending: map[batchKey]*userBatch{}}
+}
+
+// Load добавляет userID в текущий пакет и ждет его загрузки. Для
+// пользователя, которого нет в ответе, возвращает ErrUserNotFound.
+// Каждый вызов расходует лимит исходящих запросов входящего запроса, как
+// одиночный GetUserByID, даже если пакет в итоге общий.
+func (l *userLoader) Load(ctx context.Context, userID int) (*User, error) {
+	if err := spendUpstreamCall(ctx); err != nil {
+		return nil, err
+	}
+
+	key := batchKeyFrom(ctx)
+	l.mu.Lock()
+	b := l.pending[key]
+	if b == nil {
+		b = &userBatch{key: key, done: make(chan struct{})}
+		b.timer = time.AfterFunc(l.window, func() { l.flush(b) })
+		l.pending[key] = b
+	}
+	if !slices.Contains(b.ids, userID) {
+		b.ids = append(b.ids, userID)
+	}
+	full := len(b.ids) >= l.maxBatch
+	l.mu.Unlock()
+
+	if full {
+		go l.flush(b)
+	}
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrUserServiceTimeout, ctx.Err())
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	user, ok := b.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// flush отправляет пакет, если его еще не отправили. Пакет общий для
+// нескольких входящих запросов, поэтому загружается не в их контекстах:
+// отмена одного из них не должна срывать загрузку для остальных. Лимит
+// исходящих запросов уже списан в Load, а значения запросов переносит key.
+func (l *userLoader) flush(b *userBatch) {
+	l.mu.Lock()
+	if l.pending[b.key] != b {
+		l.mu.Unlock()
+		return
+	}
+	delete(l.pending, b.key)
+	l.mu.Unlock()
+	b.timer.Stop()
+
+	ctx, cancel := context.WithTimeout(b.key.context(context.Background()), userBatchTimeout)
+	defer cancel()
+	b.users, b.err = l.fetch(ctx, b.ids)
+	close(b.done)
+}
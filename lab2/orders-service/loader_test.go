@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchUserService отвечает на GET /users?ids= пользователями с ID до 100
+// и записывает полученные ?ids= и X-On-Behalf-Of.
+type batchUserService struct {
+	mu      sync.Mutex
+	batches []string
+	callers []string
+	singles int
+}
+
+func (b *batchUserService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/users" {
+		b.mu.Lock()
+		b.singles++
+		b.mu.Unlock()
+		userServiceStub(w, r)
+		return
+	}
+	ids := r.URL.Query().Get("ids")
+	b.mu.Lock()
+	b.batches = append(b.batches, ids)
+	b.callers = append(b.callers, r.Header.Get(onBehalfOfHeader))
+	b.mu.Unlock()
+
+	var items []string
+	for _, id := range strings.Split(ids, ",") {
+		if len(id) < 3 {
+			items = append(items, fmt.Sprintf(`"%s": {"id": %s, "name": "User %s"}`, id, id, id))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{%s}", strings.Join(items, ","))
+}
+
+func newBatchingClient(t *testing.T, window time.Duration, maxBatch int) (*UserServiceClient, *batchUserService) {
+	t.Helper()
+	upstream := &batchUserService{}
+	srv := httptest.NewServer(upstream)
+	t.Cleanup(srv.Close)
+
+	client := &UserServiceClient{BaseURL: srv.URL, Client: &http.Client{Timeout: time.Second}}
+	client.Loader = newUserLoader(window, maxBatch, client.GetUsersByIDs)
+	return client, upstream
+}
+
+func TestUserLoader_CoalescesWithinWindow(t *testing.T) {
+	client, upstream := newBatchingClient(t, 20*time.Millisecond, 50)
+
+	ids := []int{3, 1, 2, 1, 500}
+	users := make([]*User, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			users[i], errs[i] = client.GetUserByID(context.Background(), id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(upstream.batches) != 1 || upstream.singles != 0 {
+		t.Fatalf("Expected one batch request, got batches %v and %d single requests", upstream.batches, upstream.singles)
+	}
+	if upstream.batches[0] != "1,2,3,500" {
+		t.Errorf("Expected each ID requested once, got: %s", upstream.batches[0])
+	}
+	for i, id := range ids {
+		if id == 500 {
+			if !errors.Is(errs[i], ErrUserNotFound) {
+				t.Errorf("Expected ErrUserNotFound for user 500, got: %v", errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil || users[i] == nil || users[i].ID != id {
+			t.Errorf("Lookup of user %d: got %+v, %v", id, users[i], errs[i])
+		}
+	}
+}
+
+func TestUserLoader_FlushesAtMaxBatch(t *testing.T) {
+	// Окно намного длиннее теста: пакет уходит только по размеру
+	client, upstream := newBatchingClient(t, time.Minute, 3)
+
+	var wg sync.WaitGroup
+	for id := 1; id <= 3; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if _, err := client.GetUserByID(context.Background(), id); err != nil {
+				t.Errorf("GetUserByID(%d): %v", id, err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if len(upstream.batches) != 1 {
+		t.Errorf("Expected one full batch, got: %v", upstream.batches)
+	}
+}
+
+func TestUserLoader_CallerCancellation(t *testing.T) {
+	client, _ := newBatchingClient(t, time.Minute, 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetUserByID(ctx, 1); !errors.Is(err, ErrUserServiceTimeout) {
+		t.Errorf("Expected the caller to stop waiting at its deadline, got: %v", err)
+	}
+}
+
+func TestUserLoader_SpendsUpstreamCallLimit(t *testing.T) {
+	client, upstream := newBatchingClient(t, 5*time.Millisecond, 50)
+
+	ctx := withUpstreamCallLimit(context.Background(), 1)
+	if _, err := client.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("Expected the first lookup within the limit, got: %v", err)
+	}
+	if _, err := client.GetUserByID(ctx, 2); !errors.Is(err, ErrUpstreamCallLimit) {
+		t.Errorf("Expected ErrUpstreamCallLimit for the second lookup, got: %v", err)
+	}
+	if len(upstream.batches) != 1 {
+		t.Errorf("Expected the rejected lookup not to reach user-service, got: %v", upstream.batches)
+	}
+}
+
+func TestUserLoader_BatchesPerCaller(t *testing.T) {
+	client, upstream := newBatchingClient(t, 20*time.Millisecond, 50)
+
+	var wg sync.WaitGroup
+	for _, tc := range []struct {
+		caller string
+		id     int
+	}{{"7", 1}, {"7", 2}, {"9", 3}} {
+		wg.Add(1)
+		go func(caller string, id int) {
+			defer wg.Done()
+			if _, err := client.GetUserByID(withCaller(context.Background(), caller), id); err != nil {
+				t.Errorf("Unexpected error for user %d: %v", id, err)
+			}
+		}(tc.caller, tc.id)
+	}
+	wg.Wait()
+
+	got := map[string]string{}
+	for i, ids := range upstream.batches {
+		got[upstream.callers[i]] = ids
+	}
+	if len(upstream.batches) != 2 || got["7"] != "1,2" || got["9"] != "3" {
+		t.Errorf("Expected one batch per caller with X-On-Behalf-Of, got batches %v for callers %v", upstream.batches, upstream.callers)
+	}
+}
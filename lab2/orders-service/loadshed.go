@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUserServiceOverloaded возвращается без обращения к сети, когда
+// клиент сам отбрасывает часть запросов к перегруженному user-service.
+var ErrUserServiceOverloaded = errors.New("user service overloaded: request shed locally")
+
+// LoadShedConfig - настройки адаптивного сброса нагрузки. В отличие от
+// автомата (circuit breaker), который отсекает все запросы сразу,
+// доля сбрасываемых запросов растет плавно вместе с долей ошибок или
+// задержкой и так же плавно падает по мере восстановления.
+type LoadShedConfig struct {
+	// Window - число последних запросов, по которым считается статистика.
+	Window int
+	// MinSamples - сколько исходов нужно накопить, прежде чем сбрасывать.
+	MinSamples int
+	// ErrorThreshold - доля ошибок, выше которой начинается сброс.
+	ErrorThreshold float64
+	// LatencyThreshold - средняя задержка, выше которой начинается сброс (0 - не учитывать).
+	LatencyThreshold time.Duration
+	// MaxShed - верхняя граница доли сброса: часть запросов всегда
+	// проходит, иначе не узнать, что сервис восстановился.
+	MaxShed float64
+}
+
+var defaultLoadShedConfig = LoadShedConfig{
+	Window:         100,
+	MinSamples:     20,
+	ErrorThreshold: 0.5,
+	MaxShed:        0.9,
+}
+
+// loadLoadShedConfig читает USER_SERVICE_SHED_ERROR_THRESHOLD и
+// USER_SERVICE_SHED_LATENCY_THRESHOLD. Сброс включается, если задан хотя бы один.
+func loadLoadShedConfig(getenv func(string) string) (LoadShedConfig, bool, error) {
+	cfg := defaultLoadShedConfig
+	enabled := false
+	if raw := getenv("USER_SERVICE_SHED_ERROR_THRESHOLD"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 0 || v >= 1 {
+			return cfg, false, fmt.Errorf("USER_SERVICE_SHED_ERROR_THRESHOLD must be between 0 and 1, got %q", raw)
+		}
+		cfg.ErrorThreshold = v
+		enabled = true
+	}
+	if raw := getenv("USER_SERVICE_SHED_LATENCY_THRESHOLD"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return cfg, false, fmt.Errorf("USER_SERVICE_SHED_LATENCY_THRESHOLD must be a positive duration, got %q", raw)
+		}
+		cfg.LatencyThreshold = d
+		enabled = true
+	}
+	return cfg, enabled, nil
+}
+
+type shedOutcome struct {
+	failed  bool
+	latency time.Duration
+}
+
+// loadShedder хранит скользящее окно исходов запросов.
+type loadShedder struct {
+	cfg LoadShedConfig
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	window  []shedOutcome
+	next    int
+	count   int
+	failed  int
+	latency time.Duration
+}
+
+func newLoadShedder(cfg LoadShedConfig, seed int64) *loadShedder {
+	return &loadShedder{
+		cfg:    cfg,
+		rng:    rand.New(rand.NewSource(seed)),
+		window: make([]shedOutcome, cfg.Window),
+	}
+}
+
+// Record добавляет исход запроса в окно, вытесняя самый старый.
+func (l *loadShedder) Record(failed bool, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == len(l.window) {
+		old := l.window[l.next]
+		if old.failed {
+			l.failed--
+		}
+		l.latency -= old.latency
+	} else {
+		l.count++
+	}
+	l.window[l.next] = shedOutcome{failed: failed, latency: latency}
+	if failed {
+		l.failed++
+	}
+	l.latency += latency
+	l.next = (l.next + 1) % len(l.window)
+}
+
+// Probability - текущая доля сбрасываемых запросов.
+func (l *loadShedder) Probability() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.probabilityLocked()
+}
+
+func (l *loadShedder) probabilityLocked() float64 {
+	if l.count < l.cfg.MinSamples {
+		return 0
+	}
+
+	p := 0.0
+	if rate := float64(l.failed) / float64(l.count); rate > l.cfg.ErrorThreshold {
+		p = (rate - l.cfg.ErrorThreshold) / (1 - l.cfg.ErrorThreshold)
+	}
+	if l.cfg.LatencyThreshold > 0 {
+		if avg := l.latency / time.Duration(l.count); avg > l.cfg.LatencyThreshold {
+			if q := 1 - float64(l.cfg.LatencyThreshold)/float64(avg); q > p {
+				p = q
+			}
+		}
+	}
+	if p > l.cfg.MaxShed {
+		p = l.cfg.MaxShed
+	}
+	return p
+}
+
+// Reject решает, сбросить ли очередной запрос.
+func (l *loadShedder) Reject() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.probabilityLocked()
+	return p > 0 && l.rng.Float64() < p
+}
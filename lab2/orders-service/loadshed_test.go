@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadShedding_KicksInOnHighErrorRate(t *testing.T) {
+	var hits atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: time.Second},
+		Shedder: newLoadShedder(LoadShedConfig{Window: 20, MinSamples: 10, ErrorThreshold: 0.2, MaxShed: 0.9}, 1),
+	}
+
+	const calls = 200
+	shed := 0
+	for i := 0; i < calls; i++ {
+		_, err := client.GetUserByID(context.Background(), 1)
+		if errors.Is(err, ErrUserServiceOverloaded) {
+			shed++
+		}
+	}
+
+	if shed < calls/2 {
+		t.Errorf("Expected most calls to be shed once errors pile up, got %d of %d", shed, calls)
+	}
+	if int(hits.Load())+shed != calls {
+		t.Errorf("Expected shed calls not to reach the server: %d hits + %d shed != %d", hits.Load(), shed, calls)
+	}
+	if hits.Load() < 10 {
+		t.Errorf("Expected some probes to get through, got: %d", hits.Load())
+	}
+}
+
+func TestLoadShedding_EasesAsHealthRecovers(t *testing.T) {
+	l := newLoadShedder(LoadShedConfig{Window: 20, MinSamples: 10, ErrorThreshold: 0.2, MaxShed: 0.9}, 1)
+	for i := 0; i < 20; i++ {
+		l.Record(true, time.Millisecond)
+	}
+	if p := l.Probability(); p != 0.9 {
+		t.Fatalf("Expected shedding capped at 0.9, got: %v", p)
+	}
+
+	prev := l.Probability()
+	for i := 0; i < 20; i++ {
+		l.Record(false, time.Millisecond)
+		p := l.Probability()
+		if p > prev {
+			t.Fatalf("Expected shedding to ease monotonically, went from %v to %v", prev, p)
+		}
+		prev = p
+	}
+	if prev != 0 {
+		t.Errorf("Expected no shedding after a full window of successes, got: %v", prev)
+	}
+}
+
+func TestLoadShedding_LatencyThreshold(t *testing.T) {
+	l := newLoadShedder(LoadShedConfig{Window: 10, MinSamples: 5, ErrorThreshold: 0.5, LatencyThreshold: 100 * time.Millisecond, MaxShed: 0.9}, 1)
+	for i := 0; i < 10; i++ {
+		l.Record(false, 400*time.Millisecond)
+	}
+	if p := l.Probability(); p != 0.75 {
+		t.Errorf("Expected 0.75 shed at 4x latency threshold, got: %v", p)
+	}
+}
+
+func TestLoadShedding_NotFoundIsNotAnError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: time.Second},
+		Shedder: newLoadShedder(LoadShedConfig{Window: 10, MinSamples: 5, ErrorThreshold: 0.2, MaxShed: 0.9}, 1),
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := client.GetUserByID(context.Background(), 1); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("Expected ErrUserNotFound on call %d, got: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	mrand "math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Форматы журнала запросов.
+const (
+	logFormatJSON     = "json"
+	logFormatCombined = "combined"
+)
+
+// Форматы сгенерированных ID запросов.
+const (
+	requestIDHex  = "hex"
+	requestIDUUID = "uuid"
+)
+
+// LogConfig - настройки журнала запросов. Ошибки и медленные запросы
+// пишутся всегда, а успешные быстрые - только с долей SampleRate.
+type LogConfig struct {
+	SampleRate    float64
+	SlowThreshold time.Duration
+	// Format - json (по умолчанию) или combined (Apache Combined Log Format)
+	Format string
+	// IDHeaders - заголовки, из которых по порядку берется ID запроса.
+	// ID возвращается в ответе под первым из них. Пустой список - X-Request-ID.
+	IDHeaders []string
+	// IDFormat - формат сгенерированного ID: hex (по умолчанию) или uuid
+	IDFormat string
+}
+
+var defaultLogConfig = LogConfig{
+	SampleRate:    1,
+	SlowThreshold: 500 * time.Millisecond,
+	Format:        logFormatJSON,
+	IDHeaders:     []string{requestIDHeader},
+	IDFormat:      requestIDHex,
+}
+
+// loadLogConfig читает LOG_SAMPLE_RATE (доля от 0 до 1),
+// LOG_SLOW_THRESHOLD (в формате time.ParseDuration), LOG_FORMAT,
+// REQUEST_ID_HEADERS (имена через запятую) и REQUEST_ID_FORMAT.
+func loadLogConfig(getenv func(string) string) (LogConfig, error) {
+	cfg := defaultLogConfig
+	switch format := getenv("LOG_FORMAT"); format {
+	case "":
+	case logFormatJSON, logFormatCombined:
+		cfg.Format = format
+	default:
+		return cfg, fmt.Errorf("LOG_FORMAT must be json or combined, got %q", format)
+	}
+	if raw := getenv("LOG_SAMPLE_RATE"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return cfg, fmt.Errorf("LOG_SAMPLE_RATE must be a number between 0 and 1, got %q", raw)
+		}
+		cfg.SampleRate = rate
+	}
+	if raw := getenv("LOG_SLOW_THRESHOLD"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("LOG_SLOW_THRESHOLD must be a positive duration, got %q", raw)
+		}
+		cfg.SlowThreshold = d
+	}
+	if raw := getenv("REQUEST_ID_HEADERS"); raw != "" {
+		var headers []string
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if strings.ContainsAny(name, " \t:") {
+				return cfg, fmt.Errorf("REQUEST_ID_HEADERS: invalid header name %q", name)
+			}
+			headers = append(headers, http.CanonicalHeaderKey(name))
+		}
+		if len(headers) == 0 {
+			return cfg, fmt.Errorf("REQUEST_ID_HEADERS must list at least one header, got %q", raw)
+		}
+		cfg.IDHeaders = headers
+	}
+	switch format := getenv("REQUEST_ID_FORMAT"); format {
+	case "":
+	case requestIDHex, requestIDUUID:
+		cfg.IDFormat = format
+	default:
+		return cfg, fmt.Errorf("REQUEST_ID_FORMAT must be hex or uuid, got %q", format)
+	}
+	return cfg, nil
+}
+
+type requestIDKey struct{}
+
+// requestIDFromContext возвращает ID текущего запроса или "".
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// newRequestUUID возвращает случайный UUID версии 4.
+func newRequestUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return newRequestID()
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// statusRecorder запоминает код ответа и размер тела для журнала.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// accessLogEntry - строка журнала в формате JSON.
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Bytes      int       `json:"bytes"`
+	DurationMS int64     `json:"duration_ms"`
+	RequestID  string    `json:"request_id"`
+	RemoteAddr string    `json:"remote_addr"`
+}
+
+// clfField подставляет "-" вместо пустых значений, как принято в CLF.
+func clfField(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// formatCombined собирает строку Combined Log Format:
+// %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+func formatCombined(r *http.Request, start time.Time, status, bytes int) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	size := "-"
+	if bytes > 0 {
+		size = strconv.Itoa(bytes)
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q",
+		clfField(host),
+		clfField(callerFromContext(r.Context())),
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.URL.RequestURI()+" "+r.Proto,
+		status,
+		size,
+		clfField(r.Referer()),
+		clfField(r.UserAgent()),
+	)
+}
+
+type requestLogger struct {
+	cfg    LogConfig
+	logger *log.Logger
+
+	mu  sync.Mutex
+	rng *mrand.Rand
+}
+
+func newRequestLogger(cfg LogConfig, logger *log.Logger, seed int64) *requestLogger {
+	return &requestLogger{cfg: cfg, logger: logger, rng: mrand.New(mrand.NewSource(seed))}
+}
+
+func (l *requestLogger) sampled() bool {
+	if l.cfg.SampleRate >= 1 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Float64() < l.cfg.SampleRate
+}
+
+// newID генерирует ID запроса в формате cfg.IDFormat.
+func (l *requestLogger) newID() string {
+	if l.cfg.IDFormat == requestIDUUID {
+		return newRequestUUID()
+	}
+	return newRequestID()
+}
+
+// middleware присваивает запросу ID (или берет его из заголовка) и пишет
+// строку в журнал. ID выставляется всегда, даже если запрос не попал в выборку.
+func (l *requestLogger) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := l.cfg.IDHeaders
+		if len(headers) == 0 {
+			headers = []string{requestIDHeader}
+		}
+		var id string
+		for _, name := range headers {
+			if id = r.Header.Get(name); id != "" {
+				break
+			}
+		}
+		if id == "" {
+			id = l.newID()
+		}
+		w.Header().Set(headers[0], id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := now()
+		began := time.Now()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		elapsed := time.Since(began)
+
+		if rec.status < 400 && elapsed < l.cfg.SlowThreshold && !l.sampled() {
+			return
+		}
+
+		if l.cfg.Format == logFormatCombined {
+			l.logger.Print(formatCombined(r, start, rec.status, rec.bytes))
+			return
+		}
+		line, _ := json.Marshal(accessLogEntry{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.RequestURI(),
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMS: elapsed.Milliseconds(),
+			RequestID:  id,
+			RemoteAddr: r.RemoteAddr,
+		})
+		l.logger.Print(string(line))
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLogger_SamplesSuccessfulRequests(t *testing.T) {
+	var buf bytes.Buffer
+	l := newRequestLogger(LogConfig{SampleRate: 0.1, SlowThreshold: time.Hour}, log.New(&buf, "", 0), 1)
+
+	var ids []string
+	handler := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, requestIDFromContext(r.Context()))
+	}))
+
+	const total = 1000
+	for i := 0; i < total; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		if rec.Header().Get(requestIDHeader) == "" {
+			t.Fatalf("Expected request ID on every response, request %d had none", i)
+		}
+	}
+
+	logged := strings.Count(buf.String(), "\n")
+	if logged < total/20 || logged > total*3/20 {
+		t.Errorf("Expected roughly 10%% of %d requests logged, got: %d", total, logged)
+	}
+	for i, id := range ids {
+		if id == "" {
+			t.Fatalf("Expected request ID in context for request %d", i)
+		}
+	}
+}
+
+func TestRequestLogger_AlwaysLogsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	l := newRequestLogger(LogConfig{SampleRate: 0, SlowThreshold: time.Hour}, log.New(&buf, "", 0), 1)
+	handler := l.middleware(http.HandlerFunc(notFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set(requestIDHeader, "abc123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry accessLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry.Status != http.StatusNotFound || entry.RequestID != "abc123" || entry.Path != "/nope" {
+		t.Errorf("Expected error request to be logged with its ID, got: %+v", entry)
+	}
+}
+
+func TestRequestLogger_CustomRequestIDHeaders(t *testing.T) {
+	cfg := LogConfig{SlowThreshold: time.Hour, IDHeaders: []string{"X-Correlation-Id", "X-Amzn-Trace-Id"}}
+	var got string
+	handler := newRequestLogger(cfg, log.New(io.Discard, "", 0), 1).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestIDFromContext(r.Context())
+	}))
+
+	// ID из второго заголовка возвращается под первым
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-Amzn-Trace-Id", "root-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got != "root-1" || rec.Header().Get("X-Correlation-Id") != "root-1" {
+		t.Errorf("Expected ID from X-Amzn-Trace-Id echoed as X-Correlation-Id, got context %q, headers %v", got, rec.Header())
+	}
+	if rec.Header().Get(requestIDHeader) != "" || rec.Header().Get("X-Amzn-Trace-Id") != "" {
+		t.Errorf("Expected ID echoed only under the first header, got: %v", rec.Header())
+	}
+
+	// Первый заголовок имеет приоритет
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-Amzn-Trace-Id", "root-1")
+	req.Header.Set("X-Correlation-Id", "corr-2")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "corr-2" {
+		t.Errorf("Expected ID from the first configured header, got: %q", got)
+	}
+
+	// X-Request-ID не настроен и игнорируется
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(requestIDHeader, "ignored")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got == "ignored" || got == "" || rec.Header().Get("X-Correlation-Id") != got {
+		t.Errorf("Expected a generated ID echoed as X-Correlation-Id, got context %q, headers %v", got, rec.Header())
+	}
+}
+
+func TestRequestLogger_GeneratedIDFormat(t *testing.T) {
+	hexID := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, tc := range []struct {
+		format string
+		want   *regexp.Regexp
+	}{
+		{"", hexID},
+		{requestIDHex, hexID},
+		{requestIDUUID, uuidV4},
+	} {
+		cfg := LogConfig{SlowThreshold: time.Hour, IDFormat: tc.format}
+		handler := newRequestLogger(cfg, log.New(io.Discard, "", 0), 1).middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		if id := rec.Header().Get(requestIDHeader); !tc.want.MatchString(id) {
+			t.Errorf("format %q: unexpected generated ID %q", tc.format, id)
+		}
+	}
+}
+
+func TestLoadLogConfig(t *testing.T) {
+	cfg, err := loadLogConfig(envMap(map[string]string{"LOG_SAMPLE_RATE": "0.25", "LOG_SLOW_THRESHOLD": "2s"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.SampleRate != 0.25 || cfg.SlowThreshold != 2*time.Second {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	if _, err := loadLogConfig(envMap(map[string]string{"LOG_SAMPLE_RATE": "1.5"})); err == nil {
+		t.Error("Expected error for sample rate above 1")
+	}
+	if _, err := loadLogConfig(envMap(map[string]string{"LOG_FORMAT": "xml"})); err == nil {
+		t.Error("Expected error for unknown log format")
+	}
+
+	cfg, err = loadLogConfig(envMap(map[string]string{"REQUEST_ID_HEADERS": "x-correlation-id, X-Request-ID", "REQUEST_ID_FORMAT": "uuid"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.IDHeaders, []string{"X-Correlation-Id", "X-Request-Id"}) || cfg.IDFormat != requestIDUUID {
+		t.Errorf("Unexpected request ID config: %+v", cfg)
+	}
+}
+
+func TestRequestLogger_CombinedLogFormat(t *testing.T) {
+	setClock(t, time.Date(2024, 10, 10, 13, 55, 36, 0, time.UTC))
+	var buf bytes.Buffer
+	cfg := defaultLogConfig
+	cfg.Format = logFormatCombined
+	l := newRequestLogger(cfg, log.New(&buf, "", 0), 1)
+
+	handler := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/orders?min_qty=2", nil)
+	req.RemoteAddr = "10.0.0.7:51234"
+	req.Header.Set("Referer", "https://app.example.com/")
+	req.Header.Set("User-Agent", "curl/8.0")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(withCaller(req.Context(), "42")))
+
+	want := `10.0.0.7 - 42 [10/Oct/2024:13:55:36 +0000] "GET /orders?min_qty=2 HTTP/1.1" 200 5 "https://app.example.com/" "curl/8.0"` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected CLF line:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestRequestLogger_CombinedLogFormatDashes(t *testing.T) {
+	var buf bytes.Buffer
+	l := newRequestLogger(LogConfig{SampleRate: 1, SlowThreshold: time.Hour, Format: logFormatCombined}, log.New(&buf, "", 0), 1)
+	l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+
+	if line := buf.String(); !strings.Contains(line, `" 204 - "-" "-"`) || !strings.HasPrefix(line, "192.0.2.1 - - [") {
+		t.Errorf("Expected missing values to be dashes, got: %q", line)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxLookupIDs ограничивает размер одного POST /orders/lookup.
+const maxLookupIDs = 1000
+
+type lookupRequest struct {
+	IDs []int `json:"ids"`
+}
+
+type lookupResponse struct {
+	Orders   []Order `json:"orders"`
+	NotFound []int   `json:"not_found"`
+}
+
+// findOrders возвращает заказы в порядке запрошенных ID (без повторов)
+// и список ID, которых нет. Удаленные заказы считаются отсутствующими.
+// Через него же читают заказы по ID внутренние обработчики, которым
+// удаленный заказ не нужен (например, duplicateOrder).
+func (s *server) findOrders(ids []int) ([]Order, []int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := []Order{}
+	missing := []int{}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		order, exists := s.orders[id]
+		if !exists || order.DeletedAt != nil {
+			missing = append(missing, id)
+			continue
+		}
+		found = append(found, order)
+	}
+	return found, missing
+}
+
+// lookupOrders обрабатывает POST /orders/lookup - пакетное чтение для
+// клиентов, которым список ID не помещается в query string.
+func (s *server) lookupOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var req lookupRequest
+	if err := decodeStrictJSON(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(req.IDs) > maxLookupIDs {
+		writeError(w, http.StatusBadRequest, "validation_failed", fmt.Sprintf("at most %d ids per lookup", maxLookupIDs))
+		return
+	}
+
+	found, missing := s.findOrders(req.IDs)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lookupResponse{Orders: found, NotFound: missing})
+}
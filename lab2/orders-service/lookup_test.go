@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupOrders_MixedIDs(t *testing.T) {
+	data := quantityDataset()
+	deleted := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	order := data[4]
+	order.DeletedAt = &deleted
+	data[4] = order
+	s := newTestServer(t, data)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/lookup", `{"ids":[3,99,1,3,4]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	var resp lookupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	var got []int
+	for _, o := range resp.Orders {
+		got = append(got, o.ID)
+	}
+	if !reflect.DeepEqual(got, []int{3, 1}) {
+		t.Errorf("Expected orders [3 1] in request order without repeats, got: %v", got)
+	}
+	if !reflect.DeepEqual(resp.NotFound, []int{99, 4}) {
+		t.Errorf("Expected not_found [99 4], got: %v", resp.NotFound)
+	}
+}
+
+func TestLookupOrders_Validation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	tooMany := `{"ids":[` + strings.Repeat("1,", maxLookupIDs) + `1]}`
+	for _, body := range []string{`{"ids":"1,2"}`, `{"id":[1]}`, tooMany} {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders/lookup", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got: %d", rec.Code)
+		}
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodGet, "/orders/lookup", "")); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// orderLRU отслеживает порядок обращений к заказам и подсказывает, какие
+// из них вытеснить при превышении емкости. Имеет собственный mutex, так как
+// чтения заказов идут под s.mu.RLock.
+type orderLRU struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List // начало списка - самый свежий заказ
+	items    map[int]*list.Element
+}
+
+func newOrderLRU(capacity int) *orderLRU {
+	return &orderLRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[int]*list.Element{},
+	}
+}
+
+// Touch отмечает обращение к заказу и возвращает ID заказов, которые нужно
+// вытеснить, чтобы уложиться в емкость.
+func (l *orderLRU) Touch(id int) []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[id]; ok {
+		l.ll.MoveToFront(el)
+		return nil
+	}
+	l.items[id] = l.ll.PushFront(id)
+
+	var evicted []int
+	for l.ll.Len() > l.capacity {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		evictedID := oldest.Value.(int)
+		delete(l.items, evictedID)
+		evicted = append(evicted, evictedID)
+	}
+	return evicted
+}
+
+func (l *orderLRU) Remove(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[id]; ok {
+		l.ll.Remove(el)
+		delete(l.items, id)
+	}
+}
+
+// touchOrder отмечает обращение к заказу в LRU. Безопасно вызывать под RLock.
+func (s *server) touchOrder(id int) {
+	if s.limit != nil {
+		s.limit.Touch(id)
+	}
+}
+
+// storeOrder сохраняет заказ и вытесняет самые давние при превышении
+// лимита. Вызывать под s.mu.Lock.
+func (s *server) storeOrder(order Order) {
+	s.orders[order.ID] = order
+	s.shadowPut(order)
+	if s.limit == nil {
+		return
+	}
+
+	evicted := s.limit.Touch(order.ID)
+	for _, id := range evicted {
+		// Вытесненный заказ больше не держит товар
+		s.releaseStock(s.orders[id])
+		delete(s.orders, id)
+		s.shadowDelete(id)
+		delete(s.history, id)
+	}
+	if len(evicted) > 0 {
+		s.publishDetached("order.evicted", evicted)
+	}
+}
+
+// removeOrder удаляет заказ из хранилища. Вызывать под s.mu.Lock.
+func (s *server) removeOrder(id int) {
+	delete(s.orders, id)
+	s.shadowDelete(id)
+	if s.limit != nil {
+		s.limit.Remove(id)
+	}
+}
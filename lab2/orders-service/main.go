@@ -3,178 +3,729 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 type User struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Verified bool   `json:"verified"`
 }
 
 type Order struct {
-	ID       int    `json:"id"`
-	UserID   int    `json:"user_id"`
-	Product  string `json:"product"`
-	Quantity int    `json:"quantity"`
-	Status   string `json:"status"`
-	User     *User  `json:"user,omitempty"`
+	ID int `json:"id"`
+	// OrderNumber - номер для людей, выдается при создании и не меняется
+	OrderNumber string `json:"order_number,omitempty"`
+	UserID      int    `json:"user_id"`
+	Product     string `json:"product"`
+	Quantity    int    `json:"quantity"`
+	Status      string `json:"status"`
+	User        *User  `json:"user,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// DeletedAt выставляется при мягком удалении
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+
+	// Price и Total (Price * Quantity) берутся из products-service при
+	// чтении и не хранятся. Если цену узнать не удалось, поля опускаются.
+	Price *float64 `json:"price,omitempty"`
+	Total *float64 `json:"total,omitempty"`
 }
 
-type UserServiceClient struct {
-	BaseURL string
-	Client  *http.Client
+// now - источник текущего времени, в тестах подменяется фиксированными часами.
+var now = func() time.Time { return time.Now().UTC() }
+
+// server хранит состояние сервиса заказов и зависимости обработчиков.
+type server struct {
+	mu      sync.RWMutex
+	orders  map[int]Order
+	ids     IDGenerator
+	numbers *orderNumbers
+	history map[int][]OrderChange // журнал изменений, только дописывается
+
+	// limit - ограничение числа заказов в памяти; nil - без ограничения
+	limit *orderLRU
+	// shadow - новое хранилище заказов в теневом режиме; nil - не подключено
+	shadow *shadowStore
+	// stock - остатки по товарам; nil - учет остатков выключен
+	stock map[string]int
+	// reservations - отложенный под будущие заказы товар по токенам
+	reservations   map[string]reservation
+	reservationTTL time.Duration
+	// creating - товар, списанный под заказы, которые еще создаются
+	creating map[string]int
+	// held - сколько своего товара держит каждый заказ. Заказы, загруженные
+	// при старте, товар не списывали и в held не попадают
+	held map[int]int
+	// enrichPool ограничивает число одновременных запросов к зависимым
+	// сервисам при обогащении заказов
+	enrichPool chan struct{}
+
+	userClient *UserServiceClient
+	// productClient - клиент products-service; nil - без цен
+	productClient *ProductServiceClient
+	events        EventPublisher
+	flags         *flagStore
+	// adminEnabled открывает эндпоинты /admin/*
+	adminEnabled bool
+	// authSecret - ключ HS256 для токенов в Authorization; пустой - без проверки
+	authSecret []byte
+
+	// maxQuantity - максимальное количество в одном заказе
+	maxQuantity int
+	// defaultStatus - статус заказа, созданного без явного статуса
+	defaultStatus string
+	// unknownStatusPolicy и unknownStatusFallback - что делать с
+	// неизвестным статусом загружаемого заказа
+	unknownStatusPolicy   string
+	unknownStatusFallback string
+	// maxRecent - верхняя граница ?limit= в GET /orders/recent
+	maxRecent int
+	// tagLimits - ограничения на теги заказа
+	tagLimits TagLimits
+	// catalog - допустимые товары; nil - принимается любой товар
+	catalog map[string]bool
+	// maxUpstreamCalls - сколько запросов к user-service может сделать
+	// обработка одного входящего запроса (0 - без ограничения)
+	maxUpstreamCalls int
+
+	// healthTimeout - таймаут опроса каждой зависимости в /healthz
+	healthTimeout time.Duration
+	// userServiceCritical - отказ user-service переводит /healthz в down,
+	// а не в degraded
+	userServiceCritical bool
+
+	// userFlights склеивает одновременные запросы одного пользователя
+	// при чтении заказа
+	userFlights *userFlights
+	// inflight - запросы, которые сейчас обрабатываются
+	inflight *inflightTracker
+	// started и draining - состояние для проб /startup и /ready
+	started  atomic.Bool
+	draining atomic.Bool
+
+	// config - конфигурация запуска для GET /debug/config
+	config Config
 }
 
-func (c *UserServiceClient) GetUserByID(ctx context.Context, userID int) (*User, error) {
-	url := fmt.Sprintf("%s/users/%d", c.BaseURL, userID)
+func newServer(userClient *UserServiceClient, flags Flags) *server {
+	return &server{
+		orders:  map[int]Order{},
+		ids:     newSequentialIDs(),
+		numbers: newOrderNumbers(defaultOrderNumberFormat),
+		history: map[int][]OrderChange{},
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+		reservations:   map[string]reservation{},
+		creating:       map[string]int{},
+		held:           map[int]int{},
+		reservationTTL: defaultReservationTTL,
+
+		enrichPool: make(chan struct{}, 16),
+		userClient: userClient,
+		events:     logPublisher{},
+		flags:      newFlagStore(flags),
+
+		maxQuantity:   defaultMaxOrderQuantity,
+		defaultStatus: "pending",
+		maxRecent:     defaultMaxRecentOrders,
+		tagLimits:     defaultTagLimits,
+		healthTimeout: defaultHealthTimeout,
+		userFlights:   newUserFlights(),
+		inflight:      newInflightTracker(),
+
+		unknownStatusPolicy:   unknownStatusPassthrough,
+		unknownStatusFallback: "pending",
 	}
+}
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to user service: %w", err)
+// seedOrders - демонстрационные заказы, с которыми стартует сервис.
+func seedOrders() []Order {
+	return []Order{
+		{ID: 1, UserID: 1, Product: "Laptop", Quantity: 1, Status: "pending", CreatedAt: now()},
+		{ID: 2, UserID: 2, Product: "Mouse", Quantity: 2, Status: "shipped", CreatedAt: now()},
 	}
-	defer resp.Body.Close()
+}
+
+// loadOrders кладет заказы в хранилище и сообщает их ID генератору.
+func (s *server) loadOrders(list []Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("user not found")
+	for _, order := range list {
+		s.storeOrder(s.applyStatusPolicy(order))
+		s.ids.Observe(order.ID)
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("user service returned status: %d", resp.StatusCode)
+// nextOrderID выдает свободный ID. Вызывается под s.mu: случайные
+// генераторы могут выдать уже занятый ID, тогда берется следующий.
+func (s *server) nextOrderID() int {
+	for {
+		id := s.ids.Next()
+		if _, taken := s.orders[id]; !taken {
+			return id
+		}
+		s.ids.Observe(id)
 	}
+}
+
+// orderFilter - фильтры списка заказов из query-параметров.
+type orderFilter struct {
+	minQty         *int
+	maxQty         *int
+	createdAfter   *time.Time
+	createdBefore  *time.Time
+	includeDeleted bool
+	tag            string
+	number         string
+}
 
-	var user User
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return nil, err
+func parseOrderFilter(r *http.Request) (orderFilter, error) {
+	q := newQueryParams(r)
+	f := orderFilter{
+		minQty:         q.NonNegativeInt("min_qty"),
+		maxQty:         q.NonNegativeInt("max_qty"),
+		createdAfter:   q.Time("created_after"),
+		createdBefore:  q.Time("created_before"),
+		includeDeleted: q.Bool("include_deleted"),
+		tag:            strings.ToLower(q.String("tag")),
+		number:         q.String("number"),
 	}
+	q.Check(f.minQty == nil || f.maxQty == nil || *f.minQty <= *f.maxQty,
+		"min_qty must be less than or equal to max_qty")
+	q.Check(f.createdAfter == nil || f.createdBefore == nil || !f.createdAfter.After(*f.createdBefore),
+		"created_after must not be later than created_before")
+	return f, q.Err()
+}
 
-	return &user, nil
+// matches проверяет заказ по всем фильтрам; границы по времени включительные.
+func (f orderFilter) matches(order Order) bool {
+	if order.DeletedAt != nil && !f.includeDeleted {
+		return false
+	}
+	if f.minQty != nil && order.Quantity < *f.minQty {
+		return false
+	}
+	if f.maxQty != nil && order.Quantity > *f.maxQty {
+		return false
+	}
+	if f.createdAfter != nil && order.CreatedAt.Before(*f.createdAfter) {
+		return false
+	}
+	if f.createdBefore != nil && order.CreatedAt.After(*f.createdBefore) {
+		return false
+	}
+	if f.tag != "" && !hasTag(order.Tags, f.tag) {
+		return false
+	}
+	if f.number != "" && !strings.EqualFold(order.OrderNumber, f.number) {
+		return false
+	}
+	return true
 }
 
-var (
-	orders = map[int]Order{
-		1: {ID: 1, UserID: 1, Product: "Laptop", Quantity: 1, Status: "pending"},
-		2: {ID: 2, UserID: 2, Product: "Mouse", Quantity: 2, Status: "shipped"},
+func (s *server) getOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	fields, nested, err := parseNestedFields(r, Order{}, map[string]interface{}{"user": User{}})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
+		return
 	}
-	mutex      = sync.RWMutex{}
-	nextID     = 3
-	userClient = &UserServiceClient{
-		BaseURL: "http://localhost:8082",
-		Client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
+
+	expand, err := parseExpand(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
 	}
-)
 
-func getOrders(w http.ResponseWriter, r *http.Request) {
-	mutex.RLock()
-	defer mutex.RUnlock()
+	orphaned, err := parseOrphaned(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	includeUserErrors, err := parseIncludeUserErrors(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	mask, err := parseMaskEmail(r, s.flags.Get().MaskEmails)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
 
+	s.mu.RLock()
 	// Создаем копию заказов с информацией о пользователях
-	ordersWithUsers := make([]Order, 0, len(orders))
-	for _, order := range orders {
+	ordersWithUsers := make([]Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		if !filter.matches(order) {
+			continue
+		}
 		ordersWithUsers = append(ordersWithUsers, order)
 	}
+	s.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ordersWithUsers)
+	// ?orphaned= требует запроса к user-service на каждого пользователя
+	if orphaned != nil {
+		if ordersWithUsers, err = s.filterOrphaned(r.Context(), ordersWithUsers, *orphaned); err != nil {
+			writeError(w, http.StatusServiceUnavailable, "user_service_unavailable", err.Error())
+			return
+		}
+	}
+
+	s.attachPrices(r.Context(), ordersWithUsers)
+
+	// Поле user в ?fields= (в том числе user.name) подразумевает ?expand=user.
+	// При X-Embed-Depth: 0 пользователь не встраивается
+	expandUser := (expand["user"] || hasField(fields, "user")) && canEmbed(r.Context())
+	var userErrors map[int]error
+	if expandUser {
+		var unresolved []int
+		if unresolved, userErrors = s.embedUsers(r.Context(), ordersWithUsers); len(unresolved) > 0 {
+			w.Header().Set(unresolvedUsersHeader, joinIDs(unresolved))
+		}
+		for i := range ordersWithUsers {
+			ordersWithUsers[i].User = maskedUser(ordersWithUsers[i].User, mask)
+		}
+	}
+
+	if wantsJSONAPI(r) {
+		doc, err := ordersDocument(ordersWithUsers)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		writeJSONAPI(w, http.StatusOK, doc)
+		return
+	}
+
+	body := make([]interface{}, 0, len(ordersWithUsers))
+	for _, order := range ordersWithUsers {
+		var userErr *userLookupError
+		if err, failed := userErrors[order.UserID]; failed && includeUserErrors {
+			userErr = newUserLookupError(err)
+		}
+		projected, err := projectOrder(order, fields, nested, expandUser, userErr)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		body = append(body, projected)
+	}
+
+	writeBody(w, r, http.StatusOK, body)
 }
 
-func getOrderByID(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/orders/"):]
+// orderRoutes разбирает пути вида /orders/{id}[/action] и передает
+// запрос нужному обработчику.
+func (s *server) orderRoutes(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(r.URL.Path[len("/orders/"):], "/")
+	// ID заказов положительные: PUT с 0 или отрицательным ID иначе
+	// создал бы заказ, который не выдает ни одна стратегия ID
 	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid order ID")
 		return
 	}
 
-	mutex.RLock()
-	order, exists := orders[id]
-	mutex.RUnlock()
+	switch action {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			s.getOrderByID(w, r, id)
+		case http.MethodPut:
+			s.putOrder(w, r, id)
+		case http.MethodPatch:
+			s.patchOrder(w, r, id)
+		case http.MethodDelete:
+			s.deleteOrder(w, r, id)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		}
+	case "history":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+			return
+		}
+		s.getOrderHistory(w, r, id)
+	case "restore":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+			return
+		}
+		s.restoreOrder(w, r, id)
+	case "duplicate":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+			return
+		}
+		s.duplicateOrder(w, r, id)
+	default:
+		notFound(w, r)
+	}
+}
 
-	if !exists {
-		http.Error(w, "Order not found", http.StatusNotFound)
+func (s *server) getOrderByID(w http.ResponseWriter, r *http.Request, id int) {
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
 		return
 	}
 
-	// Получаем данные пользователя
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-	defer cancel()
+	fields, err := parseFields(r, Order{})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
+		return
+	}
 
-	user, err := userClient.GetUserByID(ctx, order.UserID)
+	mask, err := parseMaskEmail(r, s.flags.Get().MaskEmails)
 	if err != nil {
-		log.Printf("Warning: failed to get user %d: %v", order.UserID, err)
-		// Продолжаем работу даже если не удалось получить пользователя
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	s.mu.RLock()
+	order, exists := s.orders[id]
+	if exists {
+		s.touchOrder(id)
 	}
+	s.shadowCompare(id, order, exists)
+	s.mu.RUnlock()
+
+	if !exists || (order.DeletedAt != nil && !includeDeleted) {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+	if notModified(w, r, orderETag(order)) {
+		return
+	}
+	w.Header().Set("ETag", orderETag(order))
 
 	// Создаем ответ с пользовательскими данными
 	responseOrder := order
-	if user != nil {
-		responseOrder.User = user
+	priced := []Order{responseOrder}
+	s.attachPrices(r.Context(), priced)
+	responseOrder = priced[0]
+
+	// Пользователя не запрашиваем, если он не входит в выбранные поля
+	// или, без ?fields=, клиент не запросил профиль with-user. Глубина
+	// встраивания 0 запрещает встраивание в любом случае
+	if ((fields == nil && s.wantsEmbeddedUser(r)) || hasField(fields, "user")) && canEmbed(r.Context()) {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		// Одновременные чтения заказов одного пользователя делят один
+		// запрос к user-service
+		user, err := s.fetchUserCoalesced(ctx, order.UserID)
+		if err != nil {
+			log.Printf("Warning: failed to get user %d: %v", order.UserID, err)
+			// Продолжаем работу даже если не удалось получить пользователя
+			ordersServedDegraded.Add(1)
+			log.Printf("Info: order %d served without user data", order.ID)
+			if s.flags.Get().FallbackUser && !errors.Is(err, ErrUserNotFound) {
+				user = fallbackUser(order.UserID)
+			}
+		}
+		if user != nil {
+			responseOrder.User = maskedUser(user, mask)
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responseOrder)
+	if wantsJSONAPI(r) {
+		doc, err := orderDocument(responseOrder, fields)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		writeJSONAPI(w, http.StatusOK, doc)
+		return
+	}
+
+	var body interface{} = responseOrder
+	if fields != nil {
+		if body, err = selectFields(responseOrder, fields); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+	}
+
+	writeBody(w, r, http.StatusOK, body)
 }
 
-func createOrder(w http.ResponseWriter, r *http.Request) {
-	var newOrder Order
-	if err := json.NewDecoder(r.Body).Decode(&newOrder); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+func (s *server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var req createOrderRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+
+	s.createValidOrder(w, r, req)
+}
+
+// createValidOrder нормализует и проверяет уже разобранный заказ и
+// передает его insertOrder. Общая часть POST /orders и /orders/batch.
+func (s *server) createValidOrder(w http.ResponseWriter, r *http.Request, req createOrderRequest) {
+	newOrder := req.Order
+	if err := s.normalizeOrder(&newOrder); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	if err := validateOrder(newOrder, s.maxQuantity); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	if err := s.checkProduct(newOrder.Product); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	s.insertOrder(w, r, newOrder, req.ReservationToken)
+}
+
+// insertOrder проверяет пользователя, резервирует остатки, присваивает
+// заказу новый ID и отвечает 201. Общая часть POST /orders и duplicate.
+//
+// Проверка пользователя и вставка не атомарны: пользователя могут удалить
+// между ними, и заказ будет ссылаться на несуществующего пользователя.
+// Флаг recheck_user_on_create повторяет проверку после резервирования
+// остатков, сужая окно до одного запроса, но не закрывая его полностью.
+//
+// ?fields= сокращает тело ответа до выбранных полей, например ?fields=id
+// при массовом создании; Location отдается в любом случае.
+//
+// token - токен резерва из POST /inventory/reserve или "".
+func (s *server) insertOrder(w http.ResponseWriter, r *http.Request, newOrder Order, token string) {
+	// Поля проверяем до побочных эффектов, чтобы ошибка в них не
+	// оставила созданный заказ
+	fields, err := parseFields(r, Order{})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
 		return
 	}
 
 	// Проверяем существование пользователя
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-	defer cancel()
+	if !s.checkOrderUser(w, r, newOrder.UserID) {
+		return
+	}
 
-	_, err := userClient.GetUserByID(ctx, newOrder.UserID)
+	newOrder.User = nil
+	newOrder.DeletedAt = nil
+	newOrder.CreatedAt = now()
+	newOrder.OrderNumber = ""
+
+	// С токеном резерва товар уже отложен - забираем его вместо
+	// повторного резервирования
+	var held reservation
+	s.mu.Lock()
+	if token != "" {
+		held, err = s.consumeReservation(token, newOrder.Product, newOrder.Quantity)
+	} else {
+		err = s.reserveStock(newOrder.Product, newOrder.Quantity)
+	}
+	if err == nil {
+		s.holdCreating(newOrder.Product, newOrder.Quantity)
+	}
+	s.mu.Unlock()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("User not found or service unavailable: %v", err), http.StatusBadRequest)
+		writeStockError(w, err)
+		return
+	}
+
+	if s.flags.Get().RecheckUserOnCreate && !s.recheckOrderUser(w, r, newOrder.UserID) {
+		s.mu.Lock()
+		s.holdCreating(newOrder.Product, -newOrder.Quantity)
+		if token != "" {
+			s.reservations[token] = held
+		} else {
+			s.reserveStock(newOrder.Product, -newOrder.Quantity)
+		}
+		s.mu.Unlock()
 		return
 	}
 
-	mutex.Lock()
-	newOrder.ID = nextID
-	orders[nextID] = newOrder
-	nextID++
-	mutex.Unlock()
+	s.mu.Lock()
+	s.holdCreating(newOrder.Product, -newOrder.Quantity)
+	newOrder.ID = s.nextOrderID()
+	newOrder.OrderNumber = s.numbers.Next(newOrder.CreatedAt)
+	s.storeOrder(newOrder)
+	s.holdStock(newOrder.ID, newOrder.Quantity)
+	s.recordOrderChange(nil, newOrder, callerFromContext(r.Context()))
+	s.mu.Unlock()
 
+	w.Header().Set("Location", fmt.Sprintf("/orders/%d", newOrder.ID))
+	if respondMinimal(w, r, http.StatusCreated) {
+		return
+	}
+
+	var body interface{} = newOrder
+	if fields != nil {
+		if body, err = selectFields(newOrder, fields); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+	}
+	writeBody(w, r, http.StatusCreated, body)
+}
+
+// ErrorResponse - единый формат ошибок API.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(newOrder)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
 }
 
+// notFound отвечает на запросы к неизвестным маршрутам.
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "not_found", "Route not found")
+}
+
+// healthCheck - проба /live: процесс отвечает.
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-func main() {
-	http.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			getOrders(w, r)
+			s.getOrders(w, r)
 		case http.MethodPost:
-			createOrder(w, r)
+			s.createOrder(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
 		}
 	})
 
-	http.HandleFunc("/orders/", getOrderByID)
-	http.HandleFunc("/health", healthCheck)
+	mux.HandleFunc("/orders/", s.orderRoutes)
+	mux.HandleFunc("/orders/batch", s.createOrdersBatch)
+	mux.HandleFunc("/orders/lookup", s.lookupOrders)
+	mux.HandleFunc("/orders/stats", s.getOrderStats)
+	mux.HandleFunc("/orders/ids", s.getOrderIDs)
+	mux.HandleFunc("/orders/recent", s.getRecentOrders)
+	mux.HandleFunc("/orders/products", s.getProductCounts)
+	mux.HandleFunc("/orders/reassign", s.reassignOrders)
+	mux.HandleFunc("/inventory/reserve", s.reserveInventory)
+	mux.HandleFunc("/inventory/release/", s.releaseInventory)
+	mux.HandleFunc("/health", healthCheck)
+	mux.HandleFunc("/live", healthCheck)
+	mux.HandleFunc("/startup", s.startupCheck)
+	mux.HandleFunc("/ready", s.readyCheck)
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/inflight", s.adminOnly(s.getInflight))
+	mux.HandleFunc("/debug/circuit", s.adminOnly(s.getCircuit))
+	mux.HandleFunc("/debug/config", s.adminOnly(s.getDebugConfig))
+	mux.HandleFunc("/admin/flags", s.adminOnly(s.handleFlags))
+	mux.HandleFunc("/admin/cache/users", s.adminOnly(s.handleUserCache))
+	mux.HandleFunc("/admin/cache/users/", s.adminOnly(s.handleUserCache))
+	mux.HandleFunc("/admin/cache/warmup", s.adminOnly(s.handleCacheWarmup))
+	mux.HandleFunc("/admin/snapshot", s.adminOnly(s.handleSnapshot))
+	mux.HandleFunc("/admin/restore", s.adminOnly(s.handleRestore))
+	// "/" совпадает со всем, что не подошло под более конкретные шаблоны
+	mux.HandleFunc("/", notFound)
+
+	return jsonCaseMiddleware(embedDepthMiddleware(s.upstreamCallLimit(mux)))
+}
+
+// problems переписывает ошибки в problem+json по Accept или флагу problem_json.
+func (s *server) problems(next http.Handler) http.Handler {
+	return problemMiddleware(func() bool { return s.flags.Get().ProblemJSON }, next)
+}
+
+// bodyChecks ставит перед обработчиками распаковку gzip и проверки по
+// заголовкам. Content-Type проверяется еще и до распаковки: она читает
+// тело, а с Expect: 100-continue отказ должен уйти до его загрузки.
+func (s *server) bodyChecks(cfg Config, next http.Handler) http.Handler {
+	strict := func() bool { return s.flags.Get().StrictContentType }
+	return limitRequestBody(cfg.MaxRequestBody, requireJSONContentType(strict, gzipRequestMiddleware(cfg.MaxDecompressedBody, next)))
+}
 
-	log.Println("Orders service started on :8082")
-	log.Fatal(http.ListenAndServe(":8082", nil))
+func main() {
+	cfg, err := LoadConfig(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	s := newServerFromConfig(cfg)
+	s.shadow = &shadowStore{store: newMemoryOrderStore(), logger: log.Default()}
+
+	queue := newEventQueue(cfg.EventQueue, s.events)
+	s.events = queue
+	s.loadOrders(seedOrders())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s.startReservationSweeper(ctx, time.Minute)
+	if cfg.OrphanCheckInterval > 0 {
+		s.startOrphanReconciler(ctx, cfg.OrphanCheckInterval)
+	}
+
+	// У строк журнала запросов своя метка времени, префикс log не нужен
+	requests := newRequestLogger(cfg.Log, log.New(os.Stdout, "", 0), time.Now().UnixNano())
+
+	handler := requests.middleware(s.authenticate(s.inflight.middleware(timeoutMiddleware(RouteTimeouts{Default: cfg.RequestTimeout, Routes: cfg.RouteTimeouts}, s.bodyChecks(cfg, s.routes())))))
+	srv := newHTTPServer(cfg.Addr, responseHeadersMiddleware(cfg.Headers, corsMiddleware(cfg.CORS, s.problems(s.startupGate(handler)))), cfg.Server, cfg.MaxHeaderBytes)
+	log.Printf("Orders service started on %s", cfg.Addr)
+
+	// Пробы отвечают уже во время ожидания зависимостей; остальные
+	// маршруты до markStarted отвечают 503 (startupGate)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	if cfg.StartupWait {
+		if err := s.waitForDependencies(ctx, cfg.StartupWaitTimeout, sleepWithinBudget); err != nil {
+			log.Fatalf("Startup: %v", err)
+		}
+	}
+	s.markStarted()
+	<-ctx.Done()
+
+	// Сначала дожидаемся обработчиков, затем доставляем накопленные события
+	s.startDraining()
+	if err := drainServer(srv, s.inflight, cfg.DrainTimeout, log.Default()); err != nil {
+		log.Printf("Shutdown: %v", err)
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	if err := queue.Close(flushCtx); err != nil {
+		log.Printf("Event queue was not flushed: %v", err)
+	}
+	log.Println("Orders service stopped")
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaskEmail(t *testing.T) {
+	for in, want := range map[string]string{
+		"alice@example.com": "a***@example.com",
+		"я@пример.рф":       "я***@пример.рф",
+		"@example.com":      "***",
+		"not-an-email":      "***",
+	} {
+		if got := maskEmail(in); got != want {
+			t.Errorf("maskEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGetOrderByID_MasksEmbeddedEmail(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3?mask_email=true", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if order := decodeOrder(t, rec); order.User == nil || order.User.Email != "u***@example.com" {
+		t.Errorf("Expected masked email, got: %+v", order.User)
+	}
+
+	// Флаг включает маскирование по умолчанию, параметр его отменяет
+	s.setFlags(func(f *Flags) { f.MaskEmails = true })
+	if order := decodeOrder(t, s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3", nil)))); order.User.Email != "u***@example.com" {
+		t.Errorf("Expected masked email from flag, got: %q", order.User.Email)
+	}
+	if order := decodeOrder(t, s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3?mask_email=false", nil)))); order.User.Email != "user2@example.com" {
+		t.Errorf("Expected unmasked email, got: %q", order.User.Email)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/3?mask_email=maybe", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+func TestGetOrders_MasksEmbeddedEmails(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	for _, target := range []string{"/orders?expand=user&mask_email=true", "/orders?fields=id,user.email&mask_email=true"} {
+		rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got: %d", target, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "user1@example.com") || !strings.Contains(rec.Body.String(), "u***@example.com") {
+			t.Errorf("%s: expected masked emails, got: %s", target, rec.Body)
+		}
+	}
+
+	// Флаг включает маскирование по умолчанию, параметр его отменяет
+	s.setFlags(func(f *Flags) { f.MaskEmails = true })
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?expand=user", nil)); strings.Contains(rec.Body.String(), "user1@example.com") {
+		t.Errorf("Expected masked emails from flag, got: %s", rec.Body)
+	}
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?expand=user&mask_email=false", nil)); !strings.Contains(rec.Body.String(), "user1@example.com") {
+		t.Errorf("Expected unmasked emails, got: %s", rec.Body)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?mask_email=maybe", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
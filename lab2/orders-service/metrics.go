@@ -0,0 +1,34 @@
+package main
+
+import "expvar"
+
+// Метрики публикуются через expvar и доступны на /debug/vars.
+var (
+	// ordersServedDegraded - заказы, отданные без данных пользователя
+	// из-за ошибки user-service.
+	ordersServedDegraded = expvar.NewInt("orders_served_degraded_total")
+
+	// eventsDropped - события, потерянные из-за переполнения очереди
+	// публикации или опубликованные после ее закрытия.
+	eventsDropped = expvar.NewInt("events_dropped_total")
+
+	// shadowMismatches - расхождения чтений между основным и теневым
+	// хранилищем заказов.
+	shadowMismatches = expvar.NewInt("orders_shadow_mismatches_total")
+
+	// ordersOrphaned - заказы, чьи пользователи не нашлись в user-service
+	// при последней фоновой сверке.
+	ordersOrphaned = expvar.NewInt("orders_orphaned")
+
+	// userRetriesThrottled - повторы к user-service, не выполненные из-за
+	// исчерпанного бюджета повторов.
+	userRetriesThrottled = expvar.NewInt("user_service_retries_throttled_total")
+
+	// upstreamCallsLimited - запросы к user-service, не выполненные из-за
+	// лимита исходящих запросов на один входящий.
+	upstreamCallsLimited = expvar.NewInt("upstream_calls_limited_total")
+
+	// userFetchesCoalesced - запросы пользователя для GET /orders/{id},
+	// получившие результат уже выполнявшегося запроса того же пользователя.
+	userFetchesCoalesced = expvar.NewInt("user_fetches_coalesced_total")
+)
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Минимальная реализация MessagePack без внешних зависимостей. Значение
+// сначала сериализуется в JSON, поэтому теги json, omitempty и
+// MarshalJSON работают так же, как в JSON-ответах; время передается
+// строкой RFC 3339, а не расширением timestamp. Поддерживаются nil, bool,
+// целые, float, строки, массивы и словари со строковыми ключами.
+
+// maxMsgpackDepth ограничивает вложенность при разборе, чтобы глубокий
+// документ не исчерпал стек.
+const maxMsgpackDepth = 64
+
+var errMsgpackTruncated = errors.New("msgpack: unexpected end of data")
+
+// msgpackMarshal кодирует v в MessagePack.
+func msgpackMarshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := jsonUnmarshalNumbers(data, &doc); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(nil, doc)
+}
+
+// msgpackUnmarshal декодирует MessagePack в v по правилам encoding/json.
+func msgpackUnmarshal(data []byte, v interface{}) error {
+	raw, err := msgpackToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// msgpackToJSON переводит документ MessagePack в JSON.
+func msgpackToJSON(data []byte) ([]byte, error) {
+	d := msgpackDecoder{data: data}
+	doc, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes after value", len(d.data)-d.pos)
+	}
+	return json.Marshal(doc)
+}
+
+func jsonUnmarshalNumbers(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, n), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("msgpack: invalid number %q", v)
+		}
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case string:
+		return appendMsgpackString(b, v), nil
+	case []interface{}:
+		b = appendMsgpackHeader(b, len(v), 0x90, 16, 0xdc)
+		var err error
+		for _, item := range v {
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// Порядок ключей фиксирован, чтобы одинаковые данные давали одинаковые байты
+		sort.Strings(keys)
+		b = appendMsgpackHeader(b, len(keys), 0x80, 16, 0xde)
+		var err error
+		for _, key := range keys {
+			b = appendMsgpackString(b, key)
+			if b, err = appendMsgpack(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackHeader пишет заголовок массива или словаря: fix-формат
+// для n < fixLimit, иначе 16- или 32-битную длину (код wide и wide+1).
+func appendMsgpackHeader(b []byte, n int, fix byte, fixLimit int, wide byte) []byte {
+	switch {
+	case n < fixLimit:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+	}
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) take(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgpackTruncated
+	}
+	out := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return out, nil
+}
+
+// uint читает беззнаковое целое длиной size байт.
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	raw, err := d.take(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range raw {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxMsgpackDepth {
+		return nil, errors.New("msgpack: document is nested too deeply")
+	}
+	head, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(int(c&0x0f), depth)
+	case c&0xf0 == 0x80:
+		return d.object(int(c&0x0f), depth)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Расширяем знак с size байт до 64 бит
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		// bin читается как строка: в JSON-модели другого представления нет
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.object(int(n), depth)
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type code 0x%02x", c)
+	}
+}
+
+func (d *msgpackDecoder) str(n int) (interface{}, error) {
+	raw, err := d.take(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (d *msgpackDecoder) array(n, depth int) (interface{}, error) {
+	// Каждый элемент занимает хотя бы байт: длину больше остатка данных
+	// не выделяем заранее
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	out := make([]interface{}, n)
+	for i := range out {
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = item
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) object(n, depth int) (interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errMsgpackTruncated
+	}
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key must be a string, got %T", key)
+		}
+		if out[name], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
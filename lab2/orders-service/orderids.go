@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// orderIDs возвращает отсортированные ID заказов, прошедших фильтр.
+// Считается под RLock без копирования самих заказов.
+func (s *server) orderIDs(filter orderFilter) []int {
+	s.mu.RLock()
+	ids := make([]int, 0, len(s.orders))
+	for id, order := range s.orders {
+		if filter.matches(order) {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Ints(ids)
+	return ids
+}
+
+// getOrderIDs обрабатывает GET /orders/ids - список ID для синхронизации
+// без сериализации заказов. Принимает те же фильтры, что и GET /orders.
+func (s *server) getOrderIDs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.orderIDs(filter))
+}
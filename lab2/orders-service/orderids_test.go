@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func decodeIDs(t *testing.T, rec *httptest.ResponseRecorder) []int {
+	t.Helper()
+	var ids []int
+	if err := json.NewDecoder(rec.Body).Decode(&ids); err != nil {
+		t.Fatalf("Failed to decode IDs: %v", err)
+	}
+	return ids
+}
+
+func TestGetOrderIDs_MatchesStoredOrders(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	deletedAt := time.Now()
+	s.orders[42] = Order{ID: 42, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending", DeletedAt: &deletedAt}
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/ids", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	var want []int
+	for id, order := range s.orders {
+		if order.DeletedAt == nil {
+			want = append(want, id)
+		}
+	}
+	sort.Ints(want)
+	if got := decodeIDs(t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected IDs %v, got: %v", want, got)
+	}
+
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/orders/ids?include_deleted=true", nil))
+	if got := decodeIDs(t, rec); len(got) != len(want)+1 || got[len(got)-1] != 42 {
+		t.Errorf("Expected deleted order 42 with include_deleted, got: %v", got)
+	}
+}
+
+func TestGetOrderIDs_FiltersAndErrors(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/ids", nil))
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("Expected empty array for no orders, got: %q", body)
+	}
+
+	s = newTestServer(t, quantityDataset())
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/orders/ids?min_qty=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid filter, got: %d", rec.Code)
+	}
+
+	rec = s.serve(httptest.NewRequest(http.MethodPost, "/orders/ids", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got: %d", rec.Code)
+	}
+}
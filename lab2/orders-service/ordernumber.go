@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OrderNumberFormat - шаблон номера заказа для людей: префикс, год
+// создания и порядковый номер с ведущими нулями, например ORD-2024-000123.
+// Номер только показывается и ищется; маршруты и хранилище работают с ID.
+type OrderNumberFormat struct {
+	Prefix string `json:"prefix"`
+	// Digits - минимальная ширина порядкового номера; длинные номера не обрезаются
+	Digits int `json:"digits"`
+}
+
+var defaultOrderNumberFormat = OrderNumberFormat{Prefix: "ORD", Digits: 6}
+
+// loadOrderNumberFormat читает ORDER_NUMBER_PREFIX и ORDER_NUMBER_DIGITS.
+func loadOrderNumberFormat(getenv func(string) string) (OrderNumberFormat, error) {
+	format := defaultOrderNumberFormat
+	if raw := getenv("ORDER_NUMBER_PREFIX"); raw != "" {
+		if strings.ContainsAny(raw, " \t/?#") {
+			return format, fmt.Errorf("ORDER_NUMBER_PREFIX must not contain spaces or URL delimiters, got %q", raw)
+		}
+		format.Prefix = raw
+	}
+	if raw := getenv("ORDER_NUMBER_DIGITS"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > 18 {
+			return format, fmt.Errorf("ORDER_NUMBER_DIGITS must be between 1 and 18, got %q", raw)
+		}
+		format.Digits = v
+	}
+	return format, nil
+}
+
+// Format собирает номер из года и порядкового номера. Без префикса номер
+// начинается с года.
+func (f OrderNumberFormat) Format(year, seq int) string {
+	number := fmt.Sprintf("%d-%0*d", year, f.Digits, seq)
+	if f.Prefix == "" {
+		return number
+	}
+	return f.Prefix + "-" + number
+}
+
+// orderNumbers выдает номера заказов. Счетчик общий и с годом не
+// сбрасывается, поэтому номер уникален, даже если часы отстали.
+type orderNumbers struct {
+	format OrderNumberFormat
+
+	mu   sync.Mutex
+	next int
+}
+
+func newOrderNumbers(format OrderNumberFormat) *orderNumbers {
+	return &orderNumbers{format: format, next: 1}
+}
+
+// Next возвращает номер для заказа, созданного в момент created.
+func (g *orderNumbers) Next(created time.Time) string {
+	g.mu.Lock()
+	seq := g.next
+	g.next++
+	g.mu.Unlock()
+	return g.format.Format(created.Year(), seq)
+}
+
+// peek возвращает порядковый номер, который получит следующий заказ.
+func (g *orderNumbers) peek() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.next
+}
+
+// reset переставляет счетчик при восстановлении из снимка.
+func (g *orderNumbers) reset(next int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next = next
+}
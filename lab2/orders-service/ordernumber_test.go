@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrderNumberFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format OrderNumberFormat
+		year   int
+		seq    int
+		want   string
+	}{
+		{defaultOrderNumberFormat, 2024, 123, "ORD-2024-000123"},
+		{OrderNumberFormat{Prefix: "SHOP", Digits: 3}, 2025, 7, "SHOP-2025-007"},
+		{OrderNumberFormat{Prefix: "SHOP", Digits: 3}, 2025, 12345, "SHOP-2025-12345"},
+		{OrderNumberFormat{Digits: 4}, 2024, 1, "2024-0001"},
+	} {
+		if got := tc.format.Format(tc.year, tc.seq); got != tc.want {
+			t.Errorf("%+v.Format(%d, %d) = %q, want %q", tc.format, tc.year, tc.seq, got, tc.want)
+		}
+	}
+}
+
+func TestLoadOrderNumberFormat(t *testing.T) {
+	format, err := loadOrderNumberFormat(envMap(map[string]string{"ORDER_NUMBER_PREFIX": "WEB", "ORDER_NUMBER_DIGITS": "8"}))
+	if err != nil || format.Prefix != "WEB" || format.Digits != 8 {
+		t.Errorf("Unexpected format %+v (err: %v)", format, err)
+	}
+	for _, env := range []map[string]string{
+		{"ORDER_NUMBER_PREFIX": "A B"},
+		{"ORDER_NUMBER_PREFIX": "A/B"},
+		{"ORDER_NUMBER_DIGITS": "0"},
+		{"ORDER_NUMBER_DIGITS": "many"},
+	} {
+		if _, err := loadOrderNumberFormat(envMap(env)); err == nil {
+			t.Errorf("%v: expected validation error", env)
+		}
+	}
+}
+
+func TestOrderNumbers_UniqueUnderConcurrency(t *testing.T) {
+	g := newOrderNumbers(defaultOrderNumberFormat)
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	const workers, perWorker = 8, 100
+	results := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				results <- g.Next(created)
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := map[string]bool{}
+	for number := range results {
+		if seen[number] {
+			t.Fatalf("Duplicate order number %q", number)
+		}
+		seen[number] = true
+	}
+	if len(seen) != workers*perWorker {
+		t.Errorf("Expected %d numbers, got: %d", workers*perWorker, len(seen))
+	}
+}
+
+func TestCreateOrder_AssignsOrderNumber(t *testing.T) {
+	setClock(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+
+	pattern := regexp.MustCompile(`^ORD-2024-\d{6}$`)
+	numbers := map[string]int{}
+	for i := 0; i < 3; i++ {
+		// Номер из тела запроса игнорируется
+		rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"order_number":"FAKE-1"}`))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+		}
+		order := decodeOrder(t, rec)
+		if !pattern.MatchString(order.OrderNumber) {
+			t.Errorf("Unexpected order number %q", order.OrderNumber)
+		}
+		if _, dup := numbers[order.OrderNumber]; dup {
+			t.Errorf("Duplicate order number %q", order.OrderNumber)
+		}
+		numbers[order.OrderNumber] = order.ID
+	}
+
+	// PUT заменяет заказ, но номер сохраняет
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Ink","quantity":2,"order_number":"FAKE-2"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if got := decodeOrder(t, rec).OrderNumber; got != "ORD-2024-000001" {
+		t.Errorf("Expected PUT to keep the order number, got: %q", got)
+	}
+
+	for number, id := range numbers {
+		code, ids := listOrderIDs(t, s, "/orders?number="+number)
+		if code != http.StatusOK || len(ids) != 1 || ids[0] != id {
+			t.Errorf("number=%s: expected [%d], got: %d %v", number, id, code, ids)
+		}
+	}
+	if _, ids := listOrderIDs(t, s, "/orders?number=ord-2024-000002"); fmt.Sprint(ids) != fmt.Sprint([]int{numbers["ORD-2024-000002"]}) {
+		t.Errorf("Expected case-insensitive number search, got: %v", ids)
+	}
+	if _, ids := listOrderIDs(t, s, "/orders?number=FAKE-1"); len(ids) != 0 {
+		t.Errorf("Expected no match for a client-supplied number, got: %v", ids)
+	}
+}
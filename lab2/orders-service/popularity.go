@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// productCount - строка отчета GET /orders/products.
+type productCount struct {
+	Product       string `json:"product"`
+	Orders        int    `json:"orders"`
+	TotalQuantity int    `json:"total_quantity"`
+}
+
+// productCounts считает заказы и количество по каждому товару за один
+// проход под блокировкой на чтение. Сортирует по числу заказов по
+// убыванию, при равенстве - по количеству по убыванию и по названию.
+// Удаленные заказы не учитываются.
+func (s *server) productCounts() []productCount {
+	byProduct := map[string]*productCount{}
+	s.mu.RLock()
+	for _, order := range s.orders {
+		if order.DeletedAt != nil {
+			continue
+		}
+		pc, ok := byProduct[order.Product]
+		if !ok {
+			pc = &productCount{Product: order.Product}
+			byProduct[order.Product] = pc
+		}
+		pc.Orders++
+		pc.TotalQuantity += order.Quantity
+	}
+	s.mu.RUnlock()
+
+	counts := make([]productCount, 0, len(byProduct))
+	for _, pc := range byProduct {
+		counts = append(counts, *pc)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.Orders != b.Orders {
+			return a.Orders > b.Orders
+		}
+		if a.TotalQuantity != b.TotalQuantity {
+			return a.TotalQuantity > b.TotalQuantity
+		}
+		return a.Product < b.Product
+	})
+	return counts
+}
+
+// getProductCounts обрабатывает GET /orders/products[?limit=N]: отчет о
+// популярности товаров, с ?limit= - только первые N.
+func (s *server) getProductCounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	q := newQueryParams(r)
+	limit := q.Int("limit")
+	q.Check(limit == nil || *limit > 0, "limit must be a positive integer")
+	if err := q.Err(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	counts := s.productCounts()
+	if limit != nil && len(counts) > *limit {
+		counts = counts[:*limit]
+	}
+	writeBody(w, r, http.StatusOK, counts)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func popularityDataset() map[int]Order {
+	deleted := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Mouse", Quantity: 2, Status: "pending"},
+		2: {ID: 2, UserID: 2, Product: "Laptop", Quantity: 1, Status: "shipped"},
+		3: {ID: 3, UserID: 1, Product: "Mouse", Quantity: 3, Status: "delivered"},
+		4: {ID: 4, UserID: 3, Product: "Keyboard", Quantity: 4, Status: "pending"},
+		5: {ID: 5, UserID: 2, Product: "Laptop", Quantity: 1, Status: "pending"},
+		6: {ID: 6, UserID: 3, Product: "Cable", Quantity: 1, Status: "pending"},
+		// Удаленный заказ в отчет не попадает
+		7: {ID: 7, UserID: 3, Product: "Cable", Quantity: 9, Status: "pending", DeletedAt: &deleted},
+	}
+}
+
+func getProductCounts(t *testing.T, s *server, target string) []productCount {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got: %d (%s)", target, rec.Code, rec.Body)
+	}
+	var counts []productCount
+	if err := json.NewDecoder(rec.Body).Decode(&counts); err != nil {
+		t.Fatalf("Failed to decode product counts: %v", err)
+	}
+	return counts
+}
+
+func TestGetProductCounts(t *testing.T) {
+	s := newTestServer(t, popularityDataset())
+
+	want := []productCount{
+		{Product: "Mouse", Orders: 2, TotalQuantity: 5},
+		{Product: "Laptop", Orders: 2, TotalQuantity: 2},
+		{Product: "Keyboard", Orders: 1, TotalQuantity: 4},
+		{Product: "Cable", Orders: 1, TotalQuantity: 1},
+	}
+	if got := getProductCounts(t, s, "/orders/products"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected counts:\n got: %+v\nwant: %+v", got, want)
+	}
+	if got := getProductCounts(t, s, "/orders/products?limit=2"); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Expected top 2, got: %+v", got)
+	}
+	if got := getProductCounts(t, s, "/orders/products?limit=50"); len(got) != len(want) {
+		t.Errorf("Expected all products for a large limit, got: %+v", got)
+	}
+}
+
+func TestGetProductCounts_Validation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	if got := getProductCounts(t, s, "/orders/products"); len(got) != 0 {
+		t.Errorf("Expected an empty report without orders, got: %+v", got)
+	}
+	for _, target := range []string{"/orders/products?limit=0", "/orders/products?limit=top"} {
+		if rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", target, rec.Code)
+		}
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders/products", `{}`)); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// Три пробы для оркестратора:
+//   - /startup - 200, когда инициализация закончена: заказы загружены и
+//     зависимости дождались (STARTUP_WAIT_FOR_DEPENDENCIES);
+//   - /live (и старый /health) - 200, пока процесс отвечает; перезапуск
+//     по ней не зависит от состояния зависимостей;
+//   - /ready - можно ли слать трафик: старт завершен, сервис не
+//     останавливается и критичные зависимости отвечают.
+// Пробы ничего не меняют и отвечают одинаково на любой метод.
+
+// markStarted завершает старт: /startup и /ready начинают отвечать 200.
+func (s *server) markStarted() {
+	if !s.started.Swap(true) {
+		log.Println("Startup complete: /startup reports 200")
+	}
+}
+
+// startDraining выводит сервис из балансировки: /ready отвечает 503,
+// /live остается 200.
+func (s *server) startDraining() {
+	if !s.draining.Swap(true) {
+		log.Println("Draining: /ready now reports 503")
+	}
+}
+
+// startupGate до markStarted отвечает 503 на все, кроме проб: сервер
+// слушает порт уже во время ожидания зависимостей, чтобы оркестратор видел
+// /startup и /live, а клиенты без балансировщика не смотрят на /ready.
+func (s *server) startupGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.started.Load() && !publicPaths[r.URL.Path] {
+			writeError(w, http.StatusServiceUnavailable, "starting", "Service is starting")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) startupCheck(w http.ResponseWriter, r *http.Request) {
+	if !s.started.Load() {
+		writeError(w, http.StatusServiceUnavailable, "starting", "Service is starting")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// readyCheck отвечает отчетом как у /healthz. До конца старта и во время
+// остановки зависимости не опрашиваются.
+func (s *server) readyCheck(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case !s.started.Load():
+		writeHealthReport(w, healthReport{Status: "down", Checks: map[string]checkResult{"startup": {Status: "starting"}}})
+	case s.draining.Load():
+		writeHealthReport(w, healthReport{Status: "down", Checks: map[string]checkResult{"drain": {Status: "draining"}}})
+	default:
+		writeHealthReport(w, s.checkDependencies(r.Context()))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probeStatus(t *testing.T, s *server, path string) int {
+	t.Helper()
+	return s.serve(httptest.NewRequest(http.MethodGet, path, nil)).Code
+}
+
+func TestProbes_Lifecycle(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, step := range []struct {
+		name                 string
+		advance              func()
+		startup, live, ready int
+	}{
+		{"starting", func() {}, http.StatusServiceUnavailable, http.StatusOK, http.StatusServiceUnavailable},
+		{"started", s.markStarted, http.StatusOK, http.StatusOK, http.StatusOK},
+		{"draining", s.startDraining, http.StatusOK, http.StatusOK, http.StatusServiceUnavailable},
+	} {
+		step.advance()
+		if code := probeStatus(t, s, "/startup"); code != step.startup {
+			t.Errorf("%s: expected /startup %d, got: %d", step.name, step.startup, code)
+		}
+		for _, path := range []string{"/live", "/health"} {
+			if code := probeStatus(t, s, path); code != step.live {
+				t.Errorf("%s: expected %s %d, got: %d", step.name, path, step.live, code)
+			}
+		}
+		if code := probeStatus(t, s, "/ready"); code != step.ready {
+			t.Errorf("%s: expected /ready %d, got: %d", step.name, step.ready, code)
+		}
+	}
+}
+
+func TestReady_FollowsDependencies(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	s.markStarted()
+
+	if code := probeStatus(t, s, "/ready"); code != http.StatusOK {
+		t.Errorf("Expected non-critical dependency failure to keep /ready 200, got: %d", code)
+	}
+
+	s.userServiceCritical = true
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/ready", nil))
+	var report healthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode ready report: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || report.Checks["user_service"].Status != "down" {
+		t.Errorf("Expected 503 with user_service down, got: %d %+v", rec.Code, report)
+	}
+	// Живость от зависимостей не зависит
+	if code := probeStatus(t, s, "/live"); code != http.StatusOK {
+		t.Errorf("Expected /live 200 with a failing dependency, got: %d", code)
+	}
+}
+
+func TestProbes_Idempotent(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	s.markStarted()
+	s.markStarted()
+
+	for i := 0; i < 3; i++ {
+		for _, path := range []string{"/startup", "/live", "/ready"} {
+			if code := probeStatus(t, s, path); code != http.StatusOK {
+				t.Errorf("Probe %d of %s: expected 200, got: %d", i, path, code)
+			}
+		}
+	}
+	if len(s.orders) != 0 {
+		t.Errorf("Expected probes to leave state untouched, got %d orders", len(s.orders))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func problemTestHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			writeError(w, http.StatusNotFound, "not_found", "Thing not found")
+		case "/invalid":
+			writeError(w, http.StatusBadRequest, "validation_failed", "quantity must be a positive integer")
+		case "/plain-error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	})
+}
+
+func serveProblem(byDefault bool, target, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	problemMiddleware(func() bool { return byDefault }, problemTestHandler()).ServeHTTP(rec, r)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != problemMediaType {
+		t.Fatalf("Expected Content-Type %s, got: %q", problemMediaType, ct)
+	}
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	return p
+}
+
+func TestProblemMiddleware_NotFound(t *testing.T) {
+	rec := serveProblem(false, "/missing", "application/problem+json, application/json")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got: %d", rec.Code)
+	}
+	want := Problem{
+		Type:     "urn:problem-type:not-found",
+		Title:    "Resource not found",
+		Status:   http.StatusNotFound,
+		Detail:   "Thing not found",
+		Instance: "/missing",
+		Code:     "not_found",
+	}
+	if p := decodeProblem(t, rec); p != want {
+		t.Errorf("Expected %+v, got: %+v", want, p)
+	}
+}
+
+func TestProblemMiddleware_ValidationByDefault(t *testing.T) {
+	rec := serveProblem(true, "/invalid", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d", rec.Code)
+	}
+	p := decodeProblem(t, rec)
+	if p.Type != "urn:problem-type:validation-failed" || p.Title != "Validation failed" || p.Status != 400 || p.Detail != "quantity must be a positive integer" {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+}
+
+func TestProblemMiddleware_PassThrough(t *testing.T) {
+	// Без Accept и флага остается прежний конверт
+	rec := serveProblem(false, "/missing", "application/json")
+	var envelope ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil || envelope.Error.Code != "not_found" {
+		t.Errorf("Expected the legacy envelope, got: %v (%v)", envelope, err)
+	}
+
+	// q=0 означает отказ от формата
+	if rec := serveProblem(false, "/missing", "application/problem+json;q=0"); rec.Header().Get("Content-Type") == problemMediaType {
+		t.Error("Expected q=0 to disable problem+json")
+	}
+
+	// Успешные ответы и ошибки не в формате конверта не меняются
+	if rec := serveProblem(true, "/ok", ""); rec.Code != http.StatusOK || rec.Body.String() != `{"ok":true}` {
+		t.Errorf("Expected success to pass through, got: %d %q", rec.Code, rec.Body)
+	}
+	if rec := serveProblem(true, "/plain-error", ""); rec.Code != http.StatusInternalServerError || rec.Body.String() != "boom\n" {
+		t.Errorf("Expected a non-JSON error to pass through, got: %d %q", rec.Code, rec.Body)
+	}
+}
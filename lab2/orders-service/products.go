@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProductNotFound возвращается, когда products-service ответил 404.
+var ErrProductNotFound = errors.New("product not found")
+
+// defaultPriceCacheTTL - сколько хранить цену товара. Цены меняются
+// редко, а запрашиваются на каждое чтение заказа.
+const defaultPriceCacheTTL = 5 * time.Minute
+
+// Product - товар из products-service.
+type Product struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type cachedPrice struct {
+	price     float64
+	expiresAt time.Time
+}
+
+// priceCache хранит цены по названию товара (без учета регистра).
+type priceCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	prices map[string]cachedPrice
+}
+
+func newPriceCache(ttl time.Duration) *priceCache {
+	return &priceCache{ttl: ttl, prices: map[string]cachedPrice{}}
+}
+
+func (c *priceCache) Get(name string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.prices[strings.ToLower(name)]
+	if !ok || !now().Before(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.price, true
+}
+
+func (c *priceCache) Put(name string, price float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prices[strings.ToLower(name)] = cachedPrice{price: price, expiresAt: now().Add(c.ttl)}
+}
+
+// ProductServiceClient обращается к products-service.
+type ProductServiceClient struct {
+	BaseURL string
+	Client  *http.Client
+
+	// Cache, если задан, хранит цены товаров.
+	Cache *priceCache
+}
+
+// GetPrice возвращает цену товара из кэша или из products-service.
+func (c *ProductServiceClient) GetPrice(ctx context.Context, name string) (float64, error) {
+	if c.Cache != nil {
+		if price, ok := c.Cache.Get(name); ok {
+			return price, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/products/"+url.PathEscape(name), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, ErrProductNotFound
+	default:
+		return 0, fmt.Errorf("products service returned status %d", resp.StatusCode)
+	}
+
+	var product Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		return 0, err
+	}
+	if c.Cache != nil {
+		c.Cache.Put(name, product.Price)
+	}
+	return product.Price, nil
+}
+
+// attachPrices проставляет заказам цену и сумму. Каждый товар
+// запрашивается один раз; если products-service недоступен или товара
+// в каталоге нет, цена у заказа опускается.
+func (s *server) attachPrices(ctx context.Context, list []Order) {
+	if s.productClient == nil || len(list) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	prices := map[string]float64{}
+	failed := map[string]bool{}
+	for i := range list {
+		name := list[i].Product
+		price, ok := prices[name]
+		if !ok && !failed[name] {
+			var err error
+			if price, err = s.productClient.GetPrice(ctx, name); err != nil {
+				log.Printf("Warning: failed to get price of %q: %v", name, err)
+				failed[name] = true
+			} else {
+				prices[name], ok = price, true
+			}
+		}
+		if ok {
+			total := price * float64(list[i].Quantity)
+			list[i].Price, list[i].Total = &price, &total
+		}
+	}
+}
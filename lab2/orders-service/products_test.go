@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// productServiceStub знает только Pen по 2.5; остальные товары - 404.
+func productServiceStub(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(strings.TrimPrefix(r.URL.Path, "/products/"), "Pen") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"name":"Pen","price":2.5}`))
+}
+
+func useProductService(t *testing.T, s *server, handler http.HandlerFunc, cacheTTL time.Duration) {
+	t.Helper()
+	mockServer := httptest.NewServer(handler)
+	t.Cleanup(mockServer.Close)
+	s.productClient = &ProductServiceClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{Timeout: 1 * time.Second},
+	}
+	if cacheTTL > 0 {
+		s.productClient.Cache = newPriceCache(cacheTTL)
+	}
+}
+
+func TestGetOrderByID_PriceAndTotal(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	useProductService(t, s, productServiceStub, 0)
+
+	order := decodeOrder(t, s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))
+	if order.Price == nil || *order.Price != 2.5 {
+		t.Fatalf("Expected price 2.5, got: %v", order.Price)
+	}
+	if order.Total == nil || *order.Total != 12.5 {
+		t.Errorf("Expected total 12.5 for quantity 5, got: %v", order.Total)
+	}
+}
+
+func TestGetOrders_PriceOmittedWhenUnknown(t *testing.T) {
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 2, Status: "pending"},
+		2: {ID: 2, UserID: 1, Product: "Ink", Quantity: 1, Status: "pending"},
+	})
+	useProductService(t, s, productServiceStub, 0)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders", nil))
+	var list []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode orders: %v", err)
+	}
+	for _, order := range list {
+		switch order["product"] {
+		case "Pen":
+			if order["price"] != 2.5 || order["total"] != float64(5) {
+				t.Errorf("Expected priced Pen order, got: %v", order)
+			}
+		case "Ink":
+			if _, ok := order["price"]; ok {
+				t.Errorf("Expected no price for unknown product, got: %v", order)
+			}
+		}
+	}
+}
+
+func TestGetOrderByID_ProductsServiceDown(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	useProductService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, 0)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if order := decodeOrder(t, rec); order.Price != nil || order.Total != nil {
+		t.Errorf("Expected price to be omitted, got: %v, %v", order.Price, order.Total)
+	}
+}
+
+func TestProductServiceClient_CachesPrices(t *testing.T) {
+	clock := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newInventoryServer(t, nil)
+	var calls atomic.Int32
+	useProductService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		productServiceStub(w, r)
+	}, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		s.serve(httptest.NewRequest(http.MethodGet, "/orders?fields=id,price", nil))
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected one lookup while cached, got: %d", got)
+	}
+
+	clock.Set(clock.Now().Add(time.Minute))
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders?fields=id,price", nil))
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected a new lookup after TTL, got: %d", got)
+	}
+}
+
+func TestCreateOrder_IgnoresClientPrice(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"price":0.01,"total":0.01}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	if stored := s.orders[decodeOrder(t, rec).ID]; stored.Price != nil || stored.Total != nil {
+		t.Errorf("Expected price not to be stored, got: %v, %v", stored.Price, stored.Total)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Профили ответа GET /orders/{id} в параметре profile заголовка Accept,
+// например Accept: application/json; profile="with-user". По RFC 6906
+// параметр может содержать несколько профилей через пробел.
+const (
+	// profileWithUser встраивает пользователя в заказ
+	profileWithUser = "with-user"
+	// profileBare отдает заказ без пользователя
+	profileBare = "bare"
+)
+
+// acceptProfiles возвращает профили из всех элементов Accept.
+func acceptProfiles(r *http.Request) []string {
+	var profiles []string
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		profiles = append(profiles, strings.Fields(params["profile"])...)
+	}
+	return profiles
+}
+
+// wantsEmbeddedUser решает, встраивать ли пользователя, по профилю из
+// Accept. Без известного профиля решает флаг embed_user; по умолчанию
+// он выключен, и заказ отдается без пользователя.
+func (s *server) wantsEmbeddedUser(r *http.Request) bool {
+	for _, profile := range acceptProfiles(r) {
+		switch profile {
+		case profileWithUser:
+			return true
+		case profileBare:
+			return false
+		}
+	}
+	return s.flags.Get().EmbedUser
+}
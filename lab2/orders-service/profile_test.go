@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetOrderByID_AcceptProfile(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	var calls atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		userServiceStub(w, r)
+	})
+
+	for _, tc := range []struct {
+		accept    string
+		wantsUser bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{`application/json; profile="bare"`, false},
+		{`application/json; profile="with-user"`, true},
+		{`application/json;profile=with-user`, true},
+		{`text/html, application/json; profile="urn:example:v2 with-user"`, true},
+		{`application/json; profile="unknown"`, false},
+	} {
+		calls.Store(0)
+		r := httptest.NewRequest(http.MethodGet, "/orders/3", nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		rec := s.serve(r)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got: %d", tc.accept, rec.Code)
+		}
+
+		order := decodeOrder(t, rec)
+		if got := order.User != nil; got != tc.wantsUser {
+			t.Errorf("%q: expected embedded user %v, got: %+v", tc.accept, tc.wantsUser, order.User)
+		}
+		// Без профиля with-user user-service не вызывается вовсе
+		if !tc.wantsUser && calls.Load() != 0 {
+			t.Errorf("%q: expected no user-service calls, got: %d", tc.accept, calls.Load())
+		}
+	}
+}
+
+func TestGetOrderByID_EmbedUserFlagRestoresDefault(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+	s.setFlags(func(f *Flags) { f.EmbedUser = true })
+
+	if order := decodeOrder(t, s.serve(httptest.NewRequest(http.MethodGet, "/orders/3", nil))); order.User == nil {
+		t.Error("Expected flag to embed user without a profile")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders/3", nil)
+	r.Header.Set("Accept", `application/json; profile="bare"`)
+	if order := decodeOrder(t, s.serve(r)); order.User != nil {
+		t.Errorf("Expected bare profile to override the flag, got: %+v", order.User)
+	}
+}
+
+func TestEmbedDepth_LimitsUserEmbedding(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	var calls atomic.Int32
+	var received atomic.Value
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		received.Store(r.Header.Get(embedDepthHeader))
+		userServiceStub(w, r)
+	})
+
+	for _, tc := range []struct {
+		depth     string
+		wantsUser bool
+		upstream  string
+	}{
+		{"", true, "0"},
+		{"1", true, "0"},
+		{"2", true, "1"},
+		{"0", false, ""},
+	} {
+		calls.Store(0)
+		r := withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3", nil))
+		if tc.depth != "" {
+			r.Header.Set(embedDepthHeader, tc.depth)
+		}
+		order := decodeOrder(t, s.serve(r))
+		if got := order.User != nil; got != tc.wantsUser {
+			t.Errorf("depth %q: expected embedded user %v, got: %+v", tc.depth, tc.wantsUser, order.User)
+		}
+		if !tc.wantsUser {
+			if calls.Load() != 0 {
+				t.Errorf("depth %q: expected no user-service calls, got: %d", tc.depth, calls.Load())
+			}
+			continue
+		}
+		// user-service получает глубину на единицу меньше и дальше не встраивает
+		if got := received.Load(); got != tc.upstream {
+			t.Errorf("depth %q: expected %s %q upstream, got: %v", tc.depth, embedDepthHeader, tc.upstream, got)
+		}
+	}
+
+	calls.Store(0)
+	r := httptest.NewRequest(http.MethodGet, "/orders?expand=user", nil)
+	r.Header.Set(embedDepthHeader, "0")
+	rec := s.serve(r)
+	if rec.Code != http.StatusOK || calls.Load() != 0 {
+		t.Errorf("Expected ?expand=user to be ignored at depth 0, got status %d and %d calls", rec.Code, calls.Load())
+	}
+}
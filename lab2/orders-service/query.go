@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryParams читает типизированные query-параметры и копит ошибки, чтобы
+// клиент за один ответ 400 узнал обо всех неверных параметрах сразу.
+// Отсутствующий параметр ошибкой не считается.
+type queryParams struct {
+	values url.Values
+	errs   []string
+}
+
+func newQueryParams(r *http.Request) *queryParams {
+	return &queryParams{values: r.URL.Query()}
+}
+
+func (q *queryParams) fail(format string, args ...interface{}) {
+	q.errs = append(q.errs, fmt.Sprintf(format, args...))
+}
+
+// Check добавляет ошибку msg, если ok ложно. Для проверок, связывающих
+// несколько параметров.
+func (q *queryParams) Check(ok bool, msg string) {
+	if !ok {
+		q.errs = append(q.errs, msg)
+	}
+}
+
+// Err возвращает все накопленные ошибки одной строкой или nil.
+func (q *queryParams) Err() error {
+	if len(q.errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(q.errs, "; "))
+}
+
+// String возвращает значение без пробелов по краям.
+func (q *queryParams) String(name string) string {
+	return strings.TrimSpace(q.values.Get(name))
+}
+
+// Int возвращает целое или nil, если параметра нет или он неверен.
+func (q *queryParams) Int(name string) *int {
+	raw := q.String(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		q.fail("%s must be an integer", name)
+		return nil
+	}
+	return &v
+}
+
+// IntDefault возвращает целое или def, если параметра нет или он неверен.
+func (q *queryParams) IntDefault(name string, def int) int {
+	if v := q.Int(name); v != nil {
+		return *v
+	}
+	return def
+}
+
+// NonNegativeInt - Int, который не принимает отрицательные значения.
+func (q *queryParams) NonNegativeInt(name string) *int {
+	raw := q.String(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		q.fail("%s must be a non-negative integer", name)
+		return nil
+	}
+	return &v
+}
+
+// Bool принимает значения strconv.ParseBool; без параметра - false.
+func (q *queryParams) Bool(name string) bool {
+	v := q.OptionalBool(name)
+	return v != nil && *v
+}
+
+// OptionalBool - Bool, который отличает отсутствие параметра (nil) от false.
+func (q *queryParams) OptionalBool(name string) *bool {
+	raw := q.String(name)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		q.fail("%s must be a boolean", name)
+		return nil
+	}
+	return &v
+}
+
+// IntSlice разбирает список целых через запятую; пустые элементы
+// пропускаются.
+func (q *queryParams) IntSlice(name string) []int {
+	var out []int
+	for _, part := range strings.Split(q.String(name), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			q.fail("%s must be a comma-separated list of integers", name)
+			return nil
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// Time разбирает метку времени RFC 3339.
+func (q *queryParams) Time(name string) *time.Time {
+	raw := q.String(name)
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		q.fail("%s must be an RFC3339 timestamp", name)
+		return nil
+	}
+	return &t
+}
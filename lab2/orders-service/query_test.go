@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func queryOf(rawQuery string) *queryParams {
+	return newQueryParams(httptest.NewRequest(http.MethodGet, "/orders?"+rawQuery, nil))
+}
+
+func TestQueryParams_ValidValues(t *testing.T) {
+	q := queryOf("n=-3&qty=5&flag=true&name=+pen+&ids=3,1,,2&at=2024-03-01T12:00:00Z")
+
+	if v := q.Int("n"); v == nil || *v != -3 {
+		t.Errorf("Int: expected -3, got: %v", v)
+	}
+	if v := q.NonNegativeInt("qty"); v == nil || *v != 5 {
+		t.Errorf("NonNegativeInt: expected 5, got: %v", v)
+	}
+	if v := q.IntDefault("qty", 10); v != 5 {
+		t.Errorf("IntDefault: expected 5, got: %d", v)
+	}
+	if !q.Bool("flag") {
+		t.Error("Bool: expected true")
+	}
+	if v := q.OptionalBool("flag"); v == nil || !*v {
+		t.Errorf("OptionalBool: expected true, got: %v", v)
+	}
+	if v := q.String("name"); v != "pen" {
+		t.Errorf("String: expected trimmed value, got: %q", v)
+	}
+	if v := q.IntSlice("ids"); !reflect.DeepEqual(v, []int{3, 1, 2}) {
+		t.Errorf("IntSlice: expected [3 1 2], got: %v", v)
+	}
+	if v := q.Time("at"); v == nil || !v.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time: unexpected value %v", v)
+	}
+	if err := q.Err(); err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+}
+
+func TestQueryParams_MissingValues(t *testing.T) {
+	q := queryOf("")
+
+	if q.Int("n") != nil || q.NonNegativeInt("qty") != nil || q.Time("at") != nil {
+		t.Error("Expected nil for missing pointer values")
+	}
+	if q.IntDefault("limit", 20) != 20 || q.Bool("flag") || q.String("name") != "" || q.IntSlice("ids") != nil ||
+		q.OptionalBool("flag") != nil {
+		t.Error("Expected defaults for missing values")
+	}
+	if err := q.Err(); err != nil {
+		t.Errorf("Missing values must not be errors, got: %v", err)
+	}
+}
+
+func TestQueryParams_InvalidValuesAccumulate(t *testing.T) {
+	q := queryOf("n=x&qty=-1&limit=big&flag=maybe&ids=1,two&at=yesterday")
+
+	q.Int("n")
+	q.NonNegativeInt("qty")
+	if v := q.IntDefault("limit", 20); v != 20 {
+		t.Errorf("IntDefault: expected default on invalid value, got: %d", v)
+	}
+	q.Bool("flag")
+	if v := q.IntSlice("ids"); v != nil {
+		t.Errorf("IntSlice: expected nil on invalid list, got: %v", v)
+	}
+	q.Time("at")
+	q.Check(false, "custom check failed")
+
+	err := q.Err()
+	if err == nil {
+		t.Fatal("Expected accumulated errors")
+	}
+	for _, want := range []string{
+		"n must be an integer",
+		"qty must be a non-negative integer",
+		"limit must be an integer",
+		"flag must be a boolean",
+		"ids must be a comma-separated list of integers",
+		"at must be an RFC3339 timestamp",
+		"custom check failed",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestGetOrders_ReportsAllInvalidFilters(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	code, _ := listOrderIDs(t, s, "/orders?min_qty=-1&created_after=soon")
+	if code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d", code)
+	}
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?min_qty=-1&created_after=soon", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "min_qty") || !strings.Contains(body, "created_after") {
+		t.Errorf("Expected both invalid parameters in the error, got: %s", body)
+	}
+}
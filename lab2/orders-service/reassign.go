@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type reassignRequest struct {
+	FromUserID int `json:"from_user_id"`
+	ToUserID   int `json:"to_user_id"`
+}
+
+type reassignResponse struct {
+	Moved    int   `json:"moved"`
+	OrderIDs []int `json:"order_ids"`
+}
+
+// reassignOrders обрабатывает POST /orders/reassign - перенос всех заказов
+// одного пользователя другому (например, при слиянии аккаунтов).
+// Удаленные заказы не переносятся.
+func (s *server) reassignOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var req reassignRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if req.FromUserID <= 0 || req.ToUserID <= 0 {
+		writeError(w, http.StatusBadRequest, "validation_failed", "from_user_id and to_user_id must be positive")
+		return
+	}
+	if req.FromUserID == req.ToUserID {
+		writeError(w, http.StatusBadRequest, "validation_failed", "from_user_id and to_user_id must differ")
+		return
+	}
+
+	for _, userID := range []int{req.FromUserID, req.ToUserID} {
+		if !s.checkReassignUser(w, r, userID) {
+			return
+		}
+	}
+
+	actor := callerFromContext(r.Context())
+	moved := []int{}
+	s.mu.Lock()
+	for id, order := range s.orders {
+		if order.UserID != req.FromUserID || order.DeletedAt != nil {
+			continue
+		}
+		updated := order
+		updated.UserID = req.ToUserID
+		s.storeOrder(updated)
+		s.recordOrderChange(&order, updated, actor)
+		moved = append(moved, id)
+	}
+	s.mu.Unlock()
+
+	sort.Ints(moved)
+	for _, id := range moved {
+		s.publishEvent("order.reassigned", id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reassignResponse{Moved: len(moved), OrderIDs: moved})
+}
+
+// checkReassignUser проверяет, что пользователь есть в user-service.
+// В отличие от checkOrderUser, недоступность сервиса - это 503, а не 400:
+// запрос корректен, и его можно повторить.
+func (s *server) checkReassignUser(w http.ResponseWriter, r *http.Request, userID int) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	exists, err := s.userClient.UserExists(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "user_service_unavailable", fmt.Sprintf("Could not check user %d: %v", userID, err))
+		return false
+	}
+	if !exists {
+		writeError(w, http.StatusBadRequest, "invalid_user", fmt.Sprintf("User %d not found", userID))
+		return false
+	}
+	return true
+}
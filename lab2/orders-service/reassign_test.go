@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReassignOrders(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+	publisher := setEventPublisher(t, s)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/reassign", `{"from_user_id":1,"to_user_id":2}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	var resp reassignResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Moved != 2 || !reflect.DeepEqual(resp.OrderIDs, []int{1, 2}) {
+		t.Errorf("Expected orders [1 2] moved, got: %+v", resp)
+	}
+
+	for id, order := range s.orders {
+		if order.UserID != 2 {
+			t.Errorf("Expected order %d to belong to user 2, got user %d", id, order.UserID)
+		}
+	}
+
+	// События публикуются в отдельных горутинах, порядок не гарантирован
+	var events []int
+	for i := 0; i < 2; i++ {
+		e := publisher.next(t)
+		if e.Type != "order.reassigned" {
+			t.Errorf("Expected order.reassigned event, got: %+v", e)
+		}
+		events = append(events, e.OrderID)
+	}
+	sort.Ints(events)
+	if !reflect.DeepEqual(events, []int{1, 2}) {
+		t.Errorf("Expected events for orders [1 2], got: %v", events)
+	}
+
+	history := s.history[1]
+	if len(history) == 0 || history[len(history)-1].Changes["user_id"].To != 2 {
+		t.Errorf("Expected user_id change in history, got: %+v", history)
+	}
+}
+
+func TestReassignOrders_MissingTargetUser(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/users/99/") {
+			http.NotFound(w, r)
+			return
+		}
+		userServiceStub(w, r)
+	})
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders/reassign", `{"from_user_id":1,"to_user_id":99}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.Code != "invalid_user" {
+		t.Errorf("Expected code 'invalid_user', got: %q", resp.Error.Code)
+	}
+
+	if s.orders[1].UserID != 1 || s.orders[2].UserID != 1 {
+		t.Errorf("Expected orders of user 1 untouched, got: %+v, %+v", s.orders[1], s.orders[2])
+	}
+}
+
+func TestReassignOrders_Validation(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	for _, body := range []string{
+		`{"from_user_id":1,"to_user_id":1}`,
+		`{"from_user_id":0,"to_user_id":2}`,
+		`{"from_user_id":1}`,
+	} {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders/reassign", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+const (
+	// defaultRecentLimit - сколько заказов отдает /orders/recent без ?limit=.
+	defaultRecentLimit = 10
+	// defaultMaxRecentOrders - верхняя граница ?limit= по умолчанию.
+	defaultMaxRecentOrders = 100
+)
+
+// recentOrders возвращает до limit последних созданных заказов, от новых к
+// старым. Заказы с одинаковым CreatedAt упорядочены по убыванию ID.
+// Удаленные заказы не учитываются.
+func (s *server) recentOrders(limit int) []Order {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make([]Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		if order.DeletedAt == nil {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders
+}
+
+// getRecentOrders обрабатывает GET /orders/recent?limit=N. limit больше
+// s.maxRecent не ошибка - ответ просто урезается до s.maxRecent.
+func (s *server) getRecentOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	q := newQueryParams(r)
+	limit := q.IntDefault("limit", defaultRecentLimit)
+	q.Check(limit > 0, "limit must be a positive integer")
+	if err := q.Err(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	if limit > s.maxRecent {
+		limit = s.maxRecent
+	}
+
+	writeBody(w, r, http.StatusOK, s.recentOrders(limit))
+}
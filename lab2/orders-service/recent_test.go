@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recentDataset - заказы, созданные не в порядке ID; заказ 5 удален.
+func recentDataset() map[int]Order {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted := base
+	return map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending", CreatedAt: base.Add(2 * time.Hour)},
+		2: {ID: 2, UserID: 1, Product: "Ink", Quantity: 1, Status: "pending", CreatedAt: base},
+		3: {ID: 3, UserID: 2, Product: "Desk", Quantity: 1, Status: "pending", CreatedAt: base.Add(time.Hour)},
+		4: {ID: 4, UserID: 2, Product: "Lamp", Quantity: 1, Status: "pending", CreatedAt: base.Add(time.Hour)},
+		5: {ID: 5, UserID: 2, Product: "Pen", Quantity: 1, Status: "pending", CreatedAt: base.Add(3 * time.Hour), DeletedAt: &deleted},
+	}
+}
+
+// recentIDs возвращает ID заказов из /orders/recent в порядке ответа.
+func recentIDs(t *testing.T, s *server, target string) []int {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	var list []Order
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode orders: %v", err)
+	}
+	ids := []int{}
+	for _, o := range list {
+		ids = append(ids, o.ID)
+	}
+	return ids
+}
+
+func TestGetRecentOrders_Ordering(t *testing.T) {
+	s := newTestServer(t, recentDataset())
+
+	// Новые первыми, при равном CreatedAt - больший ID первым; удаленный пропущен
+	if ids := recentIDs(t, s, "/orders/recent"); !reflect.DeepEqual(ids, []int{1, 4, 3, 2}) {
+		t.Errorf("Expected orders [1 4 3 2], got: %v", ids)
+	}
+	if ids := recentIDs(t, s, "/orders/recent?limit=2"); !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Errorf("Expected orders [1 4] with limit=2, got: %v", ids)
+	}
+}
+
+func TestGetRecentOrders_LimitCap(t *testing.T) {
+	s := newTestServer(t, recentDataset())
+	s.maxRecent = 3
+
+	if ids := recentIDs(t, s, "/orders/recent?limit=50"); !reflect.DeepEqual(ids, []int{1, 4, 3}) {
+		t.Errorf("Expected limit capped to 3 orders, got: %v", ids)
+	}
+
+	for _, query := range []string{"limit=0", "limit=-1", "limit=many"} {
+		rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/recent?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Фоновая сверка заказов с user-service. Пользователя могут удалить после
+// создания заказа, и заказ начинает ссылаться в никуда. Сверка только
+// сообщает о таких заказах: в лог и в метрику orders_orphaned. Данные не
+// меняются.
+
+// orphanCheckTimeout ограничивает один проход сверки.
+const orphanCheckTimeout = 30 * time.Second
+
+// orphanReport - итог одного прохода сверки.
+type orphanReport struct {
+	CheckedAt time.Time
+	// Orphaned - ID заказов, чьих пользователей больше нет, по возрастанию
+	Orphaned []int
+	// Unchecked - пользователи, которых не удалось проверить из-за ошибок
+	Unchecked int
+}
+
+// checkUsers запрашивает каждого пользователя из userIDs один раз,
+// параллельно в пределах enrichPool, и возвращает ошибку запроса по
+// каждому ID (nil - пользователь есть). Кэш пользователей обходится, иначе
+// удаление заметим только после истечения TTL.
+func (s *server) checkUsers(ctx context.Context, userIDs []int) map[int]error {
+	pending := make(map[int]chan error, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := pending[userID]; ok {
+			continue
+		}
+		result := make(chan error, 1)
+		pending[userID] = result
+		go func(userID int) {
+			select {
+			case s.enrichPool <- struct{}{}:
+			case <-ctx.Done():
+				result <- ctx.Err()
+				return
+			}
+			defer func() { <-s.enrichPool }()
+			_, err := s.userClient.FetchUserByID(ctx, userID)
+			result <- err
+		}(userID)
+	}
+
+	results := make(map[int]error, len(pending))
+	for userID, result := range pending {
+		results[userID] = <-result
+	}
+	return results
+}
+
+// reconcileOrphans выполняет один проход: собирает пользователей заказов
+// под RLock и проверяет каждого через checkUsers. Ошибки user-service,
+// кроме 404, сиротой заказ не делают.
+func (s *server) reconcileOrphans(ctx context.Context) orphanReport {
+	ctx, cancel := context.WithTimeout(ctx, orphanCheckTimeout)
+	defer cancel()
+
+	byUser := map[int][]int{}
+	s.mu.RLock()
+	for id, order := range s.orders {
+		if order.DeletedAt == nil {
+			byUser[order.UserID] = append(byUser[order.UserID], id)
+		}
+	}
+	s.mu.RUnlock()
+
+	userIDs := make([]int, 0, len(byUser))
+	for userID := range byUser {
+		userIDs = append(userIDs, userID)
+	}
+	results := s.checkUsers(ctx, userIDs)
+
+	report := orphanReport{CheckedAt: now()}
+	for userID, err := range results {
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			// Проход прерван остановкой сервиса или таймаутом
+			report.Unchecked++
+		case errors.Is(err, ErrUserNotFound):
+			report.Orphaned = append(report.Orphaned, byUser[userID]...)
+		default:
+			report.Unchecked++
+			log.Printf("Warning: orphan check of user %d failed: %v", userID, err)
+		}
+	}
+	sort.Ints(report.Orphaned)
+	if ctx.Err() != nil {
+		// Неполный проход не должен затирать итог предыдущего
+		log.Printf("Warning: orphan check interrupted: %v", ctx.Err())
+		return report
+	}
+
+	ordersOrphaned.Set(int64(len(report.Orphaned)))
+	if len(report.Orphaned) > 0 {
+		log.Printf("Warning: %d orders reference missing users: %s", len(report.Orphaned), joinIDs(report.Orphaned))
+	}
+	return report
+}
+
+// runOrphanReconciler проводит сверку на каждом тике, пока не отменен ctx.
+// Тики передаются снаружи, чтобы тесты управляли ими сами.
+func (s *server) runOrphanReconciler(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			s.reconcileOrphans(ctx)
+		}
+	}
+}
+
+// startOrphanReconciler запускает сверку раз в interval до отмены ctx.
+func (s *server) startOrphanReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		s.runOrphanReconciler(ctx, ticker.C)
+	}()
+}
+
+// orphanFilterTimeout ограничивает проверку пользователей для ?orphaned=.
+const orphanFilterTimeout = 10 * time.Second
+
+// parseOrphaned читает ?orphaned= из GET /orders; nil - фильтр не задан.
+func parseOrphaned(r *http.Request) (*bool, error) {
+	q := newQueryParams(r)
+	orphaned := q.OptionalBool("orphaned")
+	return orphaned, q.Err()
+}
+
+// filterOrphaned оставляет заказы, чей пользователь не найден в
+// user-service (orphaned == true), или, наоборот, только найденных.
+//
+// Фильтр дорогой: каждый различный UserID из выборки проверяется запросом
+// к user-service в обход кэша, до len(enrichPool) запросов одновременно.
+// Предназначен для разовых проверок качества данных, а не для обычных
+// списков. Если хотя бы одного пользователя проверить не удалось,
+// возвращается ошибка: неполный ответ выглядел бы как точный.
+func (s *server) filterOrphaned(ctx context.Context, orders []Order, orphaned bool) ([]Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, orphanFilterTimeout)
+	defer cancel()
+
+	userIDs := make([]int, 0, len(orders))
+	for _, order := range orders {
+		userIDs = append(userIDs, order.UserID)
+	}
+	results := s.checkUsers(ctx, userIDs)
+
+	missing := make(map[int]bool, len(results))
+	for userID, err := range results {
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrUserNotFound):
+			missing[userID] = true
+		default:
+			return nil, fmt.Errorf("could not check user %d: %w", userID, err)
+		}
+	}
+
+	filtered := orders[:0]
+	for _, order := range orders {
+		if missing[order.UserID] == orphaned {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrphanReconciler_ReportsMissingUsers(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending"},
+		2: {ID: 2, UserID: 2, Product: "Pen", Quantity: 1, Status: "pending"},
+		3: {ID: 3, UserID: 2, Product: "Ink", Quantity: 2, Status: "shipped"},
+		4: {ID: 4, UserID: 3, Product: "Pad", Quantity: 1, Status: "pending"},
+	})
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/users/2" {
+			http.NotFound(w, r)
+			return
+		}
+		userServiceStub(w, r)
+	})
+	before := make(map[int]Order)
+	for id, order := range s.orders {
+		before[id] = order
+	}
+
+	ticks := make(chan time.Time)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.runOrphanReconciler(ctx, ticks)
+		close(stopped)
+	}()
+
+	ordersOrphaned.Set(0)
+	clock.Set(start.Add(time.Hour))
+	ticks <- clock.Now()
+	// Второй тик принимается только после завершения первого прохода
+	ticks <- clock.Now()
+
+	if got := ordersOrphaned.Value(); got != 2 {
+		t.Errorf("Expected 2 orphaned orders in the metric, got: %d", got)
+	}
+	mu.Lock()
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		if requests[path] == 0 || requests[path] > 2 {
+			t.Errorf("Expected %s to be checked once per pass, got %d requests", path, requests[path])
+		}
+	}
+	mu.Unlock()
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected reconciler to stop after context cancellation")
+	}
+
+	if !reflect.DeepEqual(s.orders, before) {
+		t.Errorf("Expected orders to stay unchanged, got: %+v", s.orders)
+	}
+}
+
+func TestReconcileOrphans_Report(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	setClock(t, start)
+	deletedAt := start
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending"},
+		2: {ID: 2, UserID: 2, Product: "Pen", Quantity: 1, Status: "pending"},
+		5: {ID: 5, UserID: 5, Product: "Pen", Quantity: 1, Status: "pending", DeletedAt: &deletedAt},
+		7: {ID: 7, UserID: 7, Product: "Pen", Quantity: 1, Status: "pending"},
+	})
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/users/") {
+		case "2", "5":
+			http.NotFound(w, r)
+		case "7":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			userServiceStub(w, r)
+		}
+	})
+
+	report := s.reconcileOrphans(context.Background())
+
+	// Удаленный заказ 5 не проверяется, ошибка по пользователю 7 сиротой не делает
+	if !reflect.DeepEqual(report.Orphaned, []int{2}) {
+		t.Errorf("Expected orphaned orders [2], got: %v", report.Orphaned)
+	}
+	if report.Unchecked != 1 {
+		t.Errorf("Expected 1 unchecked user, got: %d", report.Unchecked)
+	}
+	if !report.CheckedAt.Equal(start) {
+		t.Errorf("Expected check time from the clock, got: %v", report.CheckedAt)
+	}
+}
+
+func TestGetOrders_OrphanedFilter(t *testing.T) {
+	// Пользователь 2 удален: его заказы 3 и 4 - сироты
+	s := newTestServer(t, quantityDataset())
+	var mu sync.Mutex
+	requests := map[string]int{}
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/users/2" {
+			http.NotFound(w, r)
+			return
+		}
+		userServiceStub(w, r)
+	})
+
+	if _, ids := listOrderIDs(t, s, "/orders?orphaned=true"); !reflect.DeepEqual(ids, []int{3, 4}) {
+		t.Errorf("Expected orphaned orders [3 4], got: %v", ids)
+	}
+	if _, ids := listOrderIDs(t, s, "/orders?orphaned=false"); !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("Expected resolvable orders [1 2], got: %v", ids)
+	}
+	if _, ids := listOrderIDs(t, s, "/orders?orphaned=true&min_qty=20"); !reflect.DeepEqual(ids, []int{4}) {
+		t.Errorf("Expected orphaned filter to combine with others, got: %v", ids)
+	}
+
+	mu.Lock()
+	// Три запроса списка: пользователь проверяется раз на запрос, а не на заказ
+	if requests["/users/1"] != 2 || requests["/users/2"] != 3 {
+		t.Errorf("Expected one check per user per request, got: %v", requests)
+	}
+	mu.Unlock()
+
+	if code, _ := listOrderIDs(t, s, "/orders?orphaned=maybe"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid orphaned, got: %d", code)
+	}
+}
+
+func TestGetOrders_OrphanedFilterUserServiceDown(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		userServiceStub(w, r)
+	})
+
+	if code, _ := listOrderIDs(t, s, "/orders?orphaned=true"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 when a user cannot be checked, got: %d", code)
+	}
+	// Без фильтра user-service не нужен
+	if code, _ := listOrderIDs(t, s, "/orders"); code != http.StatusOK {
+		t.Errorf("Expected status 200 without the filter, got: %d", code)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrReservationNotFound - резерва с таким токеном нет: он не выдавался,
+	// уже израсходован, отпущен или истек.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationMismatch - резерв выдан на другой товар или количество.
+	ErrReservationMismatch = errors.New("reservation does not match order")
+)
+
+const defaultReservationTTL = 10 * time.Minute
+
+// reservation - товар, отложенный под будущий заказ (двухфазный checkout).
+type reservation struct {
+	Token     string    `json:"token"`
+	Product   string    `json:"product"`
+	Quantity  int       `json:"quantity"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type reserveRequest struct {
+	Product  string `json:"product"`
+	Quantity int    `json:"quantity"`
+}
+
+func newReservationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// expireReservations возвращает на склад товар из истекших резервов.
+// Вызывать под s.mu.Lock.
+func (s *server) expireReservations() {
+	current := now()
+	for token, res := range s.reservations {
+		if !current.Before(res.ExpiresAt) {
+			s.reserveStock(res.Product, -res.Quantity)
+			delete(s.reservations, token)
+		}
+	}
+}
+
+// consumeReservation забирает резерв под заказ: товар уже списан со склада,
+// поэтому повторно не резервируется. Вызывать под s.mu.Lock.
+func (s *server) consumeReservation(token, product string, qty int) (reservation, error) {
+	s.expireReservations()
+	res, ok := s.reservations[token]
+	if !ok {
+		return reservation{}, ErrReservationNotFound
+	}
+	if res.Product != product || res.Quantity != qty {
+		return reservation{}, ErrReservationMismatch
+	}
+	delete(s.reservations, token)
+	return res, nil
+}
+
+// createOrderRequest - тело POST /orders и элемент POST /orders/batch.
+// ReservationToken нужен только при создании, поэтому его нет в Order.
+type createOrderRequest struct {
+	Order
+	// ReservationToken - токен из POST /inventory/reserve: заказ забирает
+	// отложенный товар вместо нового резервирования
+	ReservationToken string `json:"reservation_token,omitempty"`
+}
+
+// startReservationSweeper периодически отпускает истекшие резервы,
+// даже если к складу никто не обращается, до отмены ctx.
+func (s *server) startReservationSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		s.runReservationSweeper(ctx, ticker.C)
+	}()
+}
+
+// runReservationSweeper отпускает истекшие резервы на каждый тик из ticks
+// и возвращается после отмены ctx.
+func (s *server) runReservationSweeper(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			s.mu.Lock()
+			s.expireReservations()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// writeStockError отвечает на ошибки резервирования склада.
+func writeStockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrReservationNotFound):
+		writeError(w, http.StatusConflict, "reservation_not_found", "Reservation not found or expired")
+	case errors.Is(err, ErrReservationMismatch):
+		writeError(w, http.StatusConflict, "reservation_mismatch", "Reservation is for a different product or quantity")
+	default:
+		writeInsufficientStock(w)
+	}
+}
+
+// reserveInventory обрабатывает POST /inventory/reserve.
+func (s *server) reserveInventory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var req reserveRequest
+	if err := decodeStrictJSON(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if req.Product == "" {
+		writeError(w, http.StatusBadRequest, "validation_failed", "product is required")
+		return
+	}
+	if err := validateQuantity(req.Quantity, s.maxQuantity); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	token, err := newReservationToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.expireReservations()
+	if err := s.reserveStock(req.Product, req.Quantity); err != nil {
+		s.mu.Unlock()
+		writeInsufficientStock(w)
+		return
+	}
+	res := reservation{Token: token, Product: req.Product, Quantity: req.Quantity, ExpiresAt: now().Add(s.reservationTTL)}
+	s.reservations[token] = res
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// releaseInventory обрабатывает POST /inventory/release/{token}.
+func (s *server) releaseInventory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/inventory/release/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireReservations()
+	res, ok := s.reservations[token]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Reservation not found or expired")
+		return
+	}
+	s.reserveStock(res.Product, -res.Quantity)
+	delete(s.reservations, token)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reserve(t *testing.T, s *server, body string) reservation {
+	t.Helper()
+	rec := s.serve(jsonRequest(http.MethodPost, "/inventory/reserve", body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var res reservation
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("Failed to decode reservation: %v", err)
+	}
+	return res
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+
+	res := reserve(t, s, `{"product":"Pen","quantity":4}`)
+	if res.Token == "" {
+		t.Fatal("Expected a reservation token")
+	}
+	if got := s.stockOf("Pen"); got != 6 {
+		t.Errorf("Expected reservation to hold stock, got: %d left", got)
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/inventory/reserve", `{"product":"Pen","quantity":7}`)); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 when holding more than available, got: %d", rec.Code)
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/inventory/release/"+res.Token, "")); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected release to return stock, got: %d", got)
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/inventory/release/"+res.Token, "")); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected second release to give 404, got: %d", rec.Code)
+	}
+}
+
+func TestReservation_Expiry(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	s.reservationTTL = time.Minute
+
+	res := reserve(t, s, `{"product":"Pen","quantity":4}`)
+
+	clock.Set(start.Add(30 * time.Second))
+	s.mu.Lock()
+	s.expireReservations()
+	s.mu.Unlock()
+	if got := s.stockOf("Pen"); got != 6 {
+		t.Fatalf("Expected reservation to still hold stock before TTL, got: %d", got)
+	}
+
+	clock.Set(start.Add(time.Minute))
+	s.mu.Lock()
+	s.expireReservations()
+	s.mu.Unlock()
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected expired reservation to return stock, got: %d", got)
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/inventory/release/"+res.Token, "")); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected expired reservation to be gone, got: %d", rec.Code)
+	}
+}
+
+func TestReservationSweeper_StopsOnCancel(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	s.reservationTTL = time.Minute
+	reserve(t, s, `{"product":"Pen","quantity":4}`)
+
+	ticks := make(chan time.Time)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.runReservationSweeper(ctx, ticks)
+		close(stopped)
+	}()
+
+	clock.Set(start.Add(time.Minute))
+	ticks <- clock.Now()
+	// Второй тик принимается только после завершения первого прохода
+	ticks <- clock.Now()
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected sweeper to return expired stock, got: %d", got)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected sweeper to stop after context cancellation")
+	}
+}
+
+func TestCreateOrder_ConsumesReservation(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	useUserService(t, s, userServiceStub)
+
+	res := reserve(t, s, `{"product":"Pen","quantity":4}`)
+
+	mismatch := `{"user_id":1,"product":"Pen","quantity":5,"reservation_token":"` + res.Token + `"}`
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", mismatch)); rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for mismatched reservation, got: %d", rec.Code)
+	}
+
+	body := `{"user_id":1,"product":"Pen","quantity":4,"reservation_token":"` + res.Token + `"}`
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "reservation_token") {
+		t.Errorf("Expected token not to be stored on the order, got: %s", rec.Body)
+	}
+	// Товар списан один раз - при резервировании
+	if got := s.stockOf("Pen"); got != 6 {
+		t.Errorf("Expected stock to be taken only once, got: %d", got)
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", body)); rec.Code != http.StatusConflict {
+		t.Errorf("Expected reused token to be rejected with 409, got: %d", rec.Code)
+	}
+}
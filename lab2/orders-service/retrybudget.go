@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryBudgetConfig - общий на весь клиент бюджет повторов: не больше
+// Retries повторов за Window по всем вызовам вместе. Во время отказа
+// user-service каждый вызов повторял бы запросы сам по себе и умножал
+// нагрузку; бюджет ограничивает это умножение.
+type RetryBudgetConfig struct {
+	// Retries - емкость бюджета (0 - бюджет выключен, повторы не ограничены).
+	Retries int `json:"retries"`
+	// Window - за сколько бюджет восполняется с нуля до Retries.
+	Window time.Duration `json:"window"`
+}
+
+const defaultRetryBudgetWindow = 10 * time.Second
+
+// loadRetryBudgetConfig читает USER_SERVICE_RETRY_BUDGET и
+// USER_SERVICE_RETRY_BUDGET_WINDOW.
+func loadRetryBudgetConfig(getenv func(string) string) (RetryBudgetConfig, error) {
+	cfg := RetryBudgetConfig{Window: defaultRetryBudgetWindow}
+	if raw := getenv("USER_SERVICE_RETRY_BUDGET"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_RETRY_BUDGET must be a non-negative integer, got %q", raw)
+		}
+		cfg.Retries = v
+	}
+	if raw := getenv("USER_SERVICE_RETRY_BUDGET_WINDOW"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("USER_SERVICE_RETRY_BUDGET_WINDOW must be a positive duration, got %q", raw)
+		}
+		cfg.Window = d
+	}
+	return cfg, nil
+}
+
+// retryBudget - token bucket повторов. Токены восполняются равномерно,
+// Retries за Window, и не копятся сверх Retries.
+type retryBudget struct {
+	cfg RetryBudgetConfig
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRetryBudget(cfg RetryBudgetConfig) *retryBudget {
+	return &retryBudget{cfg: cfg, tokens: float64(cfg.Retries), last: now()}
+}
+
+// Withdraw забирает токен на один повтор. false - бюджет исчерпан,
+// повторять нельзя.
+func (b *retryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := now()
+	if elapsed := current.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(b.cfg.Retries) * elapsed.Seconds() / b.cfg.Window.Seconds()
+		if b.tokens > float64(b.cfg.Retries) {
+			b.tokens = float64(b.cfg.Retries)
+		}
+	}
+	b.last = current
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_Refills(t *testing.T) {
+	clock := setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newRetryBudget(RetryBudgetConfig{Retries: 2, Window: 10 * time.Second})
+
+	if !b.Withdraw() || !b.Withdraw() {
+		t.Fatal("Expected the full budget to be available")
+	}
+	if b.Withdraw() {
+		t.Fatal("Expected an exhausted budget to refuse a retry")
+	}
+
+	// 2 повтора за 10s - один токен каждые 5s
+	clock.Set(clock.Now().Add(4 * time.Second))
+	if b.Withdraw() {
+		t.Error("Expected no token before 5s have passed")
+	}
+	clock.Set(clock.Now().Add(time.Second))
+	if !b.Withdraw() {
+		t.Error("Expected one token after 5s")
+	}
+
+	// Простой не копит токены сверх емкости
+	clock.Set(clock.Now().Add(time.Hour))
+	if !b.Withdraw() || !b.Withdraw() || b.Withdraw() {
+		t.Error("Expected the budget to refill only up to its capacity")
+	}
+}
+
+func TestUserServiceClient_RetryBudgetExhausted(t *testing.T) {
+	setClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var calls atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mockServer.Close()
+
+	client := &UserServiceClient{
+		BaseURL:      mockServer.URL,
+		Client:       &http.Client{Timeout: time.Second},
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+		RetryBudget:  newRetryBudget(RetryBudgetConfig{Retries: 2, Window: time.Minute}),
+	}
+	throttled := userRetriesThrottled.Value()
+
+	// Первый вызов тратит оба повтора из бюджета вместо трех своих
+	if _, err := client.FetchUserByID(context.Background(), 1); err == nil {
+		t.Fatal("Expected an error from a failing user service")
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("Expected 1 attempt and 2 budgeted retries, got %d calls", n)
+	}
+
+	// Следующие вызовы больше не повторяются
+	calls.Store(0)
+	for i := 0; i < 3; i++ {
+		client.FetchUserByID(context.Background(), 1)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected single attempts with an exhausted budget, got %d calls", n)
+	}
+	if got := userRetriesThrottled.Value() - throttled; got != 4 {
+		t.Errorf("Expected 4 throttled retries, got: %d", got)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ServerTimeouts - таймауты HTTP-сервера, защищающие от медленных
+// клиентов (slowloris), которые держат соединения открытыми.
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+var defaultServerTimeouts = ServerTimeouts{
+	ReadHeader: 5 * time.Second,
+	Read:       15 * time.Second,
+	Write:      15 * time.Second,
+	Idle:       60 * time.Second,
+}
+
+// loadServerTimeouts читает HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT,
+// HTTP_WRITE_TIMEOUT и HTTP_IDLE_TIMEOUT в формате time.ParseDuration.
+func loadServerTimeouts(getenv func(string) string) (ServerTimeouts, error) {
+	t := defaultServerTimeouts
+	for _, item := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", &t.ReadHeader},
+		{"HTTP_READ_TIMEOUT", &t.Read},
+		{"HTTP_WRITE_TIMEOUT", &t.Write},
+		{"HTTP_IDLE_TIMEOUT", &t.Idle},
+	} {
+		raw := getenv(item.key)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("%s must be a positive duration, got %q", item.key, raw)
+		}
+		*item.dst = d
+	}
+	return t, nil
+}
+
+// defaultMaxHeaderBytes - предел размера заголовков запроса. Запросы с
+// заголовками больше него сервер отклоняет с 431.
+const defaultMaxHeaderBytes = 64 << 10
+
+// loadMaxHeaderBytes читает HTTP_MAX_HEADER_BYTES.
+func loadMaxHeaderBytes(getenv func(string) string) (int, error) {
+	raw := getenv("HTTP_MAX_HEADER_BYTES")
+	if raw == "" {
+		return defaultMaxHeaderBytes, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("HTTP_MAX_HEADER_BYTES must be a positive integer, got %q", raw)
+	}
+	return n, nil
+}
+
+// newHTTPServer настраивает таймауты и предел заголовков. Сверх
+// maxHeaderBytes net/http допускает небольшой запас (около 4 КиБ).
+func newHTTPServer(addr string, handler http.Handler, t ServerTimeouts, maxHeaderBytes int) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		MaxHeaderBytes:    maxHeaderBytes,
+		ReadHeaderTimeout: t.ReadHeader,
+		ReadTimeout:       t.Read,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useShadowStore подключает теневое хранилище с логом в буфер.
+func useShadowStore(t *testing.T, s *server) (*memoryOrderStore, *bytes.Buffer) {
+	t.Helper()
+	store := newMemoryOrderStore()
+	var buf bytes.Buffer
+	s.shadow = &shadowStore{store: store, logger: log.New(&buf, "", 0)}
+	s.setFlags(func(f *Flags) { f.ShadowOrderStore = true })
+	return store, &buf
+}
+
+func TestShadowStore_WritesHitBothStores(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	store, logs := useShadowStore(t, s)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":2}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	id := decodeOrder(t, rec).ID
+
+	if rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 3}`)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	shadow, ok := store.Get(id)
+	if !ok || shadow.Quantity != 3 {
+		t.Errorf("Expected shadow to follow the update, got: %+v (exists %v)", shadow, ok)
+	}
+	if !reflect.DeepEqual(shadow, s.orders[id]) {
+		t.Errorf("Expected shadow to equal primary, got: %+v vs %+v", shadow, s.orders[id])
+	}
+
+	s.setFlags(func(f *Flags) { f.SoftDelete = false })
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if _, ok := store.Get(id); ok {
+		t.Error("Expected hard delete to remove the order from shadow")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no discrepancies, got: %s", logs)
+	}
+}
+
+func TestShadowStore_LogsDiscrepancies(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	store, logs := useShadowStore(t, s)
+
+	for i := 0; i < 2; i++ {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":2}`)); rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d", rec.Code)
+		}
+	}
+
+	// Имитируем ошибки нового хранилища: потерянную и искаженную запись
+	store.Delete(1)
+	broken, _ := store.Get(2)
+	broken.Quantity = 7
+	store.Put(broken)
+
+	before := shadowMismatches.Value()
+	for _, target := range []string{"/orders/1", "/orders/2"} {
+		rec := s.serve(httptest.NewRequest(http.MethodGet, target, nil))
+		if order := decodeOrder(t, rec); order.Quantity != 2 {
+			t.Errorf("%s: expected response from primary, got quantity: %d", target, order.Quantity)
+		}
+	}
+
+	if got := shadowMismatches.Value() - before; got != 2 {
+		t.Errorf("Expected 2 mismatches, got: %d", got)
+	}
+	for _, want := range []string{"order 1 missing in shadow store", "order 2 differs"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected log to mention %q, got: %s", want, logs)
+		}
+	}
+}
+
+func TestShadowStore_DisabledByFlag(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	store, logs := useShadowStore(t, s)
+	s.setFlags(func(f *Flags) { f.ShadowOrderStore = false })
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":2}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if _, ok := store.Get(1); ok {
+		t.Error("Expected no shadow writes with the flag off")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no comparisons with the flag off, got: %s", logs)
+	}
+}
+
+// lockCheckingStore - тень, которая проверяет, что ее читают под s.mu.
+type lockCheckingStore struct {
+	OrderStore
+	s        *server
+	unlocked bool
+}
+
+func (l *lockCheckingStore) Get(id int) (Order, bool) {
+	// TryLock удается, только если s.mu никто не держит
+	if l.s.mu.TryLock() {
+		l.s.mu.Unlock()
+		l.unlocked = true
+	}
+	return l.OrderStore.Get(id)
+}
+
+func TestShadowStore_ComparesUnderOrdersLock(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	store, _ := useShadowStore(t, s)
+	checking := &lockCheckingStore{OrderStore: store, s: s}
+	s.shadow.store = checking
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":2}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if checking.unlocked {
+		t.Error("Expected the shadow read to happen under the orders lock")
+	}
+}
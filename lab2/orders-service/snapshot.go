@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// ordersSnapshot - полное состояние сервиса заказов для GET /admin/snapshot
+// и POST /admin/restore. Нужен для демонстраций и воспроизведения ошибок;
+// формат не предназначен для долговременного хранения.
+type ordersSnapshot struct {
+	// Orders - все заказы по возрастанию ID, включая мягко удаленные
+	Orders []Order `json:"orders"`
+	// NextID - следующий ID последовательного генератора; у остальных
+	// стратегий ID случайны или зависят от времени, и поле не заполняется
+	NextID          int                   `json:"next_id,omitempty"`
+	NextOrderNumber int                   `json:"next_order_number"`
+	History         map[int][]OrderChange `json:"history"`
+	// Stock - остатки, если учет остатков включен
+	Stock map[string]int `json:"stock,omitempty"`
+	// HeldStock - сколько товара держит каждый заказ (см. server.held)
+	HeldStock map[int]int `json:"held_stock,omitempty"`
+}
+
+// snapshot снимает состояние под RLock: все изменения заказов, журнала,
+// счетчиков и остатков идут под s.mu.Lock, поэтому снимок соответствует
+// одному моменту времени. Заказы копируются вместе с тегами и датой
+// удаления, чтобы кодирование после снятия блокировки не видело более
+// поздних изменений. Резервы и товар заказов, которые еще создаются, в
+// снимок не попадают: их товар возвращается в Stock.
+func (s *server) snapshot() ordersSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := ordersSnapshot{
+		Orders:          make([]Order, 0, len(s.orders)),
+		NextOrderNumber: s.numbers.peek(),
+		History:         make(map[int][]OrderChange, len(s.history)),
+	}
+	for _, order := range s.orders {
+		snap.Orders = append(snap.Orders, cloneOrder(order))
+	}
+	sort.Slice(snap.Orders, func(i, j int) bool { return snap.Orders[i].ID < snap.Orders[j].ID })
+	if seq, ok := s.ids.(*sequentialIDs); ok {
+		snap.NextID = seq.peek()
+	}
+	for id, entries := range s.history {
+		snap.History[id] = append([]OrderChange{}, entries...)
+	}
+	if s.stock != nil {
+		snap.Stock = make(map[string]int, len(s.stock))
+		for product, qty := range s.stock {
+			snap.Stock[product] = qty
+		}
+		for _, held := range s.reservations {
+			snap.Stock[held.Product] += held.Quantity
+		}
+		for product, qty := range s.creating {
+			snap.Stock[product] += qty
+		}
+		snap.HeldStock = make(map[int]int, len(s.held))
+		for id, qty := range s.held {
+			snap.HeldStock[id] = qty
+		}
+	}
+	return snap
+}
+
+// cloneOrder копирует заказ вместе с данными, на которые он ссылается.
+func cloneOrder(order Order) Order {
+	if order.Tags != nil {
+		order.Tags = append([]string{}, order.Tags...)
+	}
+	if order.DeletedAt != nil {
+		deletedAt := *order.DeletedAt
+		order.DeletedAt = &deletedAt
+	}
+	return order
+}
+
+// validate проверяет снимок перед восстановлением, чтобы не заменить
+// состояние наполовину.
+func (snap ordersSnapshot) validate(maxQuantity, maxOrders int) error {
+	maxID := 0
+	seen := make(map[int]bool, len(snap.Orders))
+	for i, order := range snap.Orders {
+		if order.ID <= 0 {
+			return fmt.Errorf("orders[%d]: id must be a positive integer", i)
+		}
+		if seen[order.ID] {
+			return fmt.Errorf("orders[%d]: duplicate id %d", i, order.ID)
+		}
+		seen[order.ID] = true
+		if err := validateStoredOrder(order, maxQuantity); err != nil {
+			return fmt.Errorf("orders[%d]: %w", i, err)
+		}
+		if order.ID > maxID {
+			maxID = order.ID
+		}
+	}
+	if maxOrders > 0 && len(snap.Orders) > maxOrders {
+		return fmt.Errorf("snapshot has %d orders, the limit is %d", len(snap.Orders), maxOrders)
+	}
+	if snap.NextID != 0 && snap.NextID <= maxID {
+		return fmt.Errorf("next_id must be greater than the largest order id %d", maxID)
+	}
+	if snap.NextOrderNumber < 0 {
+		return fmt.Errorf("next_order_number must not be negative")
+	}
+	for product, qty := range snap.Stock {
+		if qty < 0 {
+			return fmt.Errorf("stock of %q must not be negative", product)
+		}
+	}
+	for id, qty := range snap.HeldStock {
+		if !seen[id] {
+			return fmt.Errorf("held_stock refers to unknown order %d", id)
+		}
+		if qty <= 0 {
+			return fmt.Errorf("held_stock of order %d must be positive", id)
+		}
+	}
+	return nil
+}
+
+// restore заменяет состояние снимком под одной блокировкой: читатели видят
+// либо прежнее состояние, либо восстановленное целиком. Снимок должен
+// быть проверен validate.
+func (s *server) restore(snap ordersSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.orders {
+		s.removeOrder(id)
+	}
+	for _, order := range snap.Orders {
+		order.User, order.Price, order.Total = nil, nil, nil
+		s.storeOrder(s.applyStatusPolicy(order))
+	}
+
+	s.history = make(map[int][]OrderChange, len(snap.History))
+	for id, entries := range snap.History {
+		s.history[id] = append([]OrderChange{}, entries...)
+	}
+
+	if seq, ok := s.ids.(*sequentialIDs); ok && snap.NextID != 0 {
+		seq.reset(snap.NextID)
+	} else {
+		for _, order := range snap.Orders {
+			s.ids.Observe(order.ID)
+		}
+	}
+	if snap.NextOrderNumber > 0 {
+		s.numbers.reset(snap.NextOrderNumber)
+	}
+
+	// Резервы ссылаются на прежние остатки и после восстановления теряют смысл
+	s.reservations = map[string]reservation{}
+	if s.stock != nil && snap.Stock != nil {
+		s.stock = make(map[string]int, len(snap.Stock))
+		for product, qty := range snap.Stock {
+			s.stock[product] = qty
+		}
+	}
+	// Без учета остатков в снимке заказы товар не держат
+	s.held = make(map[int]int, len(snap.HeldStock))
+	if s.stock != nil {
+		for id, qty := range snap.HeldStock {
+			s.held[id] = qty
+		}
+	}
+}
+
+// handleSnapshot обрабатывает GET /admin/snapshot.
+func (s *server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshot())
+}
+
+// handleRestore обрабатывает POST /admin/restore: тело - снимок из
+// GET /admin/snapshot. Неверный снимок отклоняется целиком.
+func (s *server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var snap ordersSnapshot
+	if err := decodeStrictJSON(r.Body, &snap); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	maxOrders := 0
+	if s.limit != nil {
+		maxOrders = s.limit.capacity
+	}
+	if err := snap.validate(s.maxQuantity, maxOrders); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	s.restore(snap)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getSnapshot(t *testing.T, s *server) []byte {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	return rec.Body.Bytes()
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	setClock(t, time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+	source := newTestServer(t, quantityDataset())
+	source.adminEnabled = true
+	useUserService(t, source, userServiceStub)
+
+	// Создание через API дает запись в журнале, номер заказа и сдвигает счетчик ID
+	if rec := source.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":2,"tags":["gift"]}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	data := getSnapshot(t, source)
+
+	var snap ordersSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if len(snap.Orders) != 5 || snap.NextID != 6 || snap.NextOrderNumber != 2 || len(snap.History[5]) != 1 {
+		t.Fatalf("Unexpected snapshot: %s", data)
+	}
+
+	target := newTestServer(t, map[int]Order{7: {ID: 7, UserID: 1, Product: "Old", Quantity: 1, Status: "pending"}})
+	target.adminEnabled = true
+	useUserService(t, target, userServiceStub)
+
+	rec := target.serve(jsonRequest(http.MethodPost, "/admin/restore", string(data)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	if restored := getSnapshot(t, target); !bytes.Equal(restored, data) {
+		t.Errorf("Expected identical snapshot after restore.\nbefore: %s\nafter:  %s", data, restored)
+	}
+	if _, ids := listOrderIDs(t, target, "/orders"); !reflect.DeepEqual(ids, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected previous orders to be replaced, got: %v", ids)
+	}
+
+	// Счетчики продолжают с места снимка
+	rec = target.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":2,"product":"Ink","quantity":1}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.ID != 6 || order.OrderNumber != "ORD-2024-000002" {
+		t.Errorf("Expected order 6 with number ORD-2024-000002, got: %d %s", order.ID, order.OrderNumber)
+	}
+}
+
+func TestSnapshot_KeepsHeldStock(t *testing.T) {
+	source := newTestServer(t, quantityDataset())
+	source.adminEnabled = true
+	source.stock = map[string]int{"Pen": 10}
+	useUserService(t, source, userServiceStub)
+	if rec := source.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":4}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	data := getSnapshot(t, source)
+
+	target := newTestServer(t, nil)
+	target.adminEnabled = true
+	target.stock = map[string]int{}
+	if rec := target.serve(jsonRequest(http.MethodPost, "/admin/restore", string(data))); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	// Созданный через API заказ возвращает товар, загруженный - нет
+	target.serve(httptest.NewRequest(http.MethodDelete, "/orders/5", nil))
+	target.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if got := target.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected only the held pens returned after restore, got: %d", got)
+	}
+}
+
+func TestRestore_RejectsInvalidSnapshot(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.adminEnabled = true
+	before := getSnapshot(t, s)
+
+	for name, body := range map[string]string{
+		"duplicate id":  `{"orders":[{"id":1,"user_id":1,"product":"Pen","quantity":1},{"id":1,"user_id":1,"product":"Ink","quantity":1}]}`,
+		"invalid order": `{"orders":[{"id":1,"user_id":1,"product":"","quantity":1}]}`,
+		"stale next_id": `{"orders":[{"id":9,"user_id":1,"product":"Pen","quantity":1}],"next_id":5}`,
+		"unknown field": `{"orders":[],"users":[]}`,
+		"held unknown":  `{"orders":[],"held_stock":{"3":1}}`,
+	} {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/admin/restore", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d (%s)", name, rec.Code, rec.Body)
+		}
+	}
+
+	if after := getSnapshot(t, s); !bytes.Equal(after, before) {
+		t.Errorf("Expected state untouched after rejected restores.\nbefore: %s\nafter:  %s", before, after)
+	}
+}
+
+func TestSnapshot_AdminOnly(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil),
+		jsonRequest(http.MethodPost, "/admin/restore", `{"orders":[]}`),
+	} {
+		if rec := s.serve(req); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected status 404 with admin disabled, got: %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+	if len(s.orders) != 4 {
+		t.Errorf("Expected orders untouched, got: %d", len(s.orders))
+	}
+}
+
+func TestSnapshot_ConsistentUnderConcurrentCreates(t *testing.T) {
+	const initialStock = 1000
+	s := newTestServer(t, nil)
+	s.adminEnabled = true
+	s.stock = map[string]int{"Pen": initialStock}
+	useUserService(t, s, userServiceStub)
+
+	const writers, perWriter = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				body := fmt.Sprintf(`{"user_id":1,"product":"Pen","quantity":%d,"tags":["w%d"]}`, i%3+1, w)
+				if rec := s.serve(jsonRequest(http.MethodPost, "/orders", body)); rec.Code != http.StatusCreated {
+					t.Errorf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+					return
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	check := func(snap ordersSnapshot) {
+		t.Helper()
+		ordered, maxID := 0, 0
+		for _, order := range snap.Orders {
+			if order.ID <= 0 || order.OrderNumber == "" || order.Product != "Pen" || order.Quantity <= 0 ||
+				order.CreatedAt.IsZero() || len(order.Tags) != 1 || len(snap.History[order.ID]) == 0 {
+				t.Fatalf("Partial order in snapshot: %+v (history %v)", order, snap.History[order.ID])
+			}
+			ordered += order.Quantity
+			maxID = max(maxID, order.ID)
+		}
+		if len(snap.History) != len(snap.Orders) {
+			t.Fatalf("Snapshot has %d orders but history for %d", len(snap.Orders), len(snap.History))
+		}
+		if snap.NextID <= maxID || snap.NextOrderNumber != len(snap.Orders)+1 {
+			t.Fatalf("Counters out of step with orders: next_id %d, next_order_number %d, %d orders up to id %d",
+				snap.NextID, snap.NextOrderNumber, len(snap.Orders), maxID)
+		}
+		// Товар либо на складе, либо в заказе - без промежуточных состояний
+		if got := snap.Stock["Pen"] + ordered; got != initialStock {
+			t.Fatalf("Stock %d plus ordered %d is %d, expected %d", snap.Stock["Pen"], ordered, got, initialStock)
+		}
+	}
+
+	snapshots := 0
+	for running := true; running; snapshots++ {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		var snap ordersSnapshot
+		if err := json.Unmarshal(getSnapshot(t, s), &snap); err != nil {
+			t.Fatalf("Failed to decode snapshot: %v", err)
+		}
+		check(snap)
+	}
+
+	var final ordersSnapshot
+	if err := json.Unmarshal(getSnapshot(t, s), &final); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if len(final.Orders) != writers*perWriter {
+		t.Errorf("Expected %d orders in the final snapshot, got: %d", writers*perWriter, len(final.Orders))
+	}
+	t.Logf("Checked %d snapshots taken during creates", snapshots)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStack - запущенные вместе orders-service и user-service.
+type testStack struct {
+	OrdersURL string
+	UsersURL  string
+	// Orders - сервер заказов, чтобы тест мог поменять флаги или данные
+	Orders *server
+}
+
+// startTestStack поднимает настоящий user-service и orders-service,
+// который ходит в него. orders-service работает в процессе теста через
+// httptest. Поднять так же и user-service нельзя: это отдельный модуль с
+// package main, а main-пакет не импортируется. Поэтому он собирается из
+// ../user-service и запускается дочерним процессом. Возвращает адреса и
+// функцию остановки; без go в PATH тест пропускается.
+func startTestStack(t *testing.T) (testStack, func()) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping service stack in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain is required to build user-service")
+	}
+
+	bin := filepath.Join(t.TempDir(), "users-service")
+	build := exec.Command(goTool, "build", "-o", bin, ".")
+	build.Dir = filepath.Join("..", "user-service")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build user-service: %v\n%s", err, out)
+	}
+
+	// Окружение задается целиком, а не наследуется: переменные хоста
+	// (STRICT_CONTENT_TYPE, ADMIN_*, REQUIRE_VERIFICATION_TOKEN и др.)
+	// меняли бы поведение user-service в тесте. Порт 0 выбирает система,
+	// а адрес берется из лога старта - без гонки за свободный порт.
+	users := exec.Command(bin)
+	users.Env = []string{"USERS_ADDR=127.0.0.1:0"}
+	stderr, err := users.StderrPipe()
+	if err != nil {
+		t.Fatalf("Failed to capture user-service output: %v", err)
+	}
+	if err := users.Start(); err != nil {
+		t.Fatalf("Failed to start user-service: %v", err)
+	}
+	stopUsers := func() {
+		users.Process.Kill()
+		users.Wait()
+	}
+
+	addr, err := waitListening(stderr, 10*time.Second)
+	if err != nil {
+		stopUsers()
+		t.Fatalf("user-service did not start: %v", err)
+	}
+	usersURL := "http://" + addr
+	if err := waitHealthy(usersURL+"/health", 10*time.Second); err != nil {
+		stopUsers()
+		t.Fatalf("user-service did not start: %v", err)
+	}
+
+	s := newServer(&UserServiceClient{
+		BaseURL: usersURL,
+		Client:  &http.Client{Timeout: 2 * time.Second},
+	}, defaultFlags)
+	s.events = discardPublisher{}
+	orders := httptest.NewServer(s.routes())
+
+	return testStack{OrdersURL: orders.URL, UsersURL: usersURL, Orders: s}, func() {
+		orders.Close()
+		stopUsers()
+	}
+}
+
+// usersStartedLine - строка лога, которой user-service сообщает адрес.
+const usersStartedLine = "Users service started on "
+
+// waitListening читает лог user-service до строки о старте и возвращает
+// адрес из нее. Остаток лога дочитывается в фоне, чтобы процесс не
+// блокировался на полном канале.
+func waitListening(logs io.Reader, timeout time.Duration) (string, error) {
+	found := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(logs)
+		for scanner.Scan() {
+			if _, addr, ok := strings.Cut(scanner.Text(), usersStartedLine); ok {
+				found <- strings.TrimSpace(addr)
+				io.Copy(io.Discard, logs)
+				return
+			}
+		}
+		close(found)
+	}()
+
+	select {
+	case addr, ok := <-found:
+		if !ok {
+			return "", errors.New("process exited before listening")
+		}
+		return addr, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no %q line after %v", strings.TrimSpace(usersStartedLine), timeout)
+	}
+}
+
+// waitHealthy опрашивает url, пока он не ответит 200.
+func waitHealthy(url string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func postJSON(t *testing.T, url, body string, v interface{}) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST %s: expected status 201, got: %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("POST %s: failed to decode response: %v", url, err)
+	}
+}
+
+func TestStack_CreateUserThenEnrichedOrder(t *testing.T) {
+	stack, stop := startTestStack(t)
+	defer stop()
+
+	var user User
+	postJSON(t, stack.UsersURL+"/users", `{"name":"Stack User","email":"stack@example.com"}`, &user)
+
+	var created Order
+	postJSON(t, stack.OrdersURL+"/orders", fmt.Sprintf(`{"user_id":%d,"product":"Pen","quantity":2}`, user.ID), &created)
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/orders/%d", stack.OrdersURL, created.ID), nil)
+	req.Header.Set("Accept", `application/json; profile="with-user"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET order failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var order Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to decode order: %v", err)
+	}
+	if order.User == nil || order.User.ID != user.ID || order.User.Name != "Stack User" {
+		t.Errorf("Expected order enriched with user %d from user-service, got: %+v", user.ID, order.User)
+	}
+}
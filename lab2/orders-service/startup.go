@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Ожидание зависимостей при старте. При совместном деплое orders-service
+// может подняться раньше user-service, и первые запросы падают. С
+// STARTUP_WAIT_FOR_DEPENDENCIES сервер начинает принимать трафик только
+// после того, как зависимости ответили на /health или истек таймаут.
+
+const (
+	defaultStartupWaitTimeout = 30 * time.Second
+	startupBackoffBase        = 100 * time.Millisecond
+	startupBackoffMax         = 2 * time.Second
+)
+
+// waitForDependencies опрашивает зависимости из dependencyChecks с
+// экспоненциальной паузой, пока они не ответят или не пройдет timeout.
+// Ошибку возвращает только для критичных зависимостей; без некритичной
+// сервис стартует в деградированном режиме. sleep подменяется в тестах.
+func (s *server) waitForDependencies(ctx context.Context, timeout time.Duration, sleep func(context.Context, time.Duration) bool) error {
+	deadline := now().Add(timeout)
+	for _, check := range s.dependencyChecks() {
+		err := waitForDependency(ctx, check, deadline, s.healthTimeout, sleep)
+		switch {
+		case err == nil:
+		case check.Critical || ctx.Err() != nil:
+			return err
+		default:
+			log.Printf("Warning: startup continues without %s: %v", check.Name, err)
+		}
+	}
+	return nil
+}
+
+func waitForDependency(ctx context.Context, check dependencyCheck, deadline time.Time, probeTimeout time.Duration, sleep func(context.Context, time.Duration) bool) error {
+	backoff := startupBackoffBase
+	for attempt := 1; ; attempt++ {
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err := check.Probe(probeCtx)
+		cancel()
+		if err == nil {
+			log.Printf("Startup: %s is ready after %d attempt(s)", check.Name, attempt)
+			return nil
+		}
+
+		remaining := deadline.Sub(now())
+		if remaining <= 0 {
+			return fmt.Errorf("%s is not ready after %d attempt(s): %w", check.Name, attempt, err)
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		log.Printf("Startup: waiting for %s (attempt %d: %v), next check in %v", check.Name, attempt, err, backoff)
+		if !sleep(ctx, backoff) {
+			return fmt.Errorf("waiting for %s: %w", check.Name, context.Cause(ctx))
+		}
+		if backoff *= 2; backoff > startupBackoffMax {
+			backoff = startupBackoffMax
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clockSleep - пауза для waitForDependencies, которая сразу двигает
+// управляемые часы вместо реального ожидания.
+func clockSleep(clock *fakeClock) func(context.Context, time.Duration) bool {
+	return func(ctx context.Context, d time.Duration) bool {
+		clock.Set(clock.Now().Add(d))
+		return ctx.Err() == nil
+	}
+}
+
+func TestWaitForDependencies_UserServiceBecomesHealthy(t *testing.T) {
+	start := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s := newTestServer(t, nil)
+
+	var probes atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		// user-service поднимается через секунду после старта
+		if clock.Now().Before(start.Add(time.Second)) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if err := s.waitForDependencies(context.Background(), 10*time.Second, clockSleep(clock)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Проверки в 0, 0.1, 0.3, 0.7 и 1.5 секунды
+	if got := probes.Load(); got != 5 {
+		t.Errorf("Expected 5 health probes with doubling backoff, got: %d", got)
+	}
+	if waited := clock.Now().Sub(start); waited != 1500*time.Millisecond {
+		t.Errorf("Expected to wait 1.5s, waited: %v", waited)
+	}
+}
+
+func TestWaitForDependencies_Timeout(t *testing.T) {
+	start := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	clock := setClock(t, start)
+	s := newTestServer(t, nil)
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// Некритичный user-service: по таймауту старт продолжается
+	if err := s.waitForDependencies(context.Background(), 5*time.Second, clockSleep(clock)); err != nil {
+		t.Errorf("Expected startup to continue without non-critical dependency, got: %v", err)
+	}
+	if waited := clock.Now().Sub(start); waited != 5*time.Second {
+		t.Errorf("Expected to wait exactly the timeout, waited: %v", waited)
+	}
+
+	s.userServiceCritical = true
+	if err := s.waitForDependencies(context.Background(), 5*time.Second, clockSleep(clock)); err == nil {
+		t.Error("Expected error for critical dependency that never became healthy")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.userServiceCritical = false
+	if err := s.waitForDependencies(ctx, 5*time.Second, clockSleep(clock)); err == nil {
+		t.Error("Expected error when shutdown interrupts the wait")
+	}
+}
+
+func TestStartupGate_RejectsTrafficWhileWaiting(t *testing.T) {
+	clock := setClock(t, time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC))
+	s := newTestServer(t, map[int]Order{1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 1}})
+	var up atomic.Bool
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		userServiceStub(w, r)
+	})
+	s.userServiceCritical = true
+	gated := s.startupGate(s.routes())
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		gated.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	// Пока user-service недоступен, API закрыто, а пробы отвечают
+	codes := map[string]int{}
+	sleep := clockSleep(clock)
+	err := s.waitForDependencies(context.Background(), 10*time.Second, func(ctx context.Context, d time.Duration) bool {
+		for _, path := range []string{"/orders", "/orders/1", "/startup", "/live"} {
+			codes[path] = status(path)
+		}
+		up.Store(true)
+		return sleep(ctx, d)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for path, want := range map[string]int{
+		"/orders":   http.StatusServiceUnavailable,
+		"/orders/1": http.StatusServiceUnavailable,
+		"/startup":  http.StatusServiceUnavailable,
+		"/live":     http.StatusOK,
+	} {
+		if codes[path] != want {
+			t.Errorf("While waiting: expected %s %d, got: %d", path, want, codes[path])
+		}
+	}
+
+	s.markStarted()
+	if code := status("/orders"); code != http.StatusOK {
+		t.Errorf("Expected /orders 200 after startup, got: %d", code)
+	}
+}
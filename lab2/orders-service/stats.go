@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// orderStats - агрегаты для GET /orders/stats. Удаленные заказы не учитываются.
+// С ?user_id= агрегаты считаются только по заказам этого пользователя.
+type orderStats struct {
+	TotalOrders     int            `json:"total_orders"`
+	ByStatus        map[string]int `json:"by_status"`
+	TotalQuantity   int            `json:"total_quantity"`
+	AverageQuantity float64        `json:"average_quantity"`
+	DistinctUsers   int            `json:"distinct_users"`
+}
+
+// computeStats считает все агрегаты за один проход по заказам. userID 0 -
+// по всем пользователям.
+func (s *server) computeStats(userID int) orderStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := orderStats{ByStatus: map[string]int{}}
+	users := map[int]struct{}{}
+	for _, order := range s.orders {
+		if order.DeletedAt != nil || (userID != 0 && order.UserID != userID) {
+			continue
+		}
+		stats.TotalOrders++
+		stats.ByStatus[order.Status]++
+		stats.TotalQuantity += order.Quantity
+		users[order.UserID] = struct{}{}
+	}
+
+	stats.DistinctUsers = len(users)
+	if stats.TotalOrders > 0 {
+		stats.AverageQuantity = float64(stats.TotalQuantity) / float64(stats.TotalOrders)
+	}
+	return stats
+}
+
+func (s *server) getOrderStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var userID int
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		var err error
+		if userID, err = strconv.Atoi(raw); err != nil || userID <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_query", "user_id must be a positive integer")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.computeStats(userID))
+}
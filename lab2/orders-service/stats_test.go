@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetOrderStats(t *testing.T) {
+	data := quantityDataset()
+	deleted := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data[5] = Order{ID: 5, UserID: 3, Product: "Chair", Quantity: 100, Status: "pending", DeletedAt: &deleted}
+	s := newTestServer(t, data)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	var stats orderStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+
+	// Удаленный заказ 5 в агрегаты не попадает
+	want := orderStats{
+		TotalOrders:     4,
+		ByStatus:        map[string]int{"pending": 3, "shipped": 1},
+		TotalQuantity:   36,
+		AverageQuantity: 9,
+		DistinctUsers:   2,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Expected %+v, got: %+v", want, stats)
+	}
+}
+
+func TestGetOrderStats_Empty(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	if stats := s.computeStats(0); stats.TotalOrders != 0 || stats.AverageQuantity != 0 || len(stats.ByStatus) != 0 {
+		t.Errorf("Expected zero stats, got: %+v", stats)
+	}
+}
+
+func TestGetOrderStats_ByUser(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	stats := s.computeStats(2)
+	want := orderStats{
+		TotalOrders:     2,
+		ByStatus:        map[string]int{"shipped": 1, "pending": 1},
+		TotalQuantity:   30,
+		AverageQuantity: 15,
+		DistinctUsers:   1,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Expected %+v, got: %+v", want, stats)
+	}
+
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/stats?user_id=abc", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid user_id, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Статусы, которых нет в allowedStatuses, при загрузке сохраненных
+// заказов (seed, снимок, в будущем - постоянное хранилище). Такой статус
+// мог записать более новый сервис. Проверка статуса выполняется только
+// при записи от клиента; при чтении состояние не отбрасывается.
+const (
+	// unknownStatusPassthrough - оставить статус как есть. Заказ
+	// отдается с ним, но клиент не может записать этот статус сам.
+	unknownStatusPassthrough = "passthrough"
+	// unknownStatusCoerce - заменить статус на безопасный fallback.
+	unknownStatusCoerce = "coerce"
+)
+
+func validUnknownStatusPolicy(policy string) error {
+	switch policy {
+	case unknownStatusPassthrough, unknownStatusCoerce:
+		return nil
+	default:
+		return fmt.Errorf("unknown policy %q: expected passthrough or coerce", policy)
+	}
+}
+
+// applyStatusPolicy приводит статус загружаемого заказа по политике
+// s.unknownStatusPolicy. Вызывается для заказов, которые не пришли от
+// клиента, поэтому ошибок не возвращает.
+func (s *server) applyStatusPolicy(order Order) Order {
+	if allowedStatuses[order.Status] {
+		return order
+	}
+	if s.unknownStatusPolicy == unknownStatusCoerce {
+		log.Printf("Warning: order %d has unknown status %q, treating it as %q", order.ID, order.Status, s.unknownStatusFallback)
+		order.Status = s.unknownStatusFallback
+		return order
+	}
+	log.Printf("Warning: order %d has unknown status %q, serving it as is", order.ID, order.Status)
+	return order
+}
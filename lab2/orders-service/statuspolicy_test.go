@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// futureStatusOrder - заказ со статусом, который мог записать более новый сервис.
+var futureStatusOrder = Order{ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "returned"}
+
+func TestUnknownStatus_Passthrough(t *testing.T) {
+	s := newTestServer(t, map[int]Order{1: futureStatusOrder})
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.Status != "returned" {
+		t.Errorf("Expected the unknown status served as is, got: %q", order.Status)
+	}
+	if _, ids := listOrderIDs(t, s, "/orders"); len(ids) != 1 {
+		t.Errorf("Expected the order in the list, got: %v", ids)
+	}
+
+	// Записать неизвестный статус клиент не может
+	rec = s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Pen","quantity":1,"status":"returned"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when writing an unknown status, got: %d", rec.Code)
+	}
+}
+
+func TestUnknownStatus_Coerce(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.unknownStatusPolicy, s.unknownStatusFallback = unknownStatusCoerce, "processing"
+	s.loadOrders([]Order{futureStatusOrder})
+
+	if got := s.orders[1].Status; got != "processing" {
+		t.Errorf("Expected seed status coerced to processing, got: %q", got)
+	}
+
+	// Снимок проходит проверку и восстанавливается с той же заменой
+	s.adminEnabled = true
+	snapshot := `{"orders":[{"id":5,"user_id":2,"product":"Ink","quantity":3,"status":"lost_in_transit"}],"next_id":6}`
+	if rec := s.serve(jsonRequest(http.MethodPost, "/admin/restore", snapshot)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if got := s.orders[5].Status; got != "processing" {
+		t.Errorf("Expected restored status coerced to processing, got: %q", got)
+	}
+}
+
+func TestLoadConfig_UnknownStatusPolicy(t *testing.T) {
+	cfg, err := LoadConfig(envMap(map[string]string{
+		"UNKNOWN_STATUS_POLICY":   "coerce",
+		"UNKNOWN_STATUS_FALLBACK": "cancelled",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := newServerFromConfig(cfg)
+	if s.unknownStatusPolicy != unknownStatusCoerce || s.unknownStatusFallback != "cancelled" {
+		t.Errorf("Expected coerce to cancelled, got: %s %s", s.unknownStatusPolicy, s.unknownStatusFallback)
+	}
+}
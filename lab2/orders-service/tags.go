@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagLimits - ограничения на теги заказа. Проверяются после нормализации,
+// поэтому регистр и повторы на них не влияют.
+type TagLimits struct {
+	// MaxTags - сколько разных тегов может быть у заказа
+	MaxTags int `json:"max_tags"`
+	// MaxLength - максимальная длина тега в символах
+	MaxLength int `json:"max_length"`
+	// Charset - допустимые символы в синтаксисе класса регулярного
+	// выражения (то, что стоит внутри [...]), например "a-z0-9-"
+	Charset string `json:"charset"`
+	pattern *regexp.Regexp
+}
+
+var defaultTagLimits = mustTagLimits(10, 32, "a-z0-9-")
+
+// newTagLimits проверяет Charset и готовит регулярное выражение для него.
+func newTagLimits(maxTags, maxLength int, charset string) (TagLimits, error) {
+	if charset == "" || strings.Contains(charset, "]") {
+		return TagLimits{}, fmt.Errorf("invalid tag charset %q", charset)
+	}
+	pattern, err := regexp.Compile("^[" + charset + "]+$")
+	if err != nil {
+		return TagLimits{}, fmt.Errorf("invalid tag charset %q: %w", charset, err)
+	}
+	return TagLimits{MaxTags: maxTags, MaxLength: maxLength, Charset: charset, pattern: pattern}, nil
+}
+
+func mustTagLimits(maxTags, maxLength int, charset string) TagLimits {
+	limits, err := newTagLimits(maxTags, maxLength, charset)
+	if err != nil {
+		panic(err)
+	}
+	return limits
+}
+
+// loadTagLimits читает ORDER_TAGS_MAX, ORDER_TAG_MAX_LENGTH и ORDER_TAG_CHARSET.
+func loadTagLimits(getenv func(string) string) (TagLimits, error) {
+	maxTags, maxLength, charset := defaultTagLimits.MaxTags, defaultTagLimits.MaxLength, defaultTagLimits.Charset
+	var err error
+	if raw := getenv("ORDER_TAGS_MAX"); raw != "" {
+		if maxTags, err = strconv.Atoi(raw); err != nil || maxTags < 0 {
+			return defaultTagLimits, fmt.Errorf("ORDER_TAGS_MAX must be a non-negative integer, got %q", raw)
+		}
+	}
+	if raw := getenv("ORDER_TAG_MAX_LENGTH"); raw != "" {
+		if maxLength, err = strconv.Atoi(raw); err != nil || maxLength <= 0 {
+			return defaultTagLimits, fmt.Errorf("ORDER_TAG_MAX_LENGTH must be a positive integer, got %q", raw)
+		}
+	}
+	if raw := getenv("ORDER_TAG_CHARSET"); raw != "" {
+		charset = raw
+	}
+	limits, err := newTagLimits(maxTags, maxLength, charset)
+	if err != nil {
+		return defaultTagLimits, fmt.Errorf("ORDER_TAG_CHARSET: %w", err)
+	}
+	return limits, nil
+}
+
+// Normalize приводит теги к нижнему регистру, обрезает пробелы,
+// убирает пустые и повторы (сохраняя порядок) и проверяет лимиты.
+func (l TagLimits) Normalize(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > l.MaxLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, l.MaxLength)
+		}
+		if !l.pattern.MatchString(tag) {
+			return nil, fmt.Errorf("tag %q contains characters outside [%s]", tag, l.Charset)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > l.MaxTags {
+		return nil, fmt.Errorf("at most %d tags per order, got %d", l.MaxTags, len(normalized))
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := defaultTagLimits.Normalize([]string{" Gift ", "priority", "GIFT", "", "  "})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"gift", "priority"}) {
+		t.Errorf("Expected [gift priority], got: %v", got)
+	}
+}
+
+func TestCreateOrder_TagLimits(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	tooMany := make([]string, defaultTagLimits.MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprintf("t%d", i))
+	}
+	for _, tags := range []string{
+		`[` + strings.Join(tooMany, ",") + `]`,
+		`["` + strings.Repeat("x", defaultTagLimits.MaxLength+1) + `"]`,
+	} {
+		body := `{"user_id":1,"product":"Pen","quantity":1,"tags":` + tags + `}`
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for tags %s, got: %d", tags, rec.Code)
+		}
+	}
+
+	// Повторы не считаются в лимит: после нормализации тег один
+	dupes := `[` + strings.Repeat(`"a","A"," a ",`, defaultTagLimits.MaxTags) + `"a"]`
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"tags":`+dupes+`}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); !reflect.DeepEqual(order.Tags, []string{"a"}) {
+		t.Errorf("Expected normalized tags [a], got: %v", order.Tags)
+	}
+}
+
+func TestTagLimits_Normalize(t *testing.T) {
+	limits := mustTagLimits(2, 5, "a-z0-9-")
+
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr string
+	}{
+		{"within limits", []string{"Gift", "vip-2"}, []string{"gift", "vip-2"}, ""},
+		{"too many tags", []string{"a", "b", "c"}, nil, "at most 2 tags"},
+		{"duplicates do not count", []string{"a", "A", " a", "b"}, []string{"a", "b"}, ""},
+		{"too long", []string{"abcdef"}, nil, "longer than 5"},
+		{"length after trimming", []string{"  abcde  "}, []string{"abcde"}, ""},
+		{"space inside", []string{"a b"}, nil, "outside [a-z0-9-]"},
+		{"underscore", []string{"a_b"}, nil, "outside [a-z0-9-]"},
+		{"non-latin", []string{"подарок"}, nil, "longer than 5"},
+		{"non-latin short", []string{"дар"}, nil, "outside [a-z0-9-]"},
+		{"punctuation", []string{"gift!"}, nil, "outside [a-z0-9-]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := limits.Normalize(tt.tags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadTagLimits(t *testing.T) {
+	limits, err := loadTagLimits(envMap(map[string]string{
+		"ORDER_TAGS_MAX":       "3",
+		"ORDER_TAG_MAX_LENGTH": "8",
+		"ORDER_TAG_CHARSET":    "a-z_",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if limits.MaxTags != 3 || limits.MaxLength != 8 || limits.Charset != "a-z_" {
+		t.Errorf("Unexpected limits: %+v", limits)
+	}
+	if _, err := limits.Normalize([]string{"new_year"}); err != nil {
+		t.Errorf("Expected underscore to be allowed by custom charset, got: %v", err)
+	}
+}
+
+func TestCreateOrder_TagViolationMessage(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"tags":["gift","fragile!"]}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `fragile!`) {
+		t.Errorf("Expected error to name the offending tag, got: %s", rec.Body)
+	}
+}
+
+func TestGetOrders_TagFilter(t *testing.T) {
+	data := quantityDataset()
+	for id, tags := range map[int][]string{1: {"gift"}, 2: {"priority", "gift"}, 3: {"priority"}} {
+		order := data[id]
+		order.Tags = tags
+		data[id] = order
+	}
+	s := newTestServer(t, data)
+
+	if _, ids := listOrderIDs(t, s, "/orders?tag=Gift"); !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("Expected orders [1 2] tagged gift, got: %v", ids)
+	}
+	if _, ids := listOrderIDs(t, s, "/orders?tag=priority&min_qty=10"); !reflect.DeepEqual(ids, []int{3}) {
+		t.Errorf("Expected order [3], got: %v", ids)
+	}
+}
+
+func TestPutOrder_NormalizesTags(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Pen","quantity":1,"tags":["Rush"," rush"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if tags := s.orders[1].Tags; !reflect.DeepEqual(tags, []string{"rush"}) {
+		t.Errorf("Expected stored tags [rush], got: %v", tags)
+	}
+}
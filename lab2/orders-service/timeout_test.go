@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware_CancelsUpstreamCall(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	cancelled := make(chan struct{})
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+			userServiceStub(w, r)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	timeoutMiddleware(RouteTimeouts{Default: 50 * time.Millisecond}, s.routes()).ServeHTTP(rec, withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3", nil)))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got: %d", rec.Code)
+	}
+	// В отличие от http.TimeoutHandler, 503 приходит обычным конвертом ошибки
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" || !strings.Contains(rec.Body.String(), `"code":"timeout"`) {
+		t.Errorf("Expected JSON error envelope, got: %q %s", ct, rec.Body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected response right after the timeout, took: %v", elapsed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected upstream request to be cancelled, it kept running")
+	}
+}
+
+func TestTimeoutMiddleware_PassesThroughFastResponses(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+
+	rec := httptest.NewRecorder()
+	timeoutMiddleware(RouteTimeouts{Default: time.Second}, s.routes()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/99", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected handler status 404 to pass through, got: %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected handler headers to pass through, got: %q", ct)
+	}
+}
+
+func TestTimeoutMiddleware_PerRoute(t *testing.T) {
+	s := newTestServer(t, nil)
+	// user-service отвечает за 100 мс: чтению столько ждать нельзя, созданию - можно
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+			userServiceStub(w, r)
+		}
+	})
+	s.orders[1] = Order{ID: 1, UserID: 1, Product: "Pen", Quantity: 1, Status: "pending"}
+	handler := timeoutMiddleware(RouteTimeouts{
+		Default: 50 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"POST /orders": 2 * time.Second,
+		},
+	}, s.routes())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/1", nil)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected read to hit the short default, got: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected create to get the longer deadline, got: %d (%s)", rec.Code, rec.Body)
+	}
+}
+
+func TestRouteTimeouts_For(t *testing.T) {
+	routes, err := parseRouteTimeouts("GET /orders=1s, POST /orders=8s, /orders/=3s, GET /orders/lookup=4s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	timeouts := RouteTimeouts{Default: 10 * time.Second, Routes: routes}
+
+	for _, tc := range []struct {
+		method, path string
+		want         time.Duration
+	}{
+		{http.MethodGet, "/orders", time.Second},
+		{http.MethodPost, "/orders", 8 * time.Second},
+		{http.MethodGet, "/orders/5", 3 * time.Second},
+		{http.MethodDelete, "/orders/5", 3 * time.Second},
+		{http.MethodGet, "/orders/lookup", 4 * time.Second},
+		{http.MethodPost, "/orders/lookup", 3 * time.Second},
+		{http.MethodGet, "/health", 10 * time.Second},
+	} {
+		if got := timeouts.For(httptest.NewRequest(tc.method, tc.path, nil)); got != tc.want {
+			t.Errorf("%s %s: expected %v, got: %v", tc.method, tc.path, tc.want, got)
+		}
+	}
+
+	for _, raw := range []string{"GET /orders", "GET /orders=soon", "GET orders=1s", "/orders=-1s"} {
+		if _, err := parseRouteTimeouts(raw); err == nil {
+			t.Errorf("%q: expected error", raw)
+		}
+	}
+}
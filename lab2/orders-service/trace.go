@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TraceTimings - длительности фаз одного запроса к user-service.
+// Нулевое значение означает, что фаза не выполнялась (например, DNS для
+// IP-адреса или TLS для http).
+type TraceTimings struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration
+	Total     time.Duration
+	Reused    bool
+}
+
+// traceRecorder собирает TraceTimings; колбэки httptrace могут
+// вызываться из разных горутин.
+type traceRecorder struct {
+	mu       sync.Mutex
+	start    time.Time
+	dnsStart time.Time
+	dialFrom time.Time
+	tlsStart time.Time
+	timings  TraceTimings
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{start: time.Now()}
+}
+
+func (rec *traceRecorder) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rec.mu.Lock()
+			rec.dnsStart = time.Now()
+			rec.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rec.mu.Lock()
+			rec.timings.DNS = time.Since(rec.dnsStart)
+			rec.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			rec.mu.Lock()
+			rec.dialFrom = time.Now()
+			rec.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			rec.mu.Lock()
+			rec.timings.Connect = time.Since(rec.dialFrom)
+			rec.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			rec.mu.Lock()
+			rec.tlsStart = time.Now()
+			rec.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			rec.mu.Lock()
+			rec.timings.TLS = time.Since(rec.tlsStart)
+			rec.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			rec.mu.Lock()
+			rec.timings.Reused = info.Reused
+			rec.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			rec.mu.Lock()
+			rec.timings.FirstByte = time.Since(rec.start)
+			rec.mu.Unlock()
+		},
+	}
+}
+
+func (rec *traceRecorder) finish() TraceTimings {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.timings.Total = time.Since(rec.start)
+	return rec.timings
+}
+
+// reportTrace передает тайминги в TraceHook и логирует медленные вызовы.
+func (c *UserServiceClient) reportTrace(userID int, timings TraceTimings) {
+	if c.TraceHook != nil {
+		c.TraceHook(userID, timings)
+	}
+	if c.SlowThreshold > 0 && timings.Total > c.SlowThreshold {
+		log.Printf("Warning: slow user-service call for user %d: total=%v dns=%v connect=%v tls=%v first_byte=%v reused=%t",
+			userID, timings.Total, timings.DNS, timings.Connect, timings.TLS, timings.FirstByte, timings.Reused)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Разбор JSON-тел запросов. Файл одинаков в обоих сервисах.
+
+// errTrailingData - после JSON-значения в теле есть что-то еще, например
+// второй объект.
+var errTrailingData = errors.New("request body must contain a single JSON value")
+
+// decodeSingleJSON читает из body одно JSON-значение в v.
+func decodeSingleJSON(body io.Reader, v interface{}) error {
+	return decodeSingleValue(json.NewDecoder(body), v)
+}
+
+// decodeStrictJSON - decodeSingleJSON, который вдобавок отклоняет поля,
+// которых нет в v. Для служебных тел (снимки, флаги), где опечатка в
+// имени поля не должна молча теряться.
+func decodeStrictJSON(body io.Reader, v interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	return decodeSingleValue(dec, v)
+}
+
+// decodeSingleValue декодирует значение из dec и проверяет, что за ним
+// нет ничего, кроме пробелов: иначе второй объект в теле молча терялся
+// бы. Одного dec.More() для проверки мало: лишнюю закрывающую скобку он
+// не замечает.
+func decodeSingleValue(dec *json.Decoder, v interface{}) error {
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
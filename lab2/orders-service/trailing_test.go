@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateOrder_RejectsTrailingJSON(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	body := `{"user_id": 1, "product": "Laptop", "quantity": 1}{"user_id": 2, "product": "Mouse", "quantity": 5}`
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", body))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for two JSON objects, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.Code != "invalid_body" || resp.Error.Message != errTrailingData.Error() {
+		t.Errorf("Expected invalid_body about trailing data, got: %+v", resp.Error)
+	}
+	if len(s.orders) != 0 {
+		t.Errorf("Expected no order created, got: %v", s.orders)
+	}
+
+}
+
+func TestDecodeSingleJSON(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want error
+	}{
+		{`{"id": 1}`, nil},
+		{"{\"id\": 1}\n\t ", nil},
+		{`{"id": 1}{"id": 2}`, errTrailingData},
+		{`{"id": 1} {"id": 2}`, errTrailingData},
+		{`{"id": 1}}`, errTrailingData},
+		{`{"id": 1}]`, errTrailingData},
+		{`{"id": 1} x`, errTrailingData},
+		{`{"id": 1} 2`, errTrailingData},
+	} {
+		var v struct{ ID int }
+		if err := decodeSingleJSON(strings.NewReader(tc.body), &v); !errors.Is(err, tc.want) {
+			t.Errorf("%q: expected %v, got: %v", tc.body, tc.want, err)
+		}
+	}
+
+	var v struct{ ID int }
+	if err := decodeStrictJSON(strings.NewReader(`{"id": 1, "name": "x"}`), &v); err == nil {
+		t.Error("Expected unknown field to be rejected by decodeStrictJSON")
+	}
+	if err := decodeStrictJSON(strings.NewReader(`{"id": 1}{"id": 2}`), &v); !errors.Is(err, errTrailingData) {
+		t.Errorf("Expected trailing data to be rejected by decodeStrictJSON, got: %v", err)
+	}
+}
+
+func TestServiceEndpoints_RejectTrailingJSON(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	s.adminEnabled = true
+	restore := string(getSnapshot(t, s))
+
+	for target, body := range map[string]string{
+		"/admin/restore":     restore + restore,
+		"/admin/flags":       `{"mask_emails": true}{"mask_emails": false}`,
+		"/inventory/reserve": `{"product": "Pen", "quantity": 1}{}`,
+		"/orders/lookup":     `{"ids": [1]}]`,
+	} {
+		if rec := s.serve(jsonRequest(http.MethodPost, target, body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 for trailing data, got: %d (%s)", target, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// phaseClient - HTTP-клиент с таймаутами по фазам запроса. Client.Timeout
+// ограничивает запрос целиком и не отличает медленное соединение от
+// медленного ответа; таймауты фаз задаются на транспорте. Создается при
+// первом использовании по DialTimeout, TLSHandshakeTimeout и
+// ResponseHeaderTimeout.
+type phaseClient struct {
+	once   sync.Once
+	client *http.Client
+}
+
+// httpClient возвращает Client, а при заданных таймаутах фаз - его копию
+// с транспортом, в котором эти таймауты выставлены. Собственный
+// RoundTripper, не *http.Transport, используется как есть.
+func (c *UserServiceClient) httpClient() *http.Client {
+	if c.DialTimeout <= 0 && c.TLSHandshakeTimeout <= 0 && c.ResponseHeaderTimeout <= 0 {
+		return c.Client
+	}
+	c.phases.once.Do(func() {
+		client := *c.Client
+		c.phases.client = &client
+
+		var transport *http.Transport
+		switch base := client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = base.Clone()
+		default:
+			return
+		}
+		if c.DialTimeout > 0 {
+			dialer := &net.Dialer{Timeout: c.DialTimeout, KeepAlive: 30 * time.Second}
+			transport.DialContext = dialer.DialContext
+		}
+		if c.TLSHandshakeTimeout > 0 {
+			transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+		}
+		if c.ResponseHeaderTimeout > 0 {
+			transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+		}
+		client.Transport = transport
+	})
+	return c.phases.client
+}
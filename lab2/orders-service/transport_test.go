@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserServiceClient_ResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		userServiceStub(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := &UserServiceClient{
+		BaseURL:               srv.URL,
+		Client:                &http.Client{Timeout: 5 * time.Second},
+		ResponseHeaderTimeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := client.FetchUserByID(context.Background(), 1)
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Fatalf("Expected response header timeout, got: %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected to fail at the header timeout, not the client timeout, took %v", elapsed)
+	}
+}
+
+func TestUserServiceClient_SlowBodyWithinClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// Тело медленнее таймаута заголовков, но в пределах Client.Timeout
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(`{"id": 1, "name": "Ann"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &UserServiceClient{
+		BaseURL:               srv.URL,
+		Client:                &http.Client{Timeout: 5 * time.Second},
+		ResponseHeaderTimeout: 50 * time.Millisecond,
+	}
+
+	user, err := client.FetchUserByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected slow body to be read, got: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Expected user 1, got: %+v", user)
+	}
+}
+
+func TestUserServiceClient_PhaseTimeoutsOnTransport(t *testing.T) {
+	base := &http.Client{Timeout: 5 * time.Second}
+	client := &UserServiceClient{
+		Client:                base,
+		DialTimeout:           time.Second,
+		TLSHandshakeTimeout:   2 * time.Second,
+		ResponseHeaderTimeout: 3 * time.Second,
+	}
+
+	hc := client.httpClient()
+	if hc == base || base.Transport != nil {
+		t.Fatal("Expected a copy of the client, leaving the original untouched")
+	}
+	if hc.Timeout != base.Timeout {
+		t.Errorf("Expected overall timeout to be kept, got: %v", hc.Timeout)
+	}
+	transport, ok := hc.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got: %T", hc.Transport)
+	}
+	if transport.TLSHandshakeTimeout != 2*time.Second || transport.ResponseHeaderTimeout != 3*time.Second || transport.DialContext == nil {
+		t.Errorf("Unexpected transport timeouts: tls=%v header=%v", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+	if client.httpClient() != hc {
+		t.Error("Expected the transport to be built once")
+	}
+
+	// Без таймаутов фаз используется Client как есть
+	if plain := (&UserServiceClient{Client: base}).httpClient(); plain != base {
+		t.Error("Expected the configured client without phase timeouts")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// orderETag - сильный ETag хранимого заказа: хеш его JSON. Встроенный
+// пользователь и цена в хранимом заказе отсутствуют, поэтому ETag
+// меняется только вместе с самим заказом.
+func orderETag(order Order) string {
+	data, _ := json.Marshal(order)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// putOrder реализует идемпотентный upsert: заменяет существующий заказ
+// (200) или создает новый с указанным ID (201).
+func (s *server) putOrder(w http.ResponseWriter, r *http.Request, id int) {
+	var order Order
+	if err := decodeBody(r, &order); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+
+	if err := s.normalizeOrder(&order); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	if err := validateOrder(order, s.maxQuantity); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	if err := s.checkProduct(order.Product); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	if !s.checkOrderUser(w, r, order.UserID) {
+		return
+	}
+
+	order.ID = id
+	order.User = nil
+	order.DeletedAt = nil
+	actor := callerFromContext(r.Context())
+
+	s.mu.Lock()
+	existing, exists := s.orders[id]
+	// Мягко удаленный заказ считается отсутствующим: PUT создает его заново
+	exists = exists && existing.DeletedAt == nil
+
+	var current string
+	if exists {
+		current = orderETag(existing)
+	}
+	if !checkPreconditions(w, r, current, exists) {
+		s.mu.Unlock()
+		return
+	}
+
+	var previous *Order
+	if exists {
+		previous = &existing
+	}
+	if err := s.swapStock(previous, &order); err != nil {
+		s.mu.Unlock()
+		writeInsufficientStock(w)
+		return
+	}
+
+	status := http.StatusOK
+	if exists {
+		order.CreatedAt = existing.CreatedAt
+		order.OrderNumber = existing.OrderNumber
+		s.storeOrder(order)
+		s.recordOrderChange(&existing, order, actor)
+	} else {
+		status = http.StatusCreated
+		order.CreatedAt = now()
+		order.OrderNumber = s.numbers.Next(order.CreatedAt)
+		s.storeOrder(order)
+		s.recordOrderChange(nil, order, actor)
+		// Генератор должен знать о явно заданном ID, иначе
+		// следующий POST перезапишет этот заказ
+		s.ids.Observe(id)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", orderETag(order))
+	if status == http.StatusCreated {
+		w.Header().Set("Location", fmt.Sprintf("/orders/%d", id))
+	}
+	writeBody(w, r, status, order)
+}
+
+// patchableFields - поля, которые можно менять через PATCH /orders/{id}.
+var patchableFields = map[string]bool{
+	"quantity": true,
+}
+
+// decodePatch разбирает тело PATCH в обобщенную карту. Числа остаются
+// json.Number, чтобы большие целые не теряли точность при переходе через float64.
+func decodePatch(r *http.Request) (map[string]interface{}, error) {
+	body, err := jsonBody(r)
+	if err != nil {
+		return nil, err
+	}
+	var patch map[string]interface{}
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := decodeSingleValue(dec, &patch); err != nil {
+		return nil, err
+	}
+	for name := range patch {
+		if !patchableFields[name] {
+			return nil, fmt.Errorf("json: unknown field %q", name)
+		}
+	}
+	return patch, nil
+}
+
+// patchInt достает из патча целочисленное поле. Дробные значения вроде 1.5
+// и строки отклоняются, а не округляются.
+func patchInt(patch map[string]interface{}, name string) (int, bool, error) {
+	raw, ok := patch[name]
+	if !ok {
+		return 0, false, nil
+	}
+	num, ok := raw.(json.Number)
+	if !ok {
+		return 0, true, fmt.Errorf("%s must be an integer", name)
+	}
+	value, err := strconv.ParseInt(num.String(), 10, strconv.IntSize)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s must be an integer", name)
+	}
+	return int(value), true, nil
+}
+
+// lockedStatuses - статусы, в которых количество менять уже нельзя.
+var lockedStatuses = map[string]bool{
+	"shipped":   true,
+	"delivered": true,
+}
+
+// patchOrder меняет количество в заказе и, если включен учет остатков,
+// списывает или возвращает на склад разницу.
+func (s *server) patchOrder(w http.ResponseWriter, r *http.Request, id int) {
+	patch, err := decodePatch(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+
+	quantity, ok, err := patchInt(patch, "quantity")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, "validation_failed", "quantity is required")
+		return
+	}
+	if err := validateQuantity(quantity, s.maxQuantity); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	// Проверка статуса, изменение остатков и запись заказа идут под одной
+	// блокировкой, чтобы параллельные PATCH не разошлись с остатками
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.orders[id]
+	if !exists || existing.DeletedAt != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Order not found")
+		return
+	}
+
+	if lockedStatuses[existing.Status] {
+		writeError(w, http.StatusConflict, "order_locked", "Quantity cannot be changed once the order is "+existing.Status)
+		return
+	}
+
+	updated := existing
+	updated.Quantity = quantity
+	if err := s.adjustStock(existing, updated.Quantity-existing.Quantity); err != nil {
+		writeInsufficientStock(w)
+		return
+	}
+
+	s.storeOrder(updated)
+	s.recordOrderChange(&existing, updated, callerFromContext(r.Context()))
+
+	writeBody(w, r, http.StatusOK, updated)
+}
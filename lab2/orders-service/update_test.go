@@ -0,0 +1,434 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newInventoryServer возвращает сервер с учетом остатков, где заказы 1 и 2
+// уже держат свой товар, как если бы их создали через API.
+func newInventoryServer(t *testing.T, stock map[string]int) *server {
+	t.Helper()
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 5, Status: "pending"},
+		2: {ID: 2, UserID: 1, Product: "Pen", Quantity: 1, Status: "shipped"},
+	})
+	s.stock = stock
+	s.held = map[int]int{1: 5, 2: 1}
+	return s
+}
+
+func (s *server) stockOf(product string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stock[product]
+}
+
+func TestPatchOrder_QuantityIncrease(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+
+	rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 8}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.Quantity != 8 {
+		t.Errorf("Expected quantity 8, got: %d", order.Quantity)
+	}
+	if got := s.stockOf("Pen"); got != 7 {
+		t.Errorf("Expected stock to drop by the delta to 7, got: %d", got)
+	}
+}
+
+func TestPatchOrder_QuantityDecrease(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+
+	if rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 2}`)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if got := s.stockOf("Pen"); got != 13 {
+		t.Errorf("Expected released stock to bring total to 13, got: %d", got)
+	}
+}
+
+func TestPatchOrder_InsufficientStock(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 2})
+
+	rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 8}`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got: %d", rec.Code)
+	}
+	if got := s.stockOf("Pen"); got != 2 {
+		t.Errorf("Expected stock to stay at 2, got: %d", got)
+	}
+
+	s.mu.RLock()
+	quantity := s.orders[1].Quantity
+	s.mu.RUnlock()
+	if quantity != 5 {
+		t.Errorf("Expected order to be unchanged, got quantity: %d", quantity)
+	}
+}
+
+func TestPatchOrder_ShippedOrderIsLocked(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+
+	if rec := s.serve(jsonRequest(http.MethodPatch, "/orders/2", `{"quantity": 3}`)); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for shipped order, got: %d", rec.Code)
+	}
+}
+
+func TestPatchOrder_Validation(t *testing.T) {
+	s := newInventoryServer(t, nil)
+
+	for _, body := range []string{`{}`, `{"quantity": 0}`, `{"product": "Ink"}`, `{"quantity": "3"}`} {
+		if rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", body, rec.Code)
+		}
+	}
+
+	if rec := s.serve(jsonRequest(http.MethodPatch, "/orders/99", `{"quantity": 1}`)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+}
+
+func TestPatchOrder_FractionalQuantity(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+
+	rec := s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 1.5}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got: %d", rec.Code)
+	}
+	if order := s.orders[1]; order.Quantity != 5 {
+		t.Errorf("Expected quantity to stay 5, got: %d", order.Quantity)
+	}
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected stock to stay 10, got: %d", got)
+	}
+}
+
+func TestCreateOrder_ReservesStock(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 3})
+	useUserService(t, s, userServiceStub)
+
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":3}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`)); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 once stock is exhausted, got: %d", rec.Code)
+	}
+}
+
+// vanishingUser отвечает пользователем на первый запрос и 404 на все
+// последующие - как если бы пользователя удалили сразу после проверки.
+func vanishingUser() http.HandlerFunc {
+	var calls atomic.Int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) > 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		userServiceStub(w, r)
+	}
+}
+
+func TestCreateOrder_RecheckCatchesDeletedUser(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	useUserService(t, s, vanishingUser())
+	s.setFlags(func(f *Flags) { f.RecheckUserOnCreate = true })
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":4}`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if len(s.orders) != 2 {
+		t.Errorf("Expected no order to be stored, got: %d orders", len(s.orders))
+	}
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected reservation to be released, got stock: %d", got)
+	}
+}
+
+func TestCreateOrder_WithoutRecheckRaceWindowRemains(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, vanishingUser())
+
+	// Без повторной проверки заказ создается, хотя пользователя уже нет:
+	// это и есть задокументированное окно гонки
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":4}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+}
+
+func TestMaxOrderQuantity_Boundary(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	s.maxQuantity = 100
+
+	for _, tc := range []struct {
+		method, target, body string
+		ok                   int
+	}{
+		{http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":%d}`, http.StatusCreated},
+		{http.MethodPut, "/orders/1", `{"user_id":1,"product":"Pen","quantity":%d}`, http.StatusOK},
+		{http.MethodPatch, "/orders/1", `{"quantity":%d}`, http.StatusOK},
+	} {
+		if rec := s.serve(jsonRequest(tc.method, tc.target, fmt.Sprintf(tc.body, 101))); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s with max+1: expected status 400, got: %d", tc.method, tc.target, rec.Code)
+		}
+		if rec := s.serve(jsonRequest(tc.method, tc.target, fmt.Sprintf(tc.body, 100))); rec.Code != tc.ok {
+			t.Errorf("%s %s with max: expected status %d, got: %d", tc.method, tc.target, tc.ok, rec.Code)
+		}
+	}
+}
+
+func TestCreateOrder_DefaultStatus(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	for _, body := range []string{
+		`{"user_id":1,"product":"Pen","quantity":1}`,
+		`{"user_id":1,"product":"Pen","quantity":1,"status":""}`,
+	} {
+		rec := s.serve(jsonRequest(http.MethodPost, "/orders", body))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d", rec.Code)
+		}
+		if order := decodeOrder(t, rec); order.Status != "pending" {
+			t.Errorf("%s: expected default status pending, got: %q", body, order.Status)
+		}
+	}
+
+	s.defaultStatus = "processing"
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`))
+	if order := decodeOrder(t, rec); order.Status != "processing" {
+		t.Errorf("Expected configured default status, got: %q", order.Status)
+	}
+}
+
+func TestCreateOrder_InvalidStatus(t *testing.T) {
+	s := newTestServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1,"status":"lost"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+func TestCreate_LocationHeader(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, userServiceStub)
+
+	for _, req := range []*http.Request{
+		jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`),
+		jsonRequest(http.MethodPost, "/orders/1/duplicate", ""),
+		jsonRequest(http.MethodPut, "/orders/40", `{"user_id":1,"product":"Pen","quantity":1}`),
+	} {
+		rec := s.serve(req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s %s: expected status 201, got: %d", req.Method, req.URL, rec.Code)
+		}
+		order := decodeOrder(t, rec)
+		if loc, want := rec.Header().Get("Location"), fmt.Sprintf("/orders/%d", order.ID); loc != want {
+			t.Errorf("%s %s: expected Location %q, got: %q", req.Method, req.URL, want, loc)
+		}
+	}
+
+	rec := s.serve(jsonRequest(http.MethodPut, "/orders/40", `{"user_id":1,"product":"Pen","quantity":2}`))
+	if loc := rec.Header().Get("Location"); rec.Code != http.StatusOK || loc != "" {
+		t.Errorf("Expected replacement to return 200 without Location, got: %d %q", rec.Code, loc)
+	}
+}
+
+func TestGetOrderByID_IfNoneMatch(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag on GET /orders/{id}")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("If-None-Match", `"stale", W/`+etag)
+	if rec := s.serve(req); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 without body, got: %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	if rec := s.serve(req); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a stale ETag, got: %d", rec.Code)
+	}
+}
+
+func TestPutOrder_Preconditions(t *testing.T) {
+	s := newInventoryServer(t, nil)
+	useUserService(t, s, userServiceStub)
+	body := `{"user_id":1,"product":"Pen","quantity":2}`
+
+	// If-None-Match: * - создать, только если заказа еще нет
+	req := jsonRequest(http.MethodPut, "/orders/1", body)
+	req.Header.Set("If-None-Match", "*")
+	if rec := s.serve(req); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for existing order, got: %d", rec.Code)
+	}
+	req = jsonRequest(http.MethodPut, "/orders/50", body)
+	req.Header.Set("If-None-Match", "*")
+	if rec := s.serve(req); rec.Code != http.StatusCreated {
+		t.Errorf("Expected 201 for a new order, got: %d", rec.Code)
+	}
+
+	etag := orderETag(s.orders[1])
+	req = jsonRequest(http.MethodPut, "/orders/1", body)
+	req.Header.Set("If-Match", `"other", `+etag)
+	rec := s.serve(req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for a matching ETag, got: %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got == etag || got != orderETag(s.orders[1]) {
+		t.Errorf("Expected the new ETag in the response, got: %q", got)
+	}
+
+	req = jsonRequest(http.MethodPut, "/orders/1", `{"user_id":1,"product":"Pen","quantity":3}`)
+	req.Header.Set("If-Match", etag)
+	if rec := s.serve(req); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale ETag, got: %d", rec.Code)
+	}
+	if s.orders[1].Quantity != 2 {
+		t.Errorf("Expected stale update to be rejected, got quantity: %d", s.orders[1].Quantity)
+	}
+}
+
+func TestDeleteOrder_IfMatch(t *testing.T) {
+	s := newInventoryServer(t, map[string]int{"Pen": 10})
+	etag := orderETag(s.orders[1])
+
+	req := httptest.NewRequest(http.MethodDelete, "/orders/1", nil)
+	req.Header.Set("If-Match", `"stale"`)
+	rec := s.serve(req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected 412 for a stale ETag, got: %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("Expected 412 to carry the current ETag, got: %q", got)
+	}
+	if s.orders[1].DeletedAt != nil || s.stockOf("Pen") != 10 {
+		t.Fatal("Expected order and stock untouched after a stale delete")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/orders/1", nil)
+	req.Header.Set("If-Match", etag)
+	if rec := s.serve(req); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 for a matching ETag, got: %d", rec.Code)
+	}
+	if s.stockOf("Pen") != 15 {
+		t.Errorf("Expected stock released by the delete, got: %d", s.stockOf("Pen"))
+	}
+
+	// Без If-Match удаление остается безусловным
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/2", nil)); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 without If-Match, got: %d", rec.Code)
+	}
+}
+
+func TestOrderErrors_ProblemJSON(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := s.problems(s.routes())
+	serveWith := func(r *http.Request) *httptest.ResponseRecorder {
+		r.Header.Set("Accept", problemMediaType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serveWith(httptest.NewRequest(http.MethodGet, "/orders/99", nil))
+	if p := decodeProblem(t, rec); rec.Code != http.StatusNotFound || p.Type != "urn:problem-type:not-found" || p.Instance != "/orders/99" {
+		t.Errorf("Unexpected 404 problem: %d %+v", rec.Code, p)
+	}
+
+	rec = serveWith(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":0}`))
+	if p := decodeProblem(t, rec); rec.Code != http.StatusBadRequest || p.Type != "urn:problem-type:validation-failed" || p.Detail == "" {
+		t.Errorf("Unexpected validation problem: %d %+v", rec.Code, p)
+	}
+
+	rec = serveWith(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":1}`))
+	if p := decodeProblem(t, rec); p.Type != "urn:problem-type:invalid-user" || p.Title != "User not found or unavailable" {
+		t.Errorf("Unexpected user problem: %d %+v", rec.Code, p)
+	}
+
+	// Флаг problem_json включает формат без Accept
+	s.setFlags(func(f *Flags) { f.ProblemJSON = true })
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/99", nil))
+	decodeProblem(t, rec)
+}
+
+func TestDeleteOrder_LoadedOrderDoesNotInflateStock(t *testing.T) {
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Laptop", Quantity: 1, Status: "pending"},
+	})
+	s.stock = seedStock()
+
+	// Загруженный при старте заказ склад не уменьшал
+	if rec := s.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if got := s.stockOf("Laptop"); got != 10 {
+		t.Errorf("Expected stock untouched by deleting a loaded order, got: %d", got)
+	}
+
+	// Восстановленный заказ списывает товар и при повторном удалении его возвращает
+	if rec := s.serve(httptest.NewRequest(http.MethodPost, "/orders/1/restore", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if got := s.stockOf("Laptop"); got != 9 {
+		t.Errorf("Expected restore to hold one laptop, got: %d", got)
+	}
+	s.serve(httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if got := s.stockOf("Laptop"); got != 10 {
+		t.Errorf("Expected the held laptop back in stock, got: %d", got)
+	}
+}
+
+func TestPatchOrder_LoadedOrderReleasesOnlyHeldStock(t *testing.T) {
+	s := newTestServer(t, map[int]Order{
+		1: {ID: 1, UserID: 1, Product: "Pen", Quantity: 5, Status: "pending"},
+	})
+	s.stock = map[string]int{"Pen": 10}
+
+	s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 7}`))
+	if got := s.stockOf("Pen"); got != 8 {
+		t.Fatalf("Expected the increase to take 2 pens, got: %d", got)
+	}
+	// Заказ держит только 2 списанные единицы: вернуть больше нельзя
+	s.serve(jsonRequest(http.MethodPatch, "/orders/1", `{"quantity": 1}`))
+	if got := s.stockOf("Pen"); got != 10 {
+		t.Errorf("Expected only the held pens returned, got: %d", got)
+	}
+}
+
+func TestStoreOrder_EvictionReleasesStock(t *testing.T) {
+	s := newTestServer(t, map[int]Order{})
+	useUserService(t, s, userServiceStub)
+	s.stock = map[string]int{"Pen": 10}
+	s.limit = newOrderLRU(1)
+
+	for i := 0; i < 2; i++ {
+		if rec := s.serve(jsonRequest(http.MethodPost, "/orders", `{"user_id":1,"product":"Pen","quantity":3}`)); rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+		}
+	}
+	// Первый заказ вытеснен вторым и вернул свои 3 единицы
+	if got := s.stockOf("Pen"); got != 7 {
+		t.Errorf("Expected stock held only by the remaining order, got: %d", got)
+	}
+}
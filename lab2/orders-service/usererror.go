@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Диагностика встраивания пользователей. С ?include_user_errors=true
+// каждый заказ списка, которому не удалось встроить пользователя,
+// получает поле user_error с причиной вместо молчаливого user: null.
+// Поле появляется только там, где пользователь встраивается (?expand=user
+// или user в ?fields=).
+
+// userLookupError - причина, по которой пользователь не встроен.
+type userLookupError struct {
+	// Code - timeout, not_found или unavailable
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func parseIncludeUserErrors(r *http.Request) (bool, error) {
+	q := newQueryParams(r)
+	v := q.Bool("include_user_errors")
+	return v, q.Err()
+}
+
+// newUserLookupError классифицирует ошибку клиента user-service.
+// Локальные отказы (разомкнутый breaker, сброс нагрузки, лимит запросов)
+// для клиента неотличимы от недоступности сервиса.
+func newUserLookupError(err error) *userLookupError {
+	code := "unavailable"
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		code = "not_found"
+	case errors.Is(err, ErrUserServiceTimeout), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		code = "timeout"
+	}
+	return &userLookupError{Code: code, Message: err.Error()}
+}
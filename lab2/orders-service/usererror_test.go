@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func userErrorDataset() map[int]Order {
+	data := map[int]Order{}
+	for id := 1; id <= 4; id++ {
+		data[id] = Order{ID: id, UserID: id, Product: "Pen", Quantity: 1, Status: "pending"}
+	}
+	return data
+}
+
+// failingUserService отвечает по-разному в зависимости от ID: 1 - найден,
+// 2 - 404, 3 - 500, 4 - дольше таймаута клиента.
+func failingUserService(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/users/2":
+		w.WriteHeader(http.StatusNotFound)
+	case "/users/3":
+		w.WriteHeader(http.StatusInternalServerError)
+	case "/users/4":
+		time.Sleep(300 * time.Millisecond)
+		userServiceStub(w, r)
+	default:
+		userServiceStub(w, r)
+	}
+}
+
+func TestGetOrders_IncludeUserErrors(t *testing.T) {
+	s := newTestServer(t, userErrorDataset())
+	useUserService(t, s, failingUserService)
+	s.userClient.Client.Timeout = 100 * time.Millisecond
+
+	want := map[float64]string{1: "", 2: "not_found", 3: "unavailable", 4: "timeout"}
+	for _, target := range []string{
+		"/orders?expand=user&include_user_errors=true",
+		"/orders?fields=id,user&include_user_errors=true",
+	} {
+		list := listOrderMaps(t, s, target)
+		if len(list) != len(want) {
+			t.Fatalf("%s: expected %d orders, got: %d", target, len(want), len(list))
+		}
+		for _, order := range list {
+			code := want[order["id"].(float64)]
+			userErr, has := order["user_error"].(map[string]interface{})
+			if code == "" {
+				if has || order["user"] == nil {
+					t.Errorf("%s: expected order %v with user and no error, got: %v", target, order["id"], order)
+				}
+				continue
+			}
+			if !has || userErr["code"] != code || userErr["message"] == "" {
+				t.Errorf("%s: expected order %v user_error %s, got: %v", target, order["id"], code, order["user_error"])
+			}
+			if order["user"] != nil {
+				t.Errorf("%s: expected order %v without user, got: %v", target, order["id"], order["user"])
+			}
+		}
+	}
+}
+
+func TestGetOrders_UserErrorsOffByDefault(t *testing.T) {
+	s := newTestServer(t, userErrorDataset())
+	useUserService(t, s, failingUserService)
+	s.userClient.Client.Timeout = 100 * time.Millisecond
+
+	for _, order := range listOrderMaps(t, s, "/orders?expand=user") {
+		if _, has := order["user_error"]; has {
+			t.Errorf("Expected no user_error without include_user_errors, got: %v", order)
+		}
+	}
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/orders?include_user_errors=maybe", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid flag, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// allowedStatuses - допустимые статусы заказа.
+var allowedStatuses = map[string]bool{
+	"pending":    true,
+	"processing": true,
+	"shipped":    true,
+	"delivered":  true,
+	"cancelled":  true,
+}
+
+// defaultMaxOrderQuantity - верхняя граница количества в одном заказе.
+const defaultMaxOrderQuantity = 1000
+
+// validateOrder проверяет поля заказа, пришедшего от клиента.
+func validateOrder(order Order, maxQuantity int) error {
+	if err := validateStoredOrder(order, maxQuantity); err != nil {
+		return err
+	}
+	if order.Status != "" && !allowedStatuses[order.Status] {
+		return fmt.Errorf("unknown status %q", order.Status)
+	}
+	return nil
+}
+
+// validateStoredOrder проверяет загружаемый заказ: то же, что
+// validateOrder, кроме статуса - неизвестный статус разбирает
+// applyStatusPolicy.
+func validateStoredOrder(order Order, maxQuantity int) error {
+	if order.UserID <= 0 {
+		return errors.New("user_id must be a positive integer")
+	}
+	if order.Product == "" {
+		return errors.New("product is required")
+	}
+	return validateQuantity(order.Quantity, maxQuantity)
+}
+
+// checkOrderUser проверяет, что пользователь заказа существует и может
+// оформлять заказы. При ошибке сам пишет ответ и возвращает false.
+// Пользователь целиком нужен только для проверки подтверждения email,
+// иначе хватает дешевой проверки существования.
+func (s *server) checkOrderUser(w http.ResponseWriter, r *http.Request, userID int) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	if !s.flags.Get().RequireVerifiedUsers {
+		exists, err := s.userClient.UserExists(ctx, userID)
+		if err == nil && !exists {
+			err = ErrUserNotFound
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_user", fmt.Sprintf("User not found or service unavailable: %v", err))
+			return false
+		}
+		return true
+	}
+
+	user, err := s.userClient.GetUserByID(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_user", fmt.Sprintf("User not found or service unavailable: %v", err))
+		return false
+	}
+	if !user.Verified {
+		writeError(w, http.StatusUnprocessableEntity, "user_not_verified", "User email is not verified")
+		return false
+	}
+
+	return true
+}
+
+// normalizeOrder приводит поля заказа к каноничному виду перед
+// validateOrder: нормализует теги и подставляет статус по умолчанию.
+func (s *server) normalizeOrder(order *Order) error {
+	tags, err := s.tagLimits.Normalize(order.Tags)
+	if err != nil {
+		return err
+	}
+	order.Tags = tags
+	// Цена и сумма вычисляются при чтении и не хранятся
+	order.Price, order.Total = nil, nil
+
+	if order.Status == "" {
+		order.Status = s.defaultStatus
+	}
+	return nil
+}
+
+// validateQuantity проверяет количество при создании, замене и PATCH заказа.
+func validateQuantity(qty, maxQuantity int) error {
+	if qty <= 0 {
+		return errors.New("quantity must be a positive integer")
+	}
+	if qty > maxQuantity {
+		return fmt.Errorf("quantity must not exceed %d", maxQuantity)
+	}
+	return nil
+}
+
+// recheckOrderUser повторно подтверждает, что пользователь все еще существует,
+// непосредственно перед вставкой заказа. Кэш пользователей не используется.
+func (s *server) recheckOrderUser(w http.ResponseWriter, r *http.Request, userID int) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.userClient.FetchUserByID(ctx, userID)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		writeError(w, http.StatusConflict, "user_gone", "User was removed while the order was being created")
+		return false
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, "user_service_unavailable", fmt.Sprintf("Could not re-check user: %v", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// warmupBatchSize - сколько пользователей запрашивается параллельно за
+// один шаг прогрева. Шаги идут друг за другом, а enrichPool не
+// занимается, чтобы прогрев не отнимал его у живых запросов.
+const warmupBatchSize = 8
+
+// warmupTimeout ограничивает весь прогрев.
+const warmupTimeout = 30 * time.Second
+
+// warmupReport - ответ POST /admin/cache/warmup.
+type warmupReport struct {
+	Enabled bool `json:"enabled"`
+	// Users - сколько разных пользователей упоминается в заказах
+	Users int `json:"users"`
+	// Warmed - сколько пользователей загружено в кэш
+	Warmed int `json:"warmed"`
+	// AlreadyCached - сколько уже было в кэше и не запрашивалось
+	AlreadyCached int `json:"already_cached"`
+	NotFound      int `json:"not_found"`
+	Failed        int `json:"failed"`
+}
+
+// warmUserCache загружает в кэш пользователей живых заказов. Используется
+// после деплоя, пока кэш пуст и первые запросы идут в user-service.
+func (s *server) warmUserCache(ctx context.Context) warmupReport {
+	cache := s.userClient.Cache
+	if cache == nil {
+		return warmupReport{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	seen := map[int]bool{}
+	s.mu.RLock()
+	for _, order := range s.orders {
+		if order.DeletedAt == nil {
+			seen[order.UserID] = true
+		}
+	}
+	s.mu.RUnlock()
+
+	report := warmupReport{Enabled: true, Users: len(seen)}
+	var pending []int
+	for id := range seen {
+		if _, ok := cache.Get(id); ok {
+			report.AlreadyCached++
+			continue
+		}
+		pending = append(pending, id)
+	}
+	sort.Ints(pending)
+
+	for start := 0; start < len(pending); start += warmupBatchSize {
+		end := start + warmupBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, id := range batch {
+			wg.Add(1)
+			go func(i, id int) {
+				defer wg.Done()
+				// FetchUserByID сам кладет пользователя в кэш
+				_, errs[i] = s.userClient.FetchUserByID(ctx, id)
+			}(i, id)
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				report.Warmed++
+			case errors.Is(err, ErrUserNotFound):
+				report.NotFound++
+			default:
+				report.Failed++
+				log.Printf("Warning: cache warmup of user %d failed: %v", batch[i], err)
+			}
+		}
+	}
+	log.Printf("User cache warmup: %d warmed, %d already cached, %d not found, %d failed",
+		report.Warmed, report.AlreadyCached, report.NotFound, report.Failed)
+	return report
+}
+
+// handleCacheWarmup обрабатывает POST /admin/cache/warmup. Без кэша
+// отвечает enabled: false и ничего не запрашивает.
+func (s *server) handleCacheWarmup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	report := s.warmUserCache(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
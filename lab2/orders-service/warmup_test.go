@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func postWarmup(t *testing.T, s *server) warmupReport {
+	t.Helper()
+	rec := s.serve(httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var report warmupReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode warmup report: %v", err)
+	}
+	return report
+}
+
+func TestCacheWarmup_PopulatesCache(t *testing.T) {
+	setClock(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	data := quantityDataset()
+	data[5] = Order{ID: 5, UserID: 404, Product: "Pen", Quantity: 1, Status: "pending"}
+	deletedAt := now()
+	data[6] = Order{ID: 6, UserID: 9, Product: "Pen", Quantity: 1, Status: "pending", DeletedAt: &deletedAt}
+	s := newTestServer(t, data)
+	s.adminEnabled = true
+
+	var calls atomic.Int32
+	useUserService(t, s, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/404") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		userServiceStub(w, r)
+	})
+	s.userClient.Cache = newUserCache(time.Minute)
+
+	report := postWarmup(t, s)
+	want := warmupReport{Enabled: true, Users: 3, Warmed: 2, NotFound: 1}
+	if report != want {
+		t.Errorf("Expected report %+v, got: %+v", want, report)
+	}
+	entries := listCache(t, s)
+	if len(entries) != 2 || entries[0].UserID != 1 || entries[1].UserID != 2 {
+		t.Fatalf("Expected users 1 and 2 in the cache, got: %+v", entries)
+	}
+
+	// Прогретый пользователь отдается из кэша без запроса в user-service
+	calls.Store(0)
+	s.serve(withUserProfile(httptest.NewRequest(http.MethodGet, "/orders/3", nil)))
+	if calls.Load() != 0 {
+		t.Errorf("Expected a cache hit after warmup, got %d upstream calls", calls.Load())
+	}
+
+	// Повторный прогрев не запрашивает уже закэшированных
+	calls.Store(0)
+	report = postWarmup(t, s)
+	if report.AlreadyCached != 2 || report.Warmed != 0 || calls.Load() != 1 {
+		t.Errorf("Expected only the missing user to be refetched, got: %+v and %d calls", report, calls.Load())
+	}
+}
+
+func TestCacheWarmup_WithoutCache(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	s.adminEnabled = true
+	if report := postWarmup(t, s); report.Enabled || report.Users != 0 {
+		t.Errorf("Expected a no-op report without a cache, got: %+v", report)
+	}
+}
+
+func TestCacheWarmup_AdminOnly(t *testing.T) {
+	s := newTestServer(t, quantityDataset())
+	if rec := s.serve(httptest.NewRequest(http.MethodPost, "/admin/cache/warmup", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 with admin disabled, got: %d", rec.Code)
+	}
+	s.adminEnabled = true
+	if rec := s.serve(httptest.NewRequest(http.MethodGet, "/admin/cache/warmup", nil)); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got: %d", rec.Code)
+	}
+}
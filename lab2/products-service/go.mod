@@ -0,0 +1,3 @@
+module products-service
+
+go 1.21
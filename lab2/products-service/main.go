@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Product - товар каталога. Цена хранится в валюте магазина.
+type Product struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+var (
+	// Ключ - название товара в нижнем регистре: заказы ссылаются на
+	// товар по имени, и регистр в них не гарантирован.
+	products = map[string]Product{
+		"laptop":   {Name: "Laptop", Price: 999.99},
+		"mouse":    {Name: "Mouse", Price: 19.5},
+		"keyboard": {Name: "Keyboard", Price: 49},
+	}
+	mutex = sync.RWMutex{}
+)
+
+// ErrorResponse - единый формат ошибок API.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+func getProducts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	mutex.RLock()
+	list := make([]Product, 0, len(products))
+	for _, p := range products {
+		list = append(list, p)
+	}
+	mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// getProductByName обрабатывает GET /products/{name}.
+func getProductByName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/products/")
+	mutex.RLock()
+	product, exists := products[strings.ToLower(name)]
+	mutex.RUnlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "Product not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func healthCheck(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func newRouter() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/products", getProducts)
+	mux.HandleFunc("/products/", getProductByName)
+	mux.HandleFunc("/health", healthCheck)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "Route not found")
+	})
+	return mux
+}
+
+func main() {
+	addr := ":8083"
+	if raw := os.Getenv("PRODUCTS_ADDR"); raw != "" {
+		addr = raw
+	}
+
+	log.Printf("Products service started on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, newRouter()))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, r)
+	return rec
+}
+
+func TestGetProductByName(t *testing.T) {
+	rec := serve(httptest.NewRequest(http.MethodGet, "/products/LAPTOP", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	var product Product
+	if err := json.NewDecoder(rec.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to decode product: %v", err)
+	}
+	if product.Name != "Laptop" || product.Price != 999.99 {
+		t.Errorf("Unexpected product: %+v", product)
+	}
+}
+
+func TestGetProductByName_NotFound(t *testing.T) {
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/products/Pen", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+}
+
+func TestGetProducts(t *testing.T) {
+	rec := serve(httptest.NewRequest(http.MethodGet, "/products", nil))
+	var list []Product
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode products: %v", err)
+	}
+	if len(list) != len(products) {
+		t.Errorf("Expected %d products, got: %d", len(products), len(list))
+	}
+}
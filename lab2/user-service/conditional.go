@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Условные запросы по RFC 7232. Файл одинаков в обоих сервисах.
+
+// entityTag - разобранный ETag: opaque - значение в кавычках вместе с ними.
+type entityTag struct {
+	weak   bool
+	opaque string
+}
+
+func parseETag(raw string) (entityTag, bool) {
+	var tag entityTag
+	if strings.HasPrefix(raw, "W/") {
+		tag.weak = true
+		raw = raw[2:]
+	}
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' || strings.Contains(raw[1:len(raw)-1], `"`) {
+		return tag, false
+	}
+	tag.opaque = raw
+	return tag, true
+}
+
+// parseETagList разбирает значение If-Match/If-None-Match: "*" или
+// список ETag через запятую. Запятая внутри кавычек разделителем не
+// считается. Неверно оформленные элементы пропускаются.
+func parseETagList(header string) (tags []entityTag, any bool) {
+	if strings.TrimSpace(header) == "*" {
+		return nil, true
+	}
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		end := 0
+		if strings.HasPrefix(header, "W/") {
+			end = 2
+		}
+		if end < len(header) && header[end] == '"' {
+			if closing := strings.IndexByte(header[end+1:], '"'); closing >= 0 {
+				end += closing + 2
+			} else {
+				end = len(header)
+			}
+		}
+		if next := strings.IndexByte(header[end:], ','); next >= 0 {
+			end += next
+		} else {
+			end = len(header)
+		}
+		if tag, ok := parseETag(strings.TrimSpace(header[:end])); ok {
+			tags = append(tags, tag)
+		}
+		header = header[end:]
+	}
+	return tags, false
+}
+
+// strongMatch - строгое сравнение: оба ETag сильные и совпадают.
+func strongMatch(a, b entityTag) bool {
+	return !a.weak && !b.weak && a.opaque == b.opaque
+}
+
+// weakMatch - слабое сравнение: совпадают значения, признак W/ не важен.
+func weakMatch(a, b entityTag) bool {
+	return a.opaque == b.opaque
+}
+
+// ifMatchPasses проверяет If-Match: "*" выполняется для существующего
+// ресурса, список - при строгом совпадении хотя бы одного ETag.
+// Пустой заголовок условия не задает.
+func ifMatchPasses(header, current string, exists bool) bool {
+	if header == "" {
+		return true
+	}
+	tags, any := parseETagList(header)
+	if any {
+		return exists
+	}
+	cur, ok := parseETag(current)
+	if !exists || !ok {
+		return false
+	}
+	for _, tag := range tags {
+		if strongMatch(tag, cur) {
+			return true
+		}
+	}
+	return false
+}
+
+// ifNoneMatchPasses проверяет If-None-Match: "*" выполняется, только если
+// ресурса нет, список - если ни один ETag не совпал при слабом сравнении.
+func ifNoneMatchPasses(header, current string, exists bool) bool {
+	if header == "" {
+		return true
+	}
+	tags, any := parseETagList(header)
+	if any {
+		return !exists
+	}
+	cur, ok := parseETag(current)
+	if !exists || !ok {
+		return true
+	}
+	for _, tag := range tags {
+		if weakMatch(tag, cur) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPreconditions применяет If-Match и If-None-Match к изменению
+// ресурса и при невыполненном условии отвечает 412 с текущим ETag.
+// Возвращает false, если ответ уже записан.
+func checkPreconditions(w http.ResponseWriter, r *http.Request, current string, exists bool) bool {
+	if ifMatchPasses(r.Header.Get("If-Match"), current, exists) &&
+		ifNoneMatchPasses(r.Header.Get("If-None-Match"), current, exists) {
+		return true
+	}
+	if exists {
+		w.Header().Set("ETag", current)
+	}
+	writeError(w, http.StatusPreconditionFailed, "precondition_failed", "Precondition failed")
+	return false
+}
+
+// notModified отвечает 304 на GET с совпавшим If-None-Match.
+// Возвращает true, если ответ уже записан.
+func notModified(w http.ResponseWriter, r *http.Request, current string) bool {
+	if ifNoneMatchPasses(r.Header.Get("If-None-Match"), current, true) {
+		return false
+	}
+	w.Header().Set("ETag", current)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
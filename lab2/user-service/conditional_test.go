@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestParseETagList(t *testing.T) {
+	tags, any := parseETagList(`"a", W/"b",  "c,d" , bogus, "e"`)
+	if any {
+		t.Fatal("Expected a list, not a wildcard")
+	}
+	want := []entityTag{{opaque: `"a"`}, {weak: true, opaque: `"b"`}, {opaque: `"c,d"`}, {opaque: `"e"`}}
+	if len(tags) != len(want) {
+		t.Fatalf("Expected %v, got: %v", want, tags)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("Tag %d: expected %v, got: %v", i, want[i], tags[i])
+		}
+	}
+
+	if _, any := parseETagList(" * "); !any {
+		t.Error("Expected * to be a wildcard")
+	}
+}
+
+func TestIfMatchPasses(t *testing.T) {
+	const current = `"v2"`
+	for _, tc := range []struct {
+		header string
+		exists bool
+		want   bool
+	}{
+		{"", true, true},
+		{`"v2"`, true, true},
+		{`"v1", "v2"`, true, true},
+		{`"v1"`, true, false},
+		// If-Match требует строгого сравнения: слабый ETag не совпадает
+		{`W/"v2"`, true, false},
+		{"*", true, true},
+		{"*", false, false},
+		{`"v2"`, false, false},
+	} {
+		if got := ifMatchPasses(tc.header, current, tc.exists); got != tc.want {
+			t.Errorf("If-Match %q (exists %v): expected %v, got: %v", tc.header, tc.exists, tc.want, got)
+		}
+	}
+}
+
+func TestIfNoneMatchPasses(t *testing.T) {
+	const current = `"v2"`
+	for _, tc := range []struct {
+		header string
+		exists bool
+		want   bool
+	}{
+		{"", true, true},
+		{`"v2"`, true, false},
+		{`"v1", "v2"`, true, false},
+		// If-None-Match использует слабое сравнение
+		{`W/"v2"`, true, false},
+		{`"v1"`, true, true},
+		{"*", true, false},
+		{"*", false, true},
+		{`"v2"`, false, true},
+	} {
+		if got := ifNoneMatchPasses(tc.header, current, tc.exists); got != tc.want {
+			t.Errorf("If-None-Match %q (exists %v): expected %v, got: %v", tc.header, tc.exists, tc.want, got)
+		}
+	}
+}
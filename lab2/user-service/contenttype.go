@@ -0,0 +1,43 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// isJSONContentType принимает application/json и application/*+json
+// с любыми параметрами (например, charset).
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// requireJSONContentType отвечает 415 на POST/PUT/PATCH с телом не в JSON.
+// Запросы без тела (например, POST /users/{id}/verify) пропускаются.
+// enabled проверяется на каждый запрос, чтобы проверку можно было
+// выключить для нестрогих клиентов без перезапуска.
+func requireJSONContentType(enabled func() bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if r.ContentLength != 0 && enabled() && !isJSONContentType(r.Header.Get("Content-Type")) {
+				writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// strictContentType включает проверку Content-Type. Задается через STRICT_CONTENT_TYPE.
+var strictContentType = true
+
+// maxRequestBody - предел для limitRequestBody. Задается через
+// HTTP_MAX_REQUEST_BODY_BYTES. Живет здесь, а не в expect.go: тот файл
+// общий с orders-service, где предел берется из Config.
+var maxRequestBody int64 = defaultMaxRequestBody
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateUser_ContentType(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	for i, tc := range []struct {
+		contentType string
+		want        int
+	}{
+		{"", http.StatusUnsupportedMediaType},
+		{"text/xml", http.StatusUnsupportedMediaType},
+		{"application/json;charset=UTF-8", http.StatusCreated},
+		{"application/vnd.api+json", http.StatusCreated},
+	} {
+		body := `{"name":"Ann","email":"ann` + string(rune('a'+i)) + `@example.com"}`
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		if rec := serve(req); rec.Code != tc.want {
+			t.Errorf("Content-Type %q: expected status %d, got: %d", tc.contentType, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestStrictContentType_Disabled(t *testing.T) {
+	setUsers(t, map[int]User{})
+	strictContentType = false
+	t.Cleanup(func() { strictContentType = true })
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ann"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	if rec := serve(req); rec.Code != http.StatusCreated {
+		t.Errorf("Expected lenient mode to accept the body, got: %d", rec.Code)
+	}
+}
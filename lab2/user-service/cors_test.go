@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func envMap(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func preflight(h http.Handler, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/health", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORS_PreflightMaxAge(t *testing.T) {
+	cfg, err := loadCORSConfig(envMap(map[string]string{
+		"CORS_ALLOWED_ORIGINS": "*",
+		"CORS_MAX_AGE":         "600",
+	}))
+	if err != nil {
+		t.Fatalf("Expected valid config, got: %v", err)
+	}
+
+	rec := preflight(corsMiddleware(cfg, newRouter()), "https://app.example.com")
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected max-age 600, got: %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard origin, got: %q", got)
+	}
+}
+
+func TestCORS_CredentialsEchoOrigin(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+	h := corsMiddleware(cfg, newRouter())
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected echoed origin, got: %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials header, got: %q", got)
+	}
+
+	// Чужой origin не получает CORS-заголовков
+	rec = preflight(h, "https://evil.example.com")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers for disallowed origin, got: %q", got)
+	}
+}
+
+func TestCORS_CredentialsWithWildcardRejected(t *testing.T) {
+	_, err := loadCORSConfig(envMap(map[string]string{
+		"CORS_ALLOWED_ORIGINS":   "*",
+		"CORS_ALLOW_CREDENTIALS": "true",
+	}))
+	if err == nil {
+		t.Fatal("Expected error for credentials with wildcard origin, got nil")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "net/http"
+
+func deleteUser(w http.ResponseWriter, r *http.Request, id int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	user, exists := users[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	// If-Match необязателен, в отличие от PUT: без него удаление безусловное.
+	// Потерянное обновление - это чужая правка, молча затертая старыми
+	// данными, а удаление ничьих данных не подставляет. Клиенты, которым
+	// важно не удалить измененного пользователя, передают If-Match; 428
+	// сломал бы тех, кто удаляет без ETag.
+	if !checkPreconditions(w, r, userETag(user), true) {
+		return
+	}
+
+	delete(users, id)
+	delete(verificationTokens, id)
+	unindexEmail(user)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Вывод из балансировки перед остановкой. Получив сигнал, сервис
+// переходит в режим draining: /ready отвечает 503, и балансировщик
+// перестает слать новые запросы. Текущие и успевшие прийти запросы
+// обслуживаются как обычно, /health остается 200, чтобы сервис не
+// перезапустили раньше времени. По истечении периода сервер закрывается.
+
+// draining - сервис выводится из балансировки.
+var draining atomic.Bool
+
+// adminEnabled открывает эндпоинты /admin/*. Задается через ADMIN_ENABLED.
+var adminEnabled = false
+
+// defaultDrainPeriod - сколько ждать после перехода в draining, чтобы
+// балансировщик успел заметить 503 на /ready.
+const defaultDrainPeriod = 5 * time.Second
+
+// shutdownTimeout - сколько ждать завершения запросов после периода draining.
+const shutdownTimeout = 10 * time.Second
+
+// loadDrainPeriod читает DRAIN_PERIOD; 0 - закрываться сразу.
+func loadDrainPeriod(getenv func(string) string) (time.Duration, error) {
+	raw := getenv("DRAIN_PERIOD")
+	if raw == "" {
+		return defaultDrainPeriod, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("DRAIN_PERIOD must be a non-negative duration, got %q", raw)
+	}
+	return d, nil
+}
+
+// readyCheck - проверка готовности для балансировщика.
+func readyCheck(w http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		writeError(w, http.StatusServiceUnavailable, "draining", "Service is draining")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+type drainResponse struct {
+	Draining bool `json:"draining"`
+}
+
+// handleDrain: POST /admin/drain выводит сервис из балансировки вручную,
+// DELETE возвращает обратно, GET показывает состояние.
+func handleDrain(w http.ResponseWriter, r *http.Request) {
+	if !adminEnabled {
+		notFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !draining.Swap(true) {
+			log.Println("Draining: /ready now reports 503")
+		}
+	case http.MethodDelete:
+		if draining.Swap(false) {
+			log.Println("Draining cancelled: /ready reports 200 again")
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(drainResponse{Draining: draining.Load()})
+}
+
+// drainAndShutdown переводит сервис в draining, ждет period, пока
+// балансировщик уберет его из ротации, и затем закрывает сервер,
+// дожидаясь начатых запросов.
+func drainAndShutdown(srv *http.Server, period time.Duration) error {
+	draining.Store(true)
+	log.Printf("Draining for %v before shutdown", period)
+	time.Sleep(period)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	return nil
+}
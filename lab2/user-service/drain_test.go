@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// resetDrain возвращает глобальное состояние после теста.
+func resetDrain(t *testing.T) {
+	t.Cleanup(func() {
+		draining.Store(false)
+		adminEnabled = false
+	})
+}
+
+func statusOf(t *testing.T, h http.Handler, method, target string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec.Code
+}
+
+func TestAdminDrain_FlipsReadiness(t *testing.T) {
+	resetDrain(t)
+	router := newRouter()
+
+	if code := statusOf(t, router, http.MethodPost, "/admin/drain"); code != http.StatusNotFound {
+		t.Errorf("Expected drain endpoint hidden without ADMIN_ENABLED, got: %d", code)
+	}
+
+	adminEnabled = true
+	if code := statusOf(t, router, http.MethodGet, "/ready"); code != http.StatusOK {
+		t.Fatalf("Expected ready before drain, got: %d", code)
+	}
+
+	if code := statusOf(t, router, http.MethodPost, "/admin/drain"); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from drain, got: %d", code)
+	}
+	if code := statusOf(t, router, http.MethodGet, "/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /ready 503 while draining, got: %d", code)
+	}
+	if code := statusOf(t, router, http.MethodGet, "/health"); code != http.StatusOK {
+		t.Errorf("Expected /health to stay 200 while draining, got: %d", code)
+	}
+	if code := statusOf(t, router, http.MethodGet, "/users/1"); code != http.StatusOK {
+		t.Errorf("Expected requests to be served while draining, got: %d", code)
+	}
+
+	statusOf(t, router, http.MethodDelete, "/admin/drain")
+	if code := statusOf(t, router, http.MethodGet, "/ready"); code != http.StatusOK {
+		t.Errorf("Expected /ready 200 after drain is cancelled, got: %d", code)
+	}
+}
+
+func TestDrainAndShutdown(t *testing.T) {
+	resetDrain(t)
+
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/", newRouter())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := newHTTPServer("", mux, defaultServerTimeouts, defaultMaxHeaderBytes)
+	go srv.Serve(ln)
+	base := "http://" + ln.Addr().String()
+	// Без keep-alive у сервера не остается простаивающих соединений,
+	// и Shutdown ждет только медленный запрос
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	slow := make(chan int, 1)
+	go func() {
+		resp, err := client.Get(base + "/slow")
+		if err != nil {
+			slow <- 0
+			return
+		}
+		resp.Body.Close()
+		slow <- resp.StatusCode
+	}()
+	// Даем медленному запросу дойти до обработчика
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- drainAndShutdown(srv, 300*time.Millisecond) }()
+	time.Sleep(50 * time.Millisecond)
+
+	for path, want := range map[string]int{"/ready": http.StatusServiceUnavailable, "/health": http.StatusOK} {
+		resp, err := client.Get(base + path)
+		if err != nil {
+			t.Fatalf("GET %s during drain failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s during drain: expected %d, got: %d", path, want, resp.StatusCode)
+		}
+	}
+
+	close(release)
+	if code := <-slow; code != http.StatusOK {
+		t.Errorf("Expected in-flight request to complete, got status: %d", code)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected shutdown error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected shutdown to finish after the drain period")
+	}
+}
+
+func TestLoadDrainPeriod(t *testing.T) {
+	if d, err := loadDrainPeriod(envMap(nil)); err != nil || d != defaultDrainPeriod {
+		t.Errorf("Expected default %v, got: %v (%v)", defaultDrainPeriod, d, err)
+	}
+	if d, err := loadDrainPeriod(envMap(map[string]string{"DRAIN_PERIOD": "0s"})); err != nil || d != 0 {
+		t.Errorf("Expected 0, got: %v (%v)", d, err)
+	}
+	if _, err := loadDrainPeriod(envMap(map[string]string{"DRAIN_PERIOD": "-1s"})); err == nil {
+		t.Error("Expected error for negative period")
+	}
+}
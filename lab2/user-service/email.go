@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// emailKey - ключ emailIndex: сравнение email идет без учета регистра.
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+func buildEmailIndex(data map[int]User) map[string]int {
+	index := make(map[string]int, len(data))
+	for id, user := range data {
+		if user.Email != "" {
+			index[emailKey(user.Email)] = id
+		}
+	}
+	return index
+}
+
+// indexEmail и unindexEmail вызываются под mutex вместе с изменением users.
+func indexEmail(user User) {
+	if user.Email != "" {
+		emailIndex[emailKey(user.Email)] = user.ID
+	}
+}
+
+func unindexEmail(user User) {
+	if emailIndex[emailKey(user.Email)] == user.ID {
+		delete(emailIndex, emailKey(user.Email))
+	}
+}
+
+// emailTaken сообщает, занят ли email кем-то кроме пользователя self.
+// Проверка и последующая запись должны идти под одной блокировкой mutex,
+// иначе два параллельных запроса могут занять один и тот же адрес.
+func emailTaken(email string, self int) (int, bool) {
+	if email == "" {
+		return 0, false
+	}
+	owner, ok := emailIndex[emailKey(email)]
+	return owner, ok && owner != self
+}
+
+func writeEmailTaken(w http.ResponseWriter, owner int) {
+	writeError(w, http.StatusConflict, "email_taken", fmt.Sprintf("Email already belongs to user %d", owner))
+}
+
+// validEmail принимает только голый адрес вида user@host, без имени
+// и угловых скобок.
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email, "@")
+}
+
+// getUserByEmail обрабатывает GET /users/by-email?email=.
+func getUserByEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	email := r.URL.Query().Get("email")
+	if !validEmail(email) {
+		writeError(w, http.StatusBadRequest, "invalid_email", "Invalid email address")
+		return
+	}
+
+	mask, err := parseMaskEmail(r, maskEmails)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	mutex.RLock()
+	var found *User
+	if id, ok := emailIndex[emailKey(email)]; ok {
+		user := maskedUser(users[id], mask)
+		found = &user
+	}
+	mutex.RUnlock()
+
+	if found == nil {
+		writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(found)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetUserByEmail(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann", Email: "Ann@Example.com"},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+	})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=ann@example.COM", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if user := decodeUser(t, rec); user.ID != 1 {
+		t.Errorf("Expected case-insensitive match on user 1, got: %+v", user)
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=carol@example.com", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", rec.Code)
+	}
+}
+
+func TestGetUserByEmail_InvalidEmail(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	for _, query := range []string{"", "?email=", "?email=not-an-email", "?email=Ann+%3Cann@example.com%3E"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got: %d", query, rec.Code)
+		}
+	}
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Imposter","email":"ANN@example.com"}`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got: %d", rec.Code)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected no user to be created, got: %d users", len(users))
+	}
+}
+
+func TestCreateAndUpdateUser_InvalidEmail(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Bob","email":"not-an-email"}`))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_email") {
+		t.Fatalf("Expected status 400 invalid_email on create, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected no user to be created, got: %d users", len(users))
+	}
+
+	etag := serve(httptest.NewRequest(http.MethodGet, "/users/1", nil)).Header().Get("ETag")
+	req := jsonRequest(http.MethodPut, "/users/1", `{"name":"Ann","email":"ann@"}`)
+	req.Header.Set("If-Match", etag)
+	rec = serve(req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_email") {
+		t.Fatalf("Expected status 400 invalid_email on update, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if email := users[1].Email; email != "ann@example.com" {
+		t.Errorf("Expected email to stay unchanged, got: %q", email)
+	}
+}
+
+func TestEmailIndex_FollowsUpdatesAndDeletes(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com"},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+	})
+
+	if rec := serve(updateRequest("/users/1", `{"name":"Ann","email":"bob@example.com"}`)); rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 when taking another user's email, got: %d", rec.Code)
+	}
+
+	rec := serve(updateRequest("/users/1", `{"name":"Ann","email":"ann@work.example.com"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=ann@example.com", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected old email to be unindexed, got: %d", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=ann@work.example.com", nil)); rec.Code != http.StatusOK {
+		t.Errorf("Expected new email to be indexed, got: %d", rec.Code)
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodDelete, "/users/2", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=bob@example.com", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted user's email to be unindexed, got: %d", rec.Code)
+	}
+
+	// Освободившийся email можно занять снова
+	if rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Bobby","email":"bob@example.com"}`)); rec.Code != http.StatusCreated {
+		t.Errorf("Expected released email to be reusable, got: %d", rec.Code)
+	}
+	if len(emailIndex) != len(users) {
+		t.Errorf("Expected one index entry per user, got %d entries for %d users", len(emailIndex), len(users))
+	}
+}
+
+func TestCreateUser_ConcurrentDuplicateEmails(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	const workers = 20
+	codes := make(chan int, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(jsonRequest(http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com"}`)).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created, conflicts := 0, 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+			conflicts++
+		default:
+			t.Errorf("Unexpected status: %d", code)
+		}
+	}
+	if created != 1 || conflicts != workers-1 {
+		t.Errorf("Expected exactly one create and %d conflicts, got %d and %d", workers-1, created, conflicts)
+	}
+}
+
+func TestUpdateUser_ConcurrentEmailChanges(t *testing.T) {
+	data := map[int]User{}
+	for id := 1; id <= 10; id++ {
+		data[id] = User{ID: id, Name: "User", Email: fmt.Sprintf("user%d@example.com", id)}
+	}
+	setUsers(t, data)
+
+	var wg sync.WaitGroup
+	var ok atomic.Int32
+	for id := 1; id <= 10; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			target := fmt.Sprintf("/users/%d", id)
+			if serve(updateRequest(target, `{"name":"User","email":"shared@example.com"}`)).Code == http.StatusOK {
+				ok.Add(1)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if ok.Load() != 1 {
+		t.Errorf("Expected exactly one user to take the email, got: %d", ok.Load())
+	}
+}
+
+func TestCreateUser_ConflictEnvelope(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com"}`))
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.Code != "email_taken" || !strings.Contains(resp.Error.Message, "user 1") {
+		t.Errorf("Expected email_taken naming the owner, got: %+v", resp.Error)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Глубина встраивания между сервисами. orders-service встраивает
+// пользователя, user-service - сводку заказов, и если ответы начнут
+// встраивать друг друга, запрос уйдет в рекурсию. X-Embed-Depth задает,
+// сколько уровней встраивания еще разрешено: сервис, который встраивает
+// данные другого, передает ему глубину на единицу меньше, а при 0 ничего
+// не встраивает. Файл одинаков в обоих сервисах.
+
+const embedDepthHeader = "X-Embed-Depth"
+
+// defaultEmbedDepth - один уровень: order.user, но не order.user.orders_summary.
+const defaultEmbedDepth = 1
+
+// maxEmbedDepth ограничивает глубину, которую может запросить клиент.
+const maxEmbedDepth = 3
+
+type embedDepthKey struct{}
+
+// parseEmbedDepth читает X-Embed-Depth; без заголовка - defaultEmbedDepth.
+func parseEmbedDepth(r *http.Request) (int, error) {
+	raw := strings.TrimSpace(r.Header.Get(embedDepthHeader))
+	if raw == "" {
+		return defaultEmbedDepth, nil
+	}
+	depth, err := strconv.Atoi(raw)
+	if err != nil || depth < 0 || depth > maxEmbedDepth {
+		return 0, fmt.Errorf("%s must be an integer between 0 and %d, got %q", embedDepthHeader, maxEmbedDepth, raw)
+	}
+	return depth, nil
+}
+
+// embedDepthFromContext возвращает разрешенную глубину встраивания.
+func embedDepthFromContext(ctx context.Context) int {
+	if depth, ok := ctx.Value(embedDepthKey{}).(int); ok {
+		return depth
+	}
+	return defaultEmbedDepth
+}
+
+// canEmbed сообщает, можно ли встроить в ответ данные другого сервиса.
+func canEmbed(ctx context.Context) bool {
+	return embedDepthFromContext(ctx) > 0
+}
+
+// embedDepthMiddleware кладет глубину из заголовка в контекст запроса.
+func embedDepthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		depth, err := parseEmbedDepth(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_header", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), embedDepthKey{}, depth)))
+	})
+}
+
+// propagateEmbedDepth передает во внешний запрос глубину на единицу
+// меньше текущей: встраивание в другом сервисе - следующий уровень.
+func propagateEmbedDepth(req *http.Request) {
+	depth := embedDepthFromContext(req.Context()) - 1
+	if depth < 0 {
+		depth = 0
+	}
+	req.Header.Set(embedDepthHeader, strconv.Itoa(depth))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseEmbedDepth(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   int
+		ok     bool
+	}{
+		{"", defaultEmbedDepth, true},
+		{"0", 0, true},
+		{" 2 ", 2, true},
+		{"3", 3, true},
+		{"4", 0, false},
+		{"-1", 0, false},
+		{"deep", 0, false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			r.Header.Set(embedDepthHeader, tc.header)
+		}
+		got, err := parseEmbedDepth(r)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("%q: got %d (err: %v), want %d (ok: %v)", tc.header, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEmbedDepthMiddleware_PropagatesDecrementedDepth(t *testing.T) {
+	var outgoing []string
+	handler := embedDepthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://upstream/", nil)
+		propagateEmbedDepth(req)
+		outgoing = append(outgoing, req.Header.Get(embedDepthHeader))
+	}))
+
+	for _, header := range []string{"", "0", "2"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(embedDepthHeader, header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if want := []string{"0", "0", "1"}; len(outgoing) != 3 || outgoing[0] != want[0] || outgoing[1] != want[1] || outgoing[2] != want[2] {
+		t.Errorf("Expected outgoing depths %v, got: %v", want, outgoing)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(embedDepthHeader, "many")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid depth, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// userETag - сильный ETag хранимого пользователя: хеш его JSON. Одинаковые
+// данные всегда дают одинаковый ETag. Считается до маскирования и выбора
+// полей, поэтому годится для If-Match независимо от вида ответа.
+func userETag(u User) string {
+	data, _ := json.Marshal(u)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// checkIfMatch проверяет предусловия изменения: без If-Match отвечает 428,
+// при невыполненном If-Match или If-None-Match - 412. Возвращает false,
+// если ответ уже записан. Вызывать под mutex, чтобы между проверкой и
+// записью пользователь не менялся.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current string) bool {
+	if r.Header.Get("If-Match") == "" {
+		writeError(w, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required")
+		return false
+	}
+	return checkPreconditions(w, r, current, true)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserETag_Deterministic(t *testing.T) {
+	u := User{ID: 1, Name: "Ann", Email: "ann@example.com"}
+	if userETag(u) != userETag(u) {
+		t.Error("Expected the same ETag for the same user")
+	}
+	changed := u
+	changed.Name = "Anna"
+	if userETag(u) == userETag(changed) {
+		t.Error("Expected a different ETag after a change")
+	}
+}
+
+func TestUpdateUser_IfMatch(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag on GET /users/{id}")
+	}
+	masked := serve(httptest.NewRequest(http.MethodGet, "/users/1?mask_email=true&fields=name", nil))
+	if got := masked.Header().Get("ETag"); got != etag {
+		t.Errorf("Expected ETag to ignore masking and fields, got: %q, want: %q", got, etag)
+	}
+
+	req := jsonRequest(http.MethodPut, "/users/1", `{"name":"Anna","email":"ann@example.com"}`)
+	req.Header.Set("If-Match", etag)
+	rec = serve(req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	fresh := rec.Header().Get("ETag")
+	if fresh == "" || fresh == etag {
+		t.Errorf("Expected a new ETag after update, got: %q", fresh)
+	}
+
+	// Второй клиент с устаревшим ETag не должен затереть изменение
+	req = jsonRequest(http.MethodPut, "/users/1", `{"name":"Annie","email":"ann@example.com"}`)
+	req.Header.Set("If-Match", etag)
+	rec = serve(req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, got: %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != fresh {
+		t.Errorf("Expected 412 to carry the current ETag, got: %q", got)
+	}
+	if name := users[1].Name; name != "Anna" {
+		t.Errorf("Expected stale update to be rejected, got name: %q", name)
+	}
+}
+
+func TestUpdateUser_IfMatchRequired(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(jsonRequest(http.MethodPut, "/users/1", `{"name":"Anna","email":"ann@example.com"}`))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected status 428 without If-Match, got: %d", rec.Code)
+	}
+
+	req := jsonRequest(http.MethodPut, "/users/1", `{"name":"Anna","email":"ann@example.com"}`)
+	req.Header.Set("If-Match", `W/`+userETag(users[1]))
+	if rec := serve(req); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected weak ETag to fail strong comparison, got: %d", rec.Code)
+	}
+}
+
+func TestGetUserByID_IfNoneMatch(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	etag := userETag(users[1])
+
+	for header, want := range map[string]int{
+		etag:           http.StatusNotModified,
+		`"x", ` + etag: http.StatusNotModified,
+		"W/" + etag:    http.StatusNotModified,
+		"*":            http.StatusNotModified,
+		`"x"`:          http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("If-None-Match", header)
+		if rec := serve(req); rec.Code != want {
+			t.Errorf("If-None-Match %q: expected %d, got: %d", header, want, rec.Code)
+		}
+	}
+}
+
+func TestUpdateUser_IfMatchList(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	req := jsonRequest(http.MethodPut, "/users/1", `{"name":"Anna","email":"ann@example.com"}`)
+	req.Header.Set("If-Match", `"stale", `+userETag(users[1]))
+	if rec := serve(req); rec.Code != http.StatusOK {
+		t.Errorf("Expected list with the current ETag to match, got: %d", rec.Code)
+	}
+}
+
+func TestDeleteUser_IfMatch(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com"},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+	})
+	etag := serve(httptest.NewRequest(http.MethodGet, "/users/1", nil)).Header().Get("ETag")
+
+	stale := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+	stale.Header.Set("If-Match", `"stale"`)
+	rec := serve(stale)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, got: %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("Expected 412 to carry the current ETag, got: %q", got)
+	}
+	if _, ok := users[1]; !ok {
+		t.Fatal("Expected user to survive a stale delete")
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+	req.Header.Set("If-Match", etag)
+	if rec := serve(req); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d", rec.Code)
+	}
+	if _, ok := users[1]; ok {
+		t.Error("Expected user to be deleted")
+	}
+
+	// Без If-Match удаление остается безусловным
+	if rec := serve(httptest.NewRequest(http.MethodDelete, "/users/2", nil)); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 without If-Match, got: %d", rec.Code)
+	}
+}
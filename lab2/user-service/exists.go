@@ -0,0 +1,22 @@
+package main
+
+import "net/http"
+
+// userExists обрабатывает GET и HEAD /users/{id}/exists: 204, если
+// пользователь есть, и 404, если нет. Для проверок вроде создания заказа,
+// которым не нужен сам пользователь.
+func userExists(w http.ResponseWriter, r *http.Request, id int) {
+	mutex.RLock()
+	_, exists := users[id]
+	mutex.RUnlock()
+
+	if !exists {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
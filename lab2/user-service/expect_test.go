@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watchedBody отмечает, читал ли кто-нибудь тело запроса.
+type watchedBody struct {
+	r    io.Reader
+	read atomic.Bool
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.r.Read(p)
+}
+
+// expectContinueRequest отправляет запрос с Expect: 100-continue и
+// возвращает статус ответа и признак того, что тело было отправлено.
+func expectContinueRequest(t *testing.T, url, contentType, body string) (int, bool) {
+	t.Helper()
+	watched := &watchedBody{r: strings.NewReader(body)}
+	req, err := http.NewRequest(http.MethodPost, url, watched)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Expect", "100-continue")
+
+	client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 5 * time.Second}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, watched.read.Load()
+}
+
+func TestExpectContinue_RejectsBeforeBody(t *testing.T) {
+	prev := maxRequestBody
+	maxRequestBody = 64
+	t.Cleanup(func() { maxRequestBody = prev })
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+
+	status, sent := expectContinueRequest(t, ts.URL+"/users", "application/json", `{"name":"`+strings.Repeat("x", 64)+`"}`)
+	if status != http.StatusRequestEntityTooLarge || sent {
+		t.Errorf("Oversized body: expected 413 without upload, got: %d (body sent: %v)", status, sent)
+	}
+
+	status, sent = expectContinueRequest(t, ts.URL+"/users", "text/plain", `{}`)
+	if status != http.StatusUnsupportedMediaType || sent {
+		t.Errorf("Wrong Content-Type: expected 415 without upload, got: %d (body sent: %v)", status, sent)
+	}
+
+	// Битый JSON обнаруживается только после загрузки тела
+	status, sent = expectContinueRequest(t, ts.URL+"/users", "application/json", `{"name":`)
+	if status != http.StatusBadRequest || !sent {
+		t.Errorf("Valid headers: expected body upload and 400 from decoding, got: %d (body sent: %v)", status, sent)
+	}
+}
+
+func TestLoadMaxRequestBody(t *testing.T) {
+	if n, err := loadMaxRequestBody(envMap(nil)); err != nil || n != defaultMaxRequestBody {
+		t.Errorf("Expected default %d, got: %d (%v)", defaultMaxRequestBody, n, err)
+	}
+	if n, err := loadMaxRequestBody(envMap(map[string]string{"HTTP_MAX_REQUEST_BODY_BYTES": "2048"})); err != nil || n != 2048 {
+		t.Errorf("Expected 2048, got: %d (%v)", n, err)
+	}
+	for _, raw := range []string{"0", "-1", "big"} {
+		if _, err := loadMaxRequestBody(envMap(map[string]string{"HTTP_MAX_REQUEST_BODY_BYTES": raw})); err == nil {
+			t.Errorf("%q: expected error", raw)
+		}
+	}
+}
+
+func TestLimitRequestBody_ChunkedBody(t *testing.T) {
+	setUsers(t, map[int]User{})
+	prev := maxRequestBody
+	maxRequestBody = 64
+	t.Cleanup(func() { maxRequestBody = prev })
+
+	// Без Content-Length размер не известен заранее: тело обрезается на пределе
+	req := jsonRequest(http.MethodPost, "/users", strings.Repeat(" ", 64)+`{"name":"Ann","email":"ann@example.com"}`)
+	req.ContentLength = -1
+	if rec := serve(req); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body over the limit, got: %d", rec.Code)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no user to be created, got: %v", users)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// parseFields читает ?fields=a,b и проверяет имена по JSON-тегам model.
+// Возвращает nil, если параметр не передан.
+func parseFields(r *http.Request, model interface{}) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(model)
+	var fields []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+func jsonFieldNames(model interface{}) map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(model)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// selectFields оставляет в сериализованном v только перечисленные поля.
+// Поля, опущенные через omitempty, в ответ не попадают.
+func selectFields(v interface{}, fields []string) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNotFound_UnknownRoute(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/does-not-exist", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got: %d", rec.Code)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got: %q", ct)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+
+	if resp.Error.Code != "not_found" {
+		t.Errorf("Expected code 'not_found', got: %q", resp.Error.Code)
+	}
+}
+
+func TestNotFound_DoesNotShadowUsersPrefix(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/abc", nil))
+
+	// Ответ должен прийти от обработчика /users/, а не от catch-all
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 from /users/ handler, got: %d", rec.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+
+	if resp.Error.Code != "invalid_id" {
+		t.Errorf("Expected code 'invalid_id', got: %q", resp.Error.Code)
+	}
+}
+
+func TestGetUserByID_SparseFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1?fields=id,email", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp) != 2 || resp["id"] != float64(1) || resp["email"] == nil {
+		t.Errorf("Expected only id and email, got: %v", resp)
+	}
+}
+
+func TestGetUserByID_UnknownField(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1?fields=password", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+// setUsers подменяет хранилище пользователей на время теста.
+func setUsers(t *testing.T, data map[int]User) {
+	t.Helper()
+	mutex.Lock()
+	prevUsers, prevIDs, prevTokens, prevIndex := users, ids, verificationTokens, emailIndex
+	users = data
+	ids = &sequentialIDs{next: len(data) + 1}
+	verificationTokens = map[int]string{}
+	emailIndex = buildEmailIndex(data)
+	mutex.Unlock()
+
+	t.Cleanup(func() {
+		mutex.Lock()
+		users, ids, verificationTokens, emailIndex = prevUsers, prevIDs, prevTokens, prevIndex
+		mutex.Unlock()
+	})
+}
+
+func serve(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, r)
+	return rec
+}
+
+func jsonRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// updateRequest - PUT с If-Match: *, для тестов, которым не важен ETag.
+func updateRequest(target, body string) *http.Request {
+	r := jsonRequest(http.MethodPut, target, body)
+	r.Header.Set("If-Match", "*")
+	return r
+}
+
+func decodeUser(t *testing.T, rec *httptest.ResponseRecorder) User {
+	t.Helper()
+	var user User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	return user
+}
+
+func TestVerifyUser(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com","verified":true}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	if user := decodeUser(t, rec); user.Verified {
+		t.Fatal("Expected new user to be unverified regardless of request body")
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodPost, "/users/1/verify", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if user := decodeUser(t, rec); !user.Verified {
+		t.Error("Expected user to be verified")
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodPost, "/users/99/verify", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown user, got: %d", rec.Code)
+	}
+}
+
+func TestVerifyUser_RequiresToken(t *testing.T) {
+	setUsers(t, map[int]User{})
+	requireVerificationToken = true
+	t.Cleanup(func() { requireVerificationToken = false })
+
+	serve(jsonRequest(http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com"}`))
+
+	mutex.RLock()
+	token := verificationTokens[1]
+	mutex.RUnlock()
+
+	if rec := serve(jsonRequest(http.MethodPost, "/users/1/verify", `{"token":"wrong"}`)); rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for wrong token, got: %d", rec.Code)
+	}
+
+	rec := serve(jsonRequest(http.MethodPost, "/users/1/verify", `{"token":"`+token+`"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for valid token, got: %d", rec.Code)
+	}
+	if user := decodeUser(t, rec); !user.Verified {
+		t.Error("Expected user to be verified")
+	}
+}
+
+func TestCreateUser_LocationHeader(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann"}})
+
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Bob","email":"bob@example.com"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	user := decodeUser(t, rec)
+	if loc := rec.Header().Get("Location"); loc != "/users/"+strconv.Itoa(user.ID) {
+		t.Errorf("Expected Location /users/%d, got: %q", user.ID, loc)
+	}
+
+	// По Location отдается созданный пользователь
+	if rec := serve(httptest.NewRequest(http.MethodGet, rec.Header().Get("Location"), nil)); rec.Code != http.StatusOK {
+		t.Errorf("Expected Location to resolve, got: %d", rec.Code)
+	}
+}
+
+func TestUserExists(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	for _, tc := range []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodGet, "/users/1/exists", http.StatusNoContent},
+		{http.MethodHead, "/users/1/exists", http.StatusNoContent},
+		{http.MethodGet, "/users/2/exists", http.StatusNotFound},
+		{http.MethodHead, "/users/2/exists", http.StatusNotFound},
+		{http.MethodPost, "/users/1/exists", http.StatusMethodNotAllowed},
+	} {
+		rec := serve(httptest.NewRequest(tc.method, tc.target, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected status %d, got: %d", tc.method, tc.target, tc.want, rec.Code)
+		}
+		if tc.want == http.StatusNoContent && rec.Body.Len() != 0 {
+			t.Errorf("%s %s: expected an empty body, got: %q", tc.method, tc.target, rec.Body)
+		}
+	}
+}
+
+func TestGetUserByID_ProblemJSON(t *testing.T) {
+	setUsers(t, map[int]User{})
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("Accept", problemMediaType)
+	rec := serve(r)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got: %d", rec.Code)
+	}
+	p := decodeProblem(t, rec)
+	if p.Status != http.StatusNotFound || p.Code != "not_found" || p.Instance != "/users/42" {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+}
+
+func TestCreateUser_PreferReturn(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	req := jsonRequest(http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com"}`)
+	req.Header.Set("Prefer", "return=minimal")
+	rec := serve(req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d (%s)", rec.Code, rec.Body)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected empty body with return=minimal, got: %s", rec.Body)
+	}
+	if rec.Header().Get("Location") != "/users/1" || rec.Header().Get("Preference-Applied") != "return=minimal" {
+		t.Errorf("Expected Location and Preference-Applied, got: %v", rec.Header())
+	}
+
+	req = jsonRequest(http.MethodPost, "/users", `{"name":"Bob","email":"bob@example.com"}`)
+	req.Header.Set("Prefer", "return=representation")
+	rec = serve(req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Preference-Applied") != "return=representation" {
+		t.Fatalf("Expected 201 with return=representation applied, got: %d %v", rec.Code, rec.Header())
+	}
+	if user := decodeUser(t, rec); user.ID != 2 || user.Name != "Bob" {
+		t.Errorf("Expected the created user in the body, got: %+v", user)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Заголовки безопасности, которые ставятся на все ответы. Файл одинаков
+// в обоих сервисах.
+
+// ResponseHeaders - заголовки, добавляемые к каждому ответу. Обработчик
+// может переопределить любой из них, выставив свой до записи ответа.
+// Убрать заголовок надежно можно только пустым значением в Routes: за
+// timeoutMiddleware обработчик пишет в собственную карту заголовков, и
+// удаление из нее до ответа не доходит.
+type ResponseHeaders struct {
+	Defaults map[string]string `json:"defaults"`
+	// Routes переопределяет Defaults для путей. Путь, оканчивающийся на
+	// "/", совпадает по префиксу, как шаблоны ServeMux; побеждает самое
+	// длинное совпадение. Пустое значение убирает заголовок.
+	Routes map[string]map[string]string `json:"routes"`
+}
+
+// defaultResponseHeaders: запрет угадывания типа и встраивания во фреймы.
+// no-cache, а не no-store: клиент может хранить ответ, но обязан
+// перепроверить его по ETag.
+func defaultResponseHeaders() map[string]string {
+	return map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Cache-Control":          "no-cache",
+	}
+}
+
+// loadResponseHeaders читает CACHE_CONTROL, RESPONSE_HEADERS вида
+// "Referrer-Policy=no-referrer;X-Frame-Options=SAMEORIGIN" и ROUTE_HEADERS
+// вида "/health:Cache-Control=no-cache;/products/:Cache-Control=max-age=60".
+// Элементы разделяются ";", потому что запятая встречается в значениях.
+// Пустое значение ("Cache-Control=") убирает заголовок.
+func loadResponseHeaders(getenv func(string) string) (ResponseHeaders, error) {
+	h := ResponseHeaders{Defaults: defaultResponseHeaders(), Routes: map[string]map[string]string{}}
+	if raw := getenv("CACHE_CONTROL"); raw != "" {
+		h.Defaults["Cache-Control"] = strings.TrimSpace(raw)
+	}
+
+	for _, item := range splitHeaderList(getenv("RESPONSE_HEADERS")) {
+		name, value, err := parseHeaderItem(item)
+		if err != nil {
+			return h, fmt.Errorf("RESPONSE_HEADERS: %w", err)
+		}
+		h.Defaults[name] = value
+	}
+
+	for _, item := range splitHeaderList(getenv("ROUTE_HEADERS")) {
+		path, header, ok := strings.Cut(item, ":")
+		if path = strings.TrimSpace(path); !ok || !strings.HasPrefix(path, "/") {
+			return h, fmt.Errorf("ROUTE_HEADERS: %q must be /path:Header=value", item)
+		}
+		name, value, err := parseHeaderItem(header)
+		if err != nil {
+			return h, fmt.Errorf("ROUTE_HEADERS: %w", err)
+		}
+		if h.Routes[path] == nil {
+			h.Routes[path] = map[string]string{}
+		}
+		h.Routes[path][name] = value
+	}
+	return h, nil
+}
+
+func splitHeaderList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseHeaderItem(item string) (string, string, error) {
+	name, value, ok := strings.Cut(item, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t:") {
+		return "", "", fmt.Errorf("%q must be Header=value", item)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// routeMatchLen сравнивает путь запроса с маршрутом по правилам ServeMux:
+// маршрут на "/" совпадает по префиксу, остальные - только точно.
+// Возвращает длину совпадения или -1. Точное совпадение всегда длиннее
+// любого префикса.
+func routeMatchLen(route, path string) int {
+	switch {
+	case route == path:
+		return len(route) + 1
+	case strings.HasSuffix(route, "/") && strings.HasPrefix(path, route):
+		return len(route)
+	default:
+		return -1
+	}
+}
+
+// For возвращает заголовки для пути: Defaults с переопределениями
+// самого длинного подходящего маршрута.
+func (h ResponseHeaders) For(path string) map[string]string {
+	var best map[string]string
+	bestLen := -1
+	for route, headers := range h.Routes {
+		n := routeMatchLen(route, path)
+		if n < 0 {
+			continue
+		}
+		if n > bestLen {
+			best, bestLen = headers, n
+		}
+	}
+	if best == nil {
+		return h.Defaults
+	}
+
+	merged := make(map[string]string, len(h.Defaults)+len(best))
+	for name, value := range h.Defaults {
+		merged[name] = value
+	}
+	for name, value := range best {
+		merged[name] = value
+	}
+	return merged
+}
+
+// responseHeadersMiddleware выставляет заголовки до вызова обработчика,
+// поэтому обработчик может их переопределить.
+func responseHeadersMiddleware(h ResponseHeaders, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range h.For(r.URL.Path) {
+			if value != "" {
+				w.Header().Set(name, value)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator выдает ID для новых записей. Реализации безопасны для
+// параллельного использования.
+type IDGenerator interface {
+	// Next возвращает следующий ID.
+	Next() int
+	// Observe сообщает об уже занятом ID (загрузка данных, PUT с явным ID),
+	// чтобы генератор не выдал его повторно.
+	Observe(id int)
+}
+
+// newIDGenerator создает генератор по имени стратегии: sequential
+// (по умолчанию), uuid или sortable.
+func newIDGenerator(strategy string) (IDGenerator, error) {
+	switch strategy {
+	case "", "sequential":
+		return newSequentialIDs(), nil
+	case "uuid":
+		return uuidIDs{}, nil
+	case "sortable":
+		return &sortableIDs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q: expected sequential, uuid or sortable", strategy)
+	}
+}
+
+// sequentialIDs - счетчик 1, 2, 3, ...
+type sequentialIDs struct {
+	mu   sync.Mutex
+	next int
+}
+
+func newSequentialIDs() *sequentialIDs {
+	return &sequentialIDs{next: 1}
+}
+
+func (g *sequentialIDs) Next() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := g.next
+	g.next++
+	return id
+}
+
+func (g *sequentialIDs) Observe(id int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if id >= g.next {
+		g.next = id + 1
+	}
+}
+
+// peek возвращает ID, который выдаст следующий Next.
+func (g *sequentialIDs) peek() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.next
+}
+
+// reset переставляет счетчик, в том числе назад. Нужен восстановлению
+// из снимка; в остальных случаях используется Observe.
+func (g *sequentialIDs) reset(next int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next = next
+}
+
+// uuidIDs - случайные ID из криптографического генератора, как у UUIDv4.
+// ID в API целые, поэтому вместо 122 случайных бит UUID берется 53:
+// столько точно представимо в JSON-числе у JS-клиентов. Коллизии редки,
+// но возможны, поэтому вызывающий код проверяет, что ID свободен.
+type uuidIDs struct{}
+
+func (uuidIDs) Next() int {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	id := int(binary.BigEndian.Uint64(b[:]) & (1<<53 - 1))
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+func (uuidIDs) Observe(int) {}
+
+// sortableIDEpoch - начало отсчета сортируемых ID.
+var sortableIDEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sortableIDs - упрощенный аналог KSUID: миллисекунды от sortableIDEpoch
+// в старших битах и 12-битный счетчик в младших. ID строго возрастают,
+// даже если часы отстали или в одну миллисекунду пришло больше 4096 запросов.
+// Значения остаются в пределах 2^53 примерно 69 лет от эпохи.
+type sortableIDs struct {
+	mu   sync.Mutex
+	last int
+}
+
+const sortableSeqBits = 12
+
+func (g *sortableIDs) Next() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := int(now().Sub(sortableIDEpoch).Milliseconds()) << sortableSeqBits
+	if id <= g.last {
+		id = g.last + 1
+	}
+	g.last = id
+	return id
+}
+
+func (g *sortableIDs) Observe(id int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if id > g.last {
+		g.last = id
+	}
+}
+
+// nextUserID выдает свободный ID. Вызывается под mutex.
+func nextUserID() int {
+	for {
+		id := ids.Next()
+		if _, taken := users[id]; !taken {
+			return id
+		}
+		ids.Observe(id)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreateUser_SkipsTakenIDs(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann"}, 3: {ID: 3, Name: "Cid"}})
+
+	var created []int
+	for i := 0; i < 2; i++ {
+		rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"New"}`))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got: %d", rec.Code)
+		}
+		created = append(created, decodeUser(t, rec).ID)
+	}
+
+	// Счетчик начинается с 3, но этот ID уже занят
+	if created[0] != 4 || created[1] != 5 {
+		t.Errorf("Expected IDs 4 and 5, got: %v", created)
+	}
+}
+
+func TestSortableIDs_Increasing(t *testing.T) {
+	g := &sortableIDs{}
+	prev := 0
+	for i := 0; i < 5000; i++ {
+		id := g.Next()
+		if id <= prev {
+			t.Fatalf("Expected strictly increasing IDs, got %d after %d", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestSortableIDs_UseClock(t *testing.T) {
+	at := sortableIDEpoch.Add(time.Second)
+	prev := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = prev })
+
+	g := &sortableIDs{}
+	if id := g.Next(); id != 1000<<sortableSeqBits {
+		t.Errorf("Expected ID from the clock, got: %d", id)
+	}
+	// Часы отстали: ID все равно растут
+	at = sortableIDEpoch
+	if id := g.Next(); id != 1000<<sortableSeqBits+1 {
+		t.Errorf("Expected ID to keep increasing after the clock went back, got: %d", id)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIDocument - документ верхнего уровня в формате JSON:API.
+type jsonAPIDocument struct {
+	Data interface{} `json:"data"`
+}
+
+type jsonAPIResource struct {
+	Type       string                     `json:"type"`
+	ID         string                     `json:"id"`
+	Attributes map[string]json.RawMessage `json:"attributes"`
+}
+
+// wantsJSONAPI сообщает, запросил ли клиент JSON:API через Accept.
+func wantsJSONAPI(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == jsonAPIMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// newJSONAPIResource превращает сериализованный v в ресурс: поле id
+// выносится наверх, остальные поля становятся атрибутами. fields, если
+// задан, ограничивает набор атрибутов.
+func newJSONAPIResource(resourceType string, id int, v interface{}, fields []string) (jsonAPIResource, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return jsonAPIResource{}, err
+	}
+	delete(attributes, "id")
+
+	if fields != nil {
+		keep := map[string]bool{}
+		for _, name := range fields {
+			keep[name] = true
+		}
+		for name := range attributes {
+			if !keep[name] {
+				delete(attributes, name)
+			}
+		}
+	}
+
+	return jsonAPIResource{Type: resourceType, ID: strconv.Itoa(id), Attributes: attributes}, nil
+}
+
+// userDocument собирает JSON:API документ для одного пользователя.
+func userDocument(user User, fields []string) (jsonAPIDocument, error) {
+	res, err := newJSONAPIResource("users", user.ID, user, fields)
+	return jsonAPIDocument{Data: res}, err
+}
+
+// usersDocument собирает JSON:API документ для коллекции, упорядоченной по ID.
+func usersDocument(all map[int]User) (jsonAPIDocument, error) {
+	ids := make([]int, 0, len(all))
+	for id := range all {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	data := make([]jsonAPIResource, 0, len(ids))
+	for _, id := range ids {
+		res, err := newJSONAPIResource("users", id, all[id], nil)
+		if err != nil {
+			return jsonAPIDocument{}, err
+		}
+		data = append(data, res)
+	}
+	return jsonAPIDocument{Data: data}, nil
+}
+
+func writeJSONAPI(w http.ResponseWriter, status int, doc jsonAPIDocument) {
+	w.Header().Set("Content-Type", jsonAPIMediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func jsonAPIRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Accept", jsonAPIMediaType)
+	return r
+}
+
+func TestJSONAPI_SingleUser(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(jsonAPIRequest("/users/1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != jsonAPIMediaType {
+		t.Errorf("Expected content type %q, got: %q", jsonAPIMediaType, ct)
+	}
+
+	var doc struct {
+		Data jsonAPIResource `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if doc.Data.Type != "users" || doc.Data.ID != "1" || string(doc.Data.Attributes["name"]) != `"Ann"` {
+		t.Errorf("Unexpected resource: %+v", doc.Data)
+	}
+}
+
+func TestJSONAPI_UserCollection(t *testing.T) {
+	setUsers(t, map[int]User{
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com"},
+	})
+
+	rec := serve(jsonAPIRequest("/users"))
+
+	var doc struct {
+		Data []jsonAPIResource `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if len(doc.Data) != 2 || doc.Data[0].ID != "1" || doc.Data[1].ID != "2" {
+		t.Errorf("Expected users 1 and 2 in order, got: %+v", doc.Data)
+	}
+}
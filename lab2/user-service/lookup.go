@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxLookupIDs ограничивает ?ids= в GET /users, чтобы один запрос не
+// превращался в выгрузку всей базы.
+const maxLookupIDs = 100
+
+// parseLookupIDs читает ?ids=1,2,3: выборку нескольких пользователей одним
+// запросом для пакетной загрузки в orders-service. nil - параметр не
+// задан; ответ содержит только найденных пользователей.
+func parseLookupIDs(r *http.Request) ([]int, error) {
+	raw, ok := r.URL.Query()["ids"]
+	if !ok {
+		return nil, nil
+	}
+	ids := []int{}
+	for _, part := range strings.Split(strings.Join(raw, ","), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("ids must be a comma-separated list of positive integers, got %q", part)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxLookupIDs {
+		return nil, fmt.Errorf("ids accepts at most %d IDs, got %d", maxLookupIDs, len(ids))
+	}
+	return ids, nil
+}
+
+// selectUsers оставляет из all только пользователей с ID из ids.
+func selectUsers(all map[int]User, ids []int) map[int]User {
+	selected := make(map[int]User, len(ids))
+	for _, id := range ids {
+		if user, ok := all[id]; ok {
+			selected[id] = user
+		}
+	}
+	return selected
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetUsers_LookupByIDs(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com"},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+		3: {ID: 3, Name: "Cid", Email: "cid@example.com"},
+	})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users?ids=1,3,42&mask_email=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var list map[int]User
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode users: %v", err)
+	}
+	// Ненайденный ID просто отсутствует в ответе
+	if len(list) != 2 || list[1].Name != "Ann" || list[3].Name != "Cid" {
+		t.Errorf("Expected users 1 and 3, got: %v", list)
+	}
+	if !strings.Contains(list[1].Email, "***") {
+		t.Errorf("Expected masked email, got: %q", list[1].Email)
+	}
+	if users[1].Email != "ann@example.com" {
+		t.Errorf("Expected stored user untouched by masking, got: %q", users[1].Email)
+	}
+}
+
+func TestGetUsers_LookupByIDsValidation(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	tooMany := make([]string, maxLookupIDs+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+	for _, query := range []string{"ids=", "ids=1,x", "ids=0", "ids=" + strings.Join(tooMany, ",")} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/users?"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d", query, rec.Code)
+		}
+	}
+}
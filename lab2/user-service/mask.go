@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maskEmail скрывает локальную часть адреса, оставляя первый символ:
+// alice@example.com -> a***@example.com.
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + domain
+}
+
+// parseMaskEmail читает ?mask_email=; без параметра действует значение по умолчанию.
+func parseMaskEmail(r *http.Request, def bool) (bool, error) {
+	raw := r.URL.Query().Get("mask_email")
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("mask_email must be a boolean, got %q", raw)
+	}
+	return v, nil
+}
+
+// maskEmails - значение ?mask_email= по умолчанию. Задается через MASK_EMAILS.
+var maskEmails = false
+
+// maskedUser возвращает копию пользователя со скрытым email, если mask.
+func maskedUser(user User, mask bool) User {
+	if mask {
+		user.Email = maskEmail(user.Email)
+	}
+	return user
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaskEmail(t *testing.T) {
+	if got := maskEmail("alice@example.com"); got != "a***@example.com" {
+		t.Errorf("Expected a***@example.com, got: %q", got)
+	}
+	if got := maskEmail("broken"); got != "***" {
+		t.Errorf("Expected *** for malformed address, got: %q", got)
+	}
+}
+
+func TestGetUser_MaskEmail(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/1?mask_email=true", nil))
+	if user := decodeUser(t, rec); user.Email != "a***@example.com" {
+		t.Errorf("Expected masked email, got: %q", user.Email)
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodGet, "/users?mask_email=true", nil))
+	var list map[int]User
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode users: %v", err)
+	}
+	if list[1].Email != "a***@example.com" {
+		t.Errorf("Expected masked email in list, got: %q", list[1].Email)
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodGet, "/users/by-email?email=ann@example.com&mask_email=true", nil))
+	if user := decodeUser(t, rec); user.Email != "a***@example.com" {
+		t.Errorf("Expected masked email from by-email lookup, got: %q", user.Email)
+	}
+
+	// Маскируется только ответ, хранимые данные остаются прежними
+	if stored := users[1].Email; stored != "ann@example.com" {
+		t.Errorf("Expected stored email untouched, got: %q", stored)
+	}
+	if user := decodeUser(t, serve(httptest.NewRequest(http.MethodGet, "/users/1", nil))); user.Email != "ann@example.com" {
+		t.Errorf("Expected unmasked email by default, got: %q", user.Email)
+	}
+}
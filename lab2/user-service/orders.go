@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ordersSummaryUnavailableHeader выставляется, когда сводку заказов
+// запросили, но orders-service не ответил.
+const ordersSummaryUnavailableHeader = "X-Orders-Summary-Unavailable"
+
+// ordersSummaryTimeout ограничивает ожидание orders-service, чтобы его
+// недоступность не задерживала ответ с профилем.
+const ordersSummaryTimeout = 2 * time.Second
+
+// OrderSummary - сводка заказов пользователя.
+type OrderSummary struct {
+	TotalOrders int            `json:"total_orders"`
+	ByStatus    map[string]int `json:"by_status"`
+}
+
+// OrderServiceClient обращается к orders-service.
+type OrderServiceClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// orderClient - клиент orders-service; задается в main.
+var orderClient = &OrderServiceClient{
+	BaseURL: "http://localhost:8082",
+	Client:  &http.Client{Timeout: 5 * time.Second},
+}
+
+// GetOrderSummary запрашивает агрегаты заказов пользователя из GET /orders/stats.
+func (c *OrderServiceClient) GetOrderSummary(ctx context.Context, userID int) (*OrderSummary, error) {
+	target := fmt.Sprintf("%s/orders/stats?user_id=%d", c.BaseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	propagateEmbedDepth(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("orders service returned status %d", resp.StatusCode)
+	}
+
+	var summary OrderSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, err
+	}
+	if summary.ByStatus == nil {
+		summary.ByStatus = map[string]int{}
+	}
+	return &summary, nil
+}
+
+// parseWithOrdersSummary читает ?with_orders_summary=true|false.
+func parseWithOrdersSummary(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("with_orders_summary")
+	if raw == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("with_orders_summary must be a boolean, got %q", raw)
+	}
+	return v, nil
+}
+
+// userWithOrders - пользователь со сводкой заказов для профиля.
+type userWithOrders struct {
+	User
+	OrdersSummary *OrderSummary `json:"orders_summary,omitempty"`
+}
+
+// fetchOrderSummary получает сводку для ответа. Если orders-service
+// недоступен, сводка опускается, а ответ помечается заголовком.
+func fetchOrderSummary(w http.ResponseWriter, r *http.Request, userID int) *OrderSummary {
+	ctx, cancel := context.WithTimeout(r.Context(), ordersSummaryTimeout)
+	defer cancel()
+
+	summary, err := orderClient.GetOrderSummary(ctx, userID)
+	if err != nil {
+		log.Printf("Warning: failed to get orders summary for user %d: %v", userID, err)
+		w.Header().Set(ordersSummaryUnavailableHeader, "true")
+		return nil
+	}
+	return summary
+}
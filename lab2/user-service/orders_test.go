@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useOrderService направляет orderClient на заглушку orders-service.
+func useOrderService(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	saved := orderClient
+	orderClient = &OrderServiceClient{BaseURL: ts.URL, Client: &http.Client{Timeout: time.Second}}
+	t.Cleanup(func() { orderClient = saved })
+}
+
+func TestGetUserByID_WithOrdersSummary(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	useOrderService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders/stats" || r.URL.Query().Get("user_id") != "1" {
+			t.Errorf("Unexpected request to orders service: %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total_orders":3,"by_status":{"pending":2,"shipped":1},"total_quantity":7}`))
+	})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/1?with_orders_summary=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	var body userWithOrders
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	if body.Name != "Ann" || body.OrdersSummary == nil {
+		t.Fatalf("Expected user with summary, got: %+v", body)
+	}
+	if s := body.OrdersSummary; s.TotalOrders != 3 || s.ByStatus["pending"] != 2 || s.ByStatus["shipped"] != 1 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+
+	// С ?fields= сводка добавляется к выбранным полям
+	rec = serve(httptest.NewRequest(http.MethodGet, "/users/1?fields=name&with_orders_summary=true", nil))
+	var selected map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&selected); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	if _, ok := selected["orders_summary"]; !ok || len(selected) != 2 {
+		t.Errorf("Expected name and orders_summary, got: %v", selected)
+	}
+}
+
+func TestGetUserByID_OrdersServiceDown(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	useOrderService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/1?with_orders_summary=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", rec.Code)
+	}
+	if rec.Header().Get(ordersSummaryUnavailableHeader) != "true" {
+		t.Errorf("Expected %s header", ordersSummaryUnavailableHeader)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	if _, ok := body["orders_summary"]; ok || body["name"] != "Ann" {
+		t.Errorf("Expected user without summary, got: %v", body)
+	}
+}
+
+func TestGetUserByID_WithoutOrdersSummary(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	useOrderService(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no call to orders service")
+	})
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get(ordersSummaryUnavailableHeader) != "" {
+		t.Errorf("Expected plain user, got: %d", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/1?with_orders_summary=maybe", nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", rec.Code)
+	}
+}
+
+func TestGetUserByID_EmbedDepth(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	var received []string
+	useOrderService(t, func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(embedDepthHeader))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total_orders":1,"by_status":{"pending":1},"total_quantity":1}`))
+	})
+
+	for _, tc := range []struct {
+		depth       string
+		wantSummary bool
+	}{
+		{"", true},
+		{"2", true},
+		// orders-service встраивает пользователя с глубиной 0: сводка не нужна
+		{"0", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/users/1?with_orders_summary=true", nil)
+		if tc.depth != "" {
+			r.Header.Set(embedDepthHeader, tc.depth)
+		}
+		rec := serve(r)
+		var body userWithOrders
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode user: %v", err)
+		}
+		if got := body.OrdersSummary != nil; got != tc.wantSummary {
+			t.Errorf("depth %q: expected summary %v, got: %+v", tc.depth, tc.wantSummary, body.OrdersSummary)
+		}
+	}
+	if len(received) != 2 || received[0] != "0" || received[1] != "1" {
+		t.Errorf("Expected orders-service to receive depths [0 1], got: %v", received)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Заголовок Prefer (RFC 7240) для ответов на создание. С return=minimal
+// клиент получает 201 с Location и пустым телом, с return=representation
+// или без предпочтения - созданный ресурс целиком. Примененное
+// предпочтение подтверждается в Preference-Applied. Файл одинаков в обоих
+// сервисах.
+
+const (
+	returnMinimal        = "minimal"
+	returnRepresentation = "representation"
+)
+
+// preferredReturn возвращает значение предпочтения return из заголовков
+// Prefer или "", если его нет или значение неизвестно. Если return указан
+// несколько раз, действует первый.
+func preferredReturn(r *http.Request) string {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			// Параметры предпочтения после ";" для return не определены
+			pref, _, _ = strings.Cut(pref, ";")
+			name, value, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "return") {
+				continue
+			}
+			switch value := strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`)); value {
+			case returnMinimal, returnRepresentation:
+				return value
+			default:
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+// respondMinimal подтверждает предпочтение return и, если клиент просил
+// return=minimal, сам отвечает status без тела. Возвращает true, если
+// ответ уже отправлен. Location и прочие заголовки нужно выставить до вызова.
+func respondMinimal(w http.ResponseWriter, r *http.Request, status int) bool {
+	switch preferredReturn(r) {
+	case returnMinimal:
+		w.Header().Set("Preference-Applied", "return="+returnMinimal)
+		w.WriteHeader(status)
+		return true
+	case returnRepresentation:
+		w.Header().Set("Preference-Applied", "return="+returnRepresentation)
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreferredReturn(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   string
+	}{
+		{"absent", nil, ""},
+		{"minimal", []string{"return=minimal"}, returnMinimal},
+		{"representation", []string{"return=representation"}, returnRepresentation},
+		{"among other preferences", []string{"respond-async, return=minimal; foo=bar, wait=10"}, returnMinimal},
+		{"quoted and mixed case", []string{`Return="Minimal"`}, returnMinimal},
+		{"first wins", []string{"return=representation", "return=minimal"}, returnRepresentation},
+		{"unknown value", []string{"return=everything"}, ""},
+		{"other preference only", []string{"handling=strict"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			for _, h := range tt.header {
+				r.Header.Add("Prefer", h)
+			}
+			if got := preferredReturn(r); got != tt.want {
+				t.Errorf("Expected %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRespondMinimal(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Prefer", "return=minimal")
+	rec := httptest.NewRecorder()
+	if !respondMinimal(rec, r, http.StatusCreated) {
+		t.Fatal("Expected the minimal response to be written")
+	}
+	if rec.Code != http.StatusCreated || rec.Body.Len() != 0 || rec.Header().Get("Preference-Applied") != "return=minimal" {
+		t.Errorf("Unexpected minimal response: %d %q %v", rec.Code, rec.Body, rec.Header())
+	}
+
+	r.Header.Set("Prefer", "return=representation")
+	rec = httptest.NewRecorder()
+	if respondMinimal(rec, r, http.StatusCreated) {
+		t.Fatal("Expected the caller to write the representation")
+	}
+	if rec.Header().Get("Preference-Applied") != "return=representation" {
+		t.Errorf("Expected Preference-Applied: return=representation, got: %v", rec.Header())
+	}
+
+	r.Header.Del("Prefer")
+	rec = httptest.NewRecorder()
+	if respondMinimal(rec, r, http.StatusCreated) || rec.Header().Get("Preference-Applied") != "" {
+		t.Errorf("Expected no preference to be applied, got: %v", rec.Header())
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Ошибки в формате application/problem+json (RFC 7807). Обработчики
+// по-прежнему пишут конверт ErrorResponse через writeError, а
+// problemMiddleware переписывает его, если клиент просит problem+json в
+// Accept или формат включен по умолчанию. Файл одинаков в обоих сервисах.
+
+const problemMediaType = "application/problem+json"
+
+// Problem - тело ошибки по RFC 7807. Code дублирует прежний код ошибки,
+// чтобы клиенты могли переходить на новый формат постепенно.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// problemTitles - заголовки для известных кодов ошибок. Для остальных
+// заголовком служит текст HTTP-статуса.
+var problemTitles = map[string]string{
+	"not_found":                "Resource not found",
+	"invalid_id":               "Invalid resource ID",
+	"invalid_body":             "Malformed request body",
+	"invalid_query":            "Invalid query parameter",
+	"invalid_fields":           "Invalid field selection",
+	"invalid_header":           "Invalid request header",
+	"validation_failed":        "Validation failed",
+	"invalid_user":             "User not found or unavailable",
+	"user_service_unavailable": "User service unavailable",
+	"precondition_failed":      "Precondition failed",
+	"method_not_allowed":       "Method not allowed",
+	"timeout":                  "Request timed out",
+}
+
+// newProblem переводит конверт ошибки в Problem. type - URN по коду
+// ошибки: у сервисов нет страниц с описанием ошибок, на которые мог бы
+// указывать URL.
+func newProblem(status int, body ErrorBody, instance string) Problem {
+	title := problemTitles[body.Code]
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	return Problem{
+		Type:     "urn:problem-type:" + strings.ReplaceAll(body.Code, "_", "-"),
+		Title:    title,
+		Status:   status,
+		Detail:   body.Message,
+		Instance: instance,
+		Code:     body.Code,
+	}
+}
+
+// wantsProblem сообщает, перечислил ли клиент problem+json в Accept.
+func wantsProblem(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == problemMediaType && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// problemMiddleware переписывает ошибки в problem+json. Успешные ответы
+// и ошибки не в формате ErrorResponse проходят без буферизации.
+func problemMiddleware(byDefault func() bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !wantsProblem(r) && !byDefault() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		pw := &problemWriter{ResponseWriter: w, instance: r.URL.Path}
+		next.ServeHTTP(pw, r)
+		pw.finish()
+	})
+}
+
+// problemWriter копит тело только JSON-ответов с кодом 4xx/5xx.
+type problemWriter struct {
+	http.ResponseWriter
+	instance    string
+	wroteHeader bool
+	buffering   bool
+	code        int
+	buf         bytes.Buffer
+}
+
+func (p *problemWriter) WriteHeader(code int) {
+	if p.wroteHeader {
+		return
+	}
+	p.wroteHeader = true
+	if code >= 400 && strings.HasPrefix(p.Header().Get("Content-Type"), "application/json") {
+		p.buffering = true
+		p.code = code
+		return
+	}
+	p.ResponseWriter.WriteHeader(code)
+}
+
+func (p *problemWriter) Write(b []byte) (int, error) {
+	if !p.wroteHeader {
+		p.WriteHeader(http.StatusOK)
+	}
+	if p.buffering {
+		return p.buf.Write(b)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// finish отправляет накопленную ошибку, переписав конверт в Problem.
+func (p *problemWriter) finish() {
+	if !p.buffering {
+		return
+	}
+	body := p.buf.Bytes()
+	var envelope ErrorResponse
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+		if data, err := json.Marshal(newProblem(p.code, envelope.Error, p.instance)); err == nil {
+			body = append(data, '\n')
+			p.Header().Set("Content-Type", problemMediaType)
+			p.Header().Del("Content-Length")
+		}
+	}
+	p.Header().Add("Vary", "Accept")
+	p.ResponseWriter.WriteHeader(p.code)
+	p.ResponseWriter.Write(body)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// minSearchQuery - минимальная длина ?q= в символах: более короткий
+// запрос совпадает почти со всеми и превращается в выгрузку базы.
+const minSearchQuery = 2
+
+// defaultSearchLimit - сколько пользователей отдает GET /users/search,
+// если SEARCH_MAX_RESULTS не задан.
+const defaultSearchLimit = 20
+
+// searchLimit ограничивает число результатов поиска, ?limit= может его
+// только уменьшить. Задается через SEARCH_MAX_RESULTS.
+var searchLimit = defaultSearchLimit
+
+// loadSearchLimit читает SEARCH_MAX_RESULTS.
+func loadSearchLimit(getenv func(string) string) (int, error) {
+	raw := getenv("SEARCH_MAX_RESULTS")
+	if raw == "" {
+		return defaultSearchLimit, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("SEARCH_MAX_RESULTS must be a positive integer, got %q", raw)
+	}
+	return n, nil
+}
+
+// matchesName сообщает, начинается ли имя или одно из его слов с query.
+// Оба значения уже приведены к нижнему регистру.
+func matchesName(name, query string) bool {
+	if strings.HasPrefix(name, query) {
+		return true
+	}
+	for _, word := range strings.Fields(name) {
+		if strings.HasPrefix(word, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchUsers возвращает пользователей, подходящих под query, по
+// возрастанию имени (при равных именах - по ID), не больше limit.
+// Вызывается под mutex.
+func searchUsers(data map[int]User, query string, limit int) []User {
+	query = strings.ToLower(query)
+	found := []User{}
+	for _, user := range data {
+		if matchesName(strings.ToLower(user.Name), query) {
+			found = append(found, user)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Name != found[j].Name {
+			return found[i].Name < found[j].Name
+		}
+		return found[i].ID < found[j].ID
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// getUsersSearch - GET /users/search?q=: поиск по имени без учета
+// регистра для подсказок при вводе.
+func getUsersSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) < minSearchQuery {
+		writeError(w, http.StatusBadRequest, "invalid_query", fmt.Sprintf("q must be at least %d characters", minSearchQuery))
+		return
+	}
+
+	limit := searchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_query", fmt.Sprintf("limit must be a positive integer, got %q", raw))
+			return
+		}
+		if n < limit {
+			limit = n
+		}
+	}
+
+	mask, err := parseMaskEmail(r, maskEmails)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	mutex.RLock()
+	found := searchUsers(users, query, limit)
+	mutex.RUnlock()
+
+	for i := range found {
+		found[i] = maskedUser(found[i], mask)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(found)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func searchNames(t *testing.T, query string) []string {
+	t.Helper()
+	rec := serve(httptest.NewRequest(http.MethodGet, "/users/search?"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got: %d (%s)", query, rec.Code, rec.Body)
+	}
+	var found []User
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatalf("Failed to decode search results: %v", err)
+	}
+	names := make([]string, len(found))
+	for i, user := range found {
+		names[i] = user.Name
+	}
+	return names
+}
+
+func TestSearchUsers_PrefixMatch(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Михаил Шаманя", Email: "mishutka@example.com"},
+		2: {ID: 2, Name: "Annabel", Email: "annabel@example.com"},
+		3: {ID: 3, Name: "ann Lee", Email: "ann@example.com"},
+		4: {ID: 4, Name: "Joanna", Email: "joanna@example.com"},
+		5: {ID: 5, Name: "Bob Annson", Email: "bob@example.com"},
+	})
+
+	// Совпадение по началу имени или слова, без учета регистра;
+	// "Joanna" содержит "ann" только в середине слова
+	got := searchNames(t, "q=ANN")
+	want := []string{"Annabel", "Bob Annson", "ann Lee"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v sorted by name, got: %v", want, got)
+	}
+
+	if got := searchNames(t, "q=шам"); len(got) != 1 || got[0] != "Михаил Шаманя" {
+		t.Errorf("Expected case-insensitive match on a Cyrillic word, got: %v", got)
+	}
+	if got := searchNames(t, "q=zz"); len(got) != 0 {
+		t.Errorf("Expected no matches, got: %v", got)
+	}
+}
+
+func TestSearchUsers_QueryValidation(t *testing.T) {
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann"}})
+
+	for _, query := range []string{"", "q=", "q=%20%20", "q=a", "q=ш", "q=an&limit=0", "q=an&limit=x"} {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/users/search?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got: %d", query, rec.Code)
+		}
+	}
+	if rec := serve(httptest.NewRequest(http.MethodPost, "/users/search?q=an", nil)); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got: %d", rec.Code)
+	}
+}
+
+func TestSearchUsers_ResultCap(t *testing.T) {
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann D"},
+		2: {ID: 2, Name: "Ann C"},
+		3: {ID: 3, Name: "Ann B"},
+		4: {ID: 4, Name: "Ann A"},
+	})
+	prev := searchLimit
+	searchLimit = 3
+	t.Cleanup(func() { searchLimit = prev })
+
+	if got := searchNames(t, "q=ann"); strings.Join(got, "|") != "Ann A|Ann B|Ann C" {
+		t.Errorf("Expected the first 3 matches by name, got: %v", got)
+	}
+	if got := searchNames(t, "q=ann&limit=2"); len(got) != 2 {
+		t.Errorf("Expected ?limit= to lower the cap, got: %v", got)
+	}
+	// ?limit= не может поднять настроенный предел
+	if got := searchNames(t, "q=ann&limit=100"); len(got) != 3 {
+		t.Errorf("Expected results capped at SEARCH_MAX_RESULTS, got: %v", got)
+	}
+}
+
+func TestLoadSearchLimit(t *testing.T) {
+	if n, err := loadSearchLimit(envMap(nil)); err != nil || n != defaultSearchLimit {
+		t.Errorf("Expected default limit, got: %d, %v", n, err)
+	}
+	if n, err := loadSearchLimit(envMap(map[string]string{"SEARCH_MAX_RESULTS": "5"})); err != nil || n != 5 {
+		t.Errorf("Expected limit 5, got: %d, %v", n, err)
+	}
+	for _, raw := range []string{"0", "-1", "many"} {
+		if _, err := loadSearchLimit(envMap(map[string]string{"SEARCH_MAX_RESULTS": raw})); err == nil {
+			t.Errorf("%q: expected validation error", raw)
+		}
+	}
+}
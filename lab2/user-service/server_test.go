@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_SlowHeadersAreCutOff(t *testing.T) {
+	timeouts := defaultServerTimeouts
+	timeouts.ReadHeader = 100 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := newHTTPServer("", newRouter(), timeouts, defaultMaxHeaderBytes)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Отправляем заголовки по кусочку, как slowloris, и не завершаем их
+	start := time.Now()
+	for _, chunk := range []string{"GET /health HTTP/1.1\r\n", "Host: localhost\r\n", "X-Slow: 1\r\n"} {
+		if _, err := conn.Write([]byte(chunk)); err != nil {
+			break
+		}
+		time.Sleep(80 * time.Millisecond)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 512)
+	for {
+		if _, err = conn.Read(buf); err != nil {
+			break
+		}
+	}
+
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("Expected server to close the slow connection, but it stayed open")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connection was cut off too late: %v", elapsed)
+	}
+}
+
+func TestLoadServerTimeouts(t *testing.T) {
+	timeouts, err := loadServerTimeouts(envMap(map[string]string{"HTTP_READ_HEADER_TIMEOUT": "2s"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if timeouts.ReadHeader != 2*time.Second || timeouts.Idle != defaultServerTimeouts.Idle {
+		t.Errorf("Expected override with defaults kept, got: %+v", timeouts)
+	}
+
+	if _, err := loadServerTimeouts(envMap(map[string]string{"HTTP_WRITE_TIMEOUT": "-1s"})); err == nil {
+		t.Error("Expected error for negative timeout, got nil")
+	}
+}
+
+func TestServer_OversizedHeadersRejected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := newHTTPServer("", newRouter(), defaultServerTimeouts, 1024)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/health", nil)
+	req.Header.Set("X-Padding", strings.Repeat("a", 16<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("Expected status 431, got: %d", resp.StatusCode)
+	}
+
+	// Обычный запрос по-прежнему проходит
+	resp, err = http.Get("http://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", resp.StatusCode)
+	}
+}
+
+func TestLoadMaxHeaderBytes(t *testing.T) {
+	if n, err := loadMaxHeaderBytes(envMap(nil)); err != nil || n != defaultMaxHeaderBytes {
+		t.Errorf("Expected default %d, got: %d (%v)", defaultMaxHeaderBytes, n, err)
+	}
+	if n, err := loadMaxHeaderBytes(envMap(map[string]string{"HTTP_MAX_HEADER_BYTES": "8192"})); err != nil || n != 8192 {
+		t.Errorf("Expected 8192, got: %d (%v)", n, err)
+	}
+	if _, err := loadMaxHeaderBytes(envMap(map[string]string{"HTTP_MAX_HEADER_BYTES": "0"})); err == nil {
+		t.Error("Expected error for zero limit")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// usersSnapshot - полное состояние сервиса пользователей для
+// GET /admin/snapshot и POST /admin/restore. Нужен для демонстраций и
+// воспроизведения ошибок; формат не предназначен для долговременного хранения.
+type usersSnapshot struct {
+	// Users - все пользователи по возрастанию ID
+	Users []User `json:"users"`
+	// NextID - следующий ID последовательного генератора; у остальных
+	// стратегий поле не заполняется
+	NextID int `json:"next_id,omitempty"`
+	// VerificationTokens - токены еще не подтвержденных пользователей,
+	// без них после восстановления их нельзя будет подтвердить
+	VerificationTokens map[int]string `json:"verification_tokens,omitempty"`
+}
+
+func takeSnapshot() usersSnapshot {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	snap := usersSnapshot{Users: make([]User, 0, len(users))}
+	for _, user := range users {
+		snap.Users = append(snap.Users, user)
+	}
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+	if seq, ok := ids.(*sequentialIDs); ok {
+		snap.NextID = seq.peek()
+	}
+	if len(verificationTokens) > 0 {
+		snap.VerificationTokens = make(map[int]string, len(verificationTokens))
+		for id, token := range verificationTokens {
+			snap.VerificationTokens[id] = token
+		}
+	}
+	return snap
+}
+
+// validate проверяет снимок перед восстановлением, чтобы не заменить
+// состояние наполовину.
+func (snap usersSnapshot) validate() error {
+	maxID := 0
+	seen := make(map[int]bool, len(snap.Users))
+	emails := make(map[string]int, len(snap.Users))
+	for i, user := range snap.Users {
+		if user.ID <= 0 {
+			return fmt.Errorf("users[%d]: id must be a positive integer", i)
+		}
+		if seen[user.ID] {
+			return fmt.Errorf("users[%d]: duplicate id %d", i, user.ID)
+		}
+		seen[user.ID] = true
+		if user.Email != "" {
+			if !validEmail(user.Email) {
+				return fmt.Errorf("users[%d]: invalid email %q", i, user.Email)
+			}
+			if owner, taken := emails[emailKey(user.Email)]; taken {
+				return fmt.Errorf("users[%d]: email %q already belongs to user %d", i, user.Email, owner)
+			}
+			emails[emailKey(user.Email)] = user.ID
+		}
+		if user.ID > maxID {
+			maxID = user.ID
+		}
+	}
+	if snap.NextID != 0 && snap.NextID <= maxID {
+		return fmt.Errorf("next_id must be greater than the largest user id %d", maxID)
+	}
+	for id := range snap.VerificationTokens {
+		if !seen[id] {
+			return fmt.Errorf("verification token for unknown user %d", id)
+		}
+	}
+	return nil
+}
+
+// restoreSnapshot заменяет состояние снимком под одной блокировкой mutex.
+// Снимок должен быть проверен validate.
+func restoreSnapshot(snap usersSnapshot) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	users = make(map[int]User, len(snap.Users))
+	for _, user := range snap.Users {
+		users[user.ID] = user
+	}
+	emailIndex = buildEmailIndex(users)
+	verificationTokens = make(map[int]string, len(snap.VerificationTokens))
+	for id, token := range snap.VerificationTokens {
+		verificationTokens[id] = token
+	}
+
+	if seq, ok := ids.(*sequentialIDs); ok && snap.NextID != 0 {
+		seq.reset(snap.NextID)
+	} else {
+		for id := range users {
+			ids.Observe(id)
+		}
+	}
+}
+
+// handleSnapshot обрабатывает GET /admin/snapshot.
+func handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !adminEnabled {
+		notFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(takeSnapshot())
+}
+
+// handleRestore обрабатывает POST /admin/restore: тело - снимок из
+// GET /admin/snapshot. Неверный снимок отклоняется целиком.
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	if !adminEnabled {
+		notFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var snap usersSnapshot
+	if err := decodeStrictJSON(r.Body, &snap); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if err := snap.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+		return
+	}
+
+	restoreSnapshot(snap)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getSnapshot(t *testing.T) []byte {
+	t.Helper()
+	rec := serve(httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", rec.Code, rec.Body)
+	}
+	return rec.Body.Bytes()
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	resetDrain(t)
+	adminEnabled = true
+	setUsers(t, map[int]User{
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com", Verified: true},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com", Verified: true},
+	})
+
+	// Новый пользователь получает токен подтверждения и сдвигает счетчик ID
+	if rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Cid","email":"cid@example.com"}`)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got: %d", rec.Code)
+	}
+	data := getSnapshot(t)
+
+	var snap usersSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if len(snap.Users) != 3 || snap.NextID != 4 || snap.VerificationTokens[3] == "" {
+		t.Fatalf("Unexpected snapshot: %s", data)
+	}
+
+	setUsers(t, map[int]User{9: {ID: 9, Name: "Old", Email: "ann@example.com"}})
+	if rec := serve(jsonRequest(http.MethodPost, "/admin/restore", string(data))); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got: %d (%s)", rec.Code, rec.Body)
+	}
+
+	if restored := getSnapshot(t); !bytes.Equal(restored, data) {
+		t.Errorf("Expected identical snapshot after restore.\nbefore: %s\nafter:  %s", data, restored)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/users/9", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected previous users to be replaced, got: %d", rec.Code)
+	}
+
+	// Индекс email перестроен: адрес Ann снова занят ею
+	rec := serve(jsonRequest(http.MethodPost, "/users", `{"name":"Dup","email":"ANN@example.com"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for restored email, got: %d", rec.Code)
+	}
+	rec = serve(jsonRequest(http.MethodPost, "/users", `{"name":"Dan","email":"dan@example.com"}`))
+	if rec.Code != http.StatusCreated || decodeUser(t, rec).ID != 4 {
+		t.Errorf("Expected next user to get ID 4, got: %d", rec.Code)
+	}
+}
+
+func TestRestore_RejectsInvalidSnapshot(t *testing.T) {
+	resetDrain(t)
+	adminEnabled = true
+	setUsers(t, map[int]User{1: {ID: 1, Name: "Ann", Email: "ann@example.com"}})
+	before := getSnapshot(t)
+
+	for name, body := range map[string]string{
+		"duplicate id":    `{"users":[{"id":1,"name":"A"},{"id":1,"name":"B"}]}`,
+		"duplicate email": `{"users":[{"id":1,"email":"a@example.com"},{"id":2,"email":"A@example.com"}]}`,
+		"invalid email":   `{"users":[{"id":1,"email":"not-an-email"}]}`,
+		"stale next_id":   `{"users":[{"id":5,"name":"A"}],"next_id":3}`,
+		"orphan token":    `{"users":[{"id":1,"name":"A"}],"verification_tokens":{"2":"abc"}}`,
+		"trailing data":   `{"users":[{"id":1,"name":"A"}]}{"users":[]}`,
+	} {
+		if rec := serve(jsonRequest(http.MethodPost, "/admin/restore", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got: %d (%s)", name, rec.Code, rec.Body)
+		}
+	}
+
+	if after := getSnapshot(t); !bytes.Equal(after, before) {
+		t.Errorf("Expected state untouched after rejected restores.\nbefore: %s\nafter:  %s", before, after)
+	}
+}
+
+func TestSnapshot_AdminOnly(t *testing.T) {
+	resetDrain(t)
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected snapshot hidden without ADMIN_ENABLED, got: %d", rec.Code)
+	}
+	if rec := serve(jsonRequest(http.MethodPost, "/admin/restore", `{"users":[]}`)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected restore hidden without ADMIN_ENABLED, got: %d", rec.Code)
+	}
+}
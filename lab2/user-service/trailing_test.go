@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestCreateUser_RejectsTrailingJSON(t *testing.T) {
+	setUsers(t, map[int]User{})
+
+	body := `{"name":"Ann","email":"ann@example.com"}{"name":"Bob","email":"bob@example.com"}`
+	rec := serve(jsonRequest(http.MethodPost, "/users", body))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for two JSON objects, got: %d (%s)", rec.Code, rec.Body)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.Code != "invalid_body" || resp.Error.Message != errTrailingData.Error() {
+		t.Errorf("Expected invalid_body about trailing data, got: %+v", resp.Error)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no user created, got: %v", users)
+	}
+
+}
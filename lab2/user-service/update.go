@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// updateUser заменяет имя и email пользователя. ID и статус подтверждения
+// через PUT не меняются. Требует If-Match с актуальным ETag пользователя.
+// PATCH у пользователей нет, поэтому PUT - единственное изменение, которое
+// может затереть чужую правку.
+func updateUser(w http.ResponseWriter, r *http.Request, id int) {
+	var update User
+	if err := decodeSingleJSON(r.Body, &update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if update.Email != "" && !validEmail(update.Email) {
+		writeError(w, http.StatusBadRequest, "invalid_email", "Invalid email address")
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	existing, exists := users[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	if !checkIfMatch(w, r, userETag(existing)) {
+		return
+	}
+
+	if owner, taken := emailTaken(update.Email, id); taken {
+		writeEmailTaken(w, owner)
+		return
+	}
+
+	updated := existing
+	updated.Name = update.Name
+	updated.Email = update.Email
+
+	unindexEmail(existing)
+	users[id] = updated
+	indexEmail(updated)
+
+	w.Header().Set("ETag", userETag(updated))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+// requireVerificationToken требует токен из письма при подтверждении email.
+// Задается через REQUIRE_VERIFICATION_TOKEN.
+var requireVerificationToken = false
+
+// verificationTokens - токены подтверждения по ID пользователя.
+// Защищены тем же mutex, что и users.
+var verificationTokens = map[int]string{}
+
+type verifyRequest struct {
+	Token string `json:"token"`
+}
+
+func newVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sendVerificationEmail имитирует отправку письма со ссылкой подтверждения.
+func sendVerificationEmail(user User, token string) {
+	log.Printf("Simulated email to %s: POST /users/%d/verify with token %s", user.Email, user.ID, token)
+}
+
+func verifyUser(w http.ResponseWriter, r *http.Request, id int) {
+	var req verifyRequest
+	if requireVerificationToken {
+		// Пустое тело допустимо: токен тогда не передан
+		if err := decodeSingleJSON(r.Body, &req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+			return
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	user, exists := users[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	if !user.Verified {
+		expected := verificationTokens[id]
+		if requireVerificationToken && (req.Token == "" || subtle.ConstantTimeCompare([]byte(req.Token), []byte(expected)) != 1) {
+			writeError(w, http.StatusForbidden, "invalid_token", "Invalid verification token")
+			return
+		}
+
+		user.Verified = true
+		users[id] = user
+		delete(verificationTokens, id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}